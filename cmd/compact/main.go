@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"auction-site-go/internal/persistence"
+)
+
+// compact archives the history of ended auctions in the sealed segments of a
+// segmented event log; the server must not be running while it does
+func main() {
+	eventsDir := flag.String("events-dir", "tmp/events", "Segmented event log to compact")
+	flag.Parse()
+
+	result, err := persistence.CompactSegments(*eventsDir, persistence.LogOptions{})
+	if err != nil {
+		log.Fatalf("Failed to compact: %v", err)
+	}
+
+	log.Printf("Archived %d auctions in %d segments of %s: %d events reduced to %d",
+		result.Auctions, result.Segments, *eventsDir, result.EventsBefore, result.EventsAfter)
+}
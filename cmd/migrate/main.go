@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"auction-site-go/internal/persistence"
+)
+
+// migrate converts the JSONL command and event logs into an SQLite database
+func main() {
+	eventsFile := flag.String("events", "tmp/events.jsonl", "JSONL event log to read")
+	eventsDir := flag.String("events-dir", "", "Segmented JSONL event log to read instead of -events")
+	commandsFile := flag.String("commands", "tmp/commands.jsonl", "JSONL command log to read")
+	databaseFile := flag.String("db", "tmp/auctions.db", "SQLite database to create")
+	flag.Parse()
+
+	var from *persistence.JSONLStore
+	var err error
+	if *eventsDir != "" {
+		from, err = persistence.OpenSegmentedJSONLStore(*commandsFile, *eventsDir, "", persistence.LogOptions{}, persistence.SegmentOptions{})
+	} else {
+		from, err = persistence.OpenJSONLStore(*commandsFile, *eventsFile, persistence.LogOptions{})
+	}
+	if err != nil {
+		log.Fatalf("Failed to open logs: %v", err)
+	}
+	defer from.Close()
+
+	to, err := persistence.OpenSQLiteStore(*databaseFile)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer to.Close()
+
+	commands, events, err := persistence.Migrate(from, to)
+	if err != nil {
+		log.Fatalf("Failed to migrate: %v", err)
+	}
+
+	log.Printf("Migrated %d commands and %d events into %s", commands, events, *databaseFile)
+}
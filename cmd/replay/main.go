@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+// replay checks that replaying the command log through the current domain logic
+// reproduces the event log, and exits with status 1 if it does not
+func main() {
+	storage := flag.String("storage", "jsonl", "Storage backend to read: jsonl or sqlite")
+	eventsFile := flag.String("events", "tmp/events.jsonl", "JSONL event log to read")
+	eventsDir := flag.String("events-dir", "", "Segmented JSONL event log to read instead of -events")
+	commandsFile := flag.String("commands", "tmp/commands.jsonl", "JSONL command log to read")
+	databaseFile := flag.String("db", "tmp/auctions.db", "SQLite database to read")
+	flag.Parse()
+
+	var scanCommands func(fn func(int64, domain.Command) error) error
+	var scanEvents func(fn func(int64, domain.Event) error) error
+	switch *storage {
+	case "jsonl":
+		// Read the logs without opening them for appending, so that they are left as they are
+		scanCommands = func(fn func(int64, domain.Command) error) error {
+			return persistence.ScanCommands(*commandsFile, persistence.LogOptions{}, fn)
+		}
+		scanEvents = func(fn func(int64, domain.Event) error) error {
+			if *eventsDir != "" {
+				return persistence.ScanSegmentedEvents(*eventsDir, persistence.LogOptions{}, fn)
+			}
+			return persistence.ScanEvents(*eventsFile, persistence.LogOptions{}, fn)
+		}
+	case "sqlite":
+		store, err := persistence.OpenSQLiteStore(*databaseFile)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer store.Close()
+		scanCommands, scanEvents = store.ScanCommands, store.ScanEvents
+	default:
+		log.Fatalf("Unknown storage backend: %s", *storage)
+	}
+
+	report, err := persistence.CheckReplay(scanCommands, scanEvents)
+	if err != nil {
+		log.Fatalf("Failed to replay: %v", err)
+	}
+
+	for _, divergence := range report.Divergences {
+		fmt.Println(divergence)
+	}
+	log.Printf("Replayed %d commands against %d events: %d divergences", report.Commands, report.Events, len(report.Divergences))
+	if len(report.Archived) > 0 {
+		log.Printf("Skipped %d auctions archived by compaction", len(report.Archived))
+	}
+	if len(report.Divergences) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"auction-site-go/internal/domain"
@@ -24,35 +27,153 @@ func main() {
 		commandsFile = "tmp/commands.jsonl"
 	}
 
+	// Get the directory of a segmented event log; when set, events are written to
+	// segments in it instead of to EVENTS_FILE, which is moved into it if it exists
+	eventsDir := os.Getenv("EVENTS_DIR")
+
+	// Get the size and age at which a segment of the event log is sealed or use defaults
+	var segmentOptions persistence.SegmentOptions
+	segmentOptions.MaxBytes = 64 << 20
+	if v := os.Getenv("EVENTS_SEGMENT_BYTES"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 {
+			log.Fatalf("Invalid EVENTS_SEGMENT_BYTES: %s", v)
+		}
+		segmentOptions.MaxBytes = size
+	}
+
+	segmentOptions.MaxAge = 24 * time.Hour
+	if v := os.Getenv("EVENTS_SEGMENT_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			log.Fatalf("Invalid EVENTS_SEGMENT_SECONDS: %s", v)
+		}
+		segmentOptions.MaxAge = time.Duration(seconds) * time.Second
+	}
+
+	// Get the storage backend: "jsonl" (default) uses EVENTS_FILE and COMMANDS_FILE,
+	// "sqlite" uses an embedded database at DATABASE_FILE
+	storage := os.Getenv("STORAGE")
+	if storage == "" {
+		storage = "jsonl"
+	}
+
+	databaseFile := os.Getenv("DATABASE_FILE")
+	if databaseFile == "" {
+		databaseFile = "tmp/auctions.db"
+	}
+
+	// Get how long fsyncs of the JSONL logs wait to group concurrent appends or use default
+	var logOptions persistence.LogOptions
+	if v := os.Getenv("LOG_GROUP_COMMIT_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			log.Fatalf("Invalid LOG_GROUP_COMMIT_MS: %s", v)
+		}
+		logOptions.GroupCommitWindow = time.Duration(ms) * time.Millisecond
+	}
+
+	// Get the largest record the JSONL logs accept or use default
+	if v := os.Getenv("LOG_MAX_RECORD_BYTES"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			log.Fatalf("Invalid LOG_MAX_RECORD_BYTES: %s", v)
+		}
+		logOptions.MaxRecordSize = size
+	}
+
 	// Get server port from environment variables or use default
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	// Ensure directory exists
-	log.Printf("Ensuring directory exists for events file: %s", eventsFile)
-	dir := filepath.Dir(eventsFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Fatalf("Failed to create directory: %v", err)
+	// Get the interval between checks for ended auctions or use default
+	closeCheckInterval := time.Second
+	if v := os.Getenv("CLOSE_CHECK_INTERVAL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			log.Fatalf("Invalid CLOSE_CHECK_INTERVAL_SECONDS: %s", v)
+		}
+		closeCheckInterval = time.Duration(seconds) * time.Second
+	}
+
+	// Get the snapshot directory and interval or use defaults; an interval of 0 disables snapshots
+	snapshotDir := os.Getenv("SNAPSHOT_DIR")
+	if snapshotDir == "" {
+		snapshotDir = "tmp/snapshots"
+	}
+
+	snapshotInterval := 5 * time.Minute
+	if v := os.Getenv("SNAPSHOT_INTERVAL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			log.Fatalf("Invalid SNAPSHOT_INTERVAL_SECONDS: %s", v)
+		}
+		snapshotInterval = time.Duration(seconds) * time.Second
+	}
+
+	// Get how long responses to requests with an Idempotency-Key are kept or use default;
+	// 0 disables idempotency keys
+	idempotencyKeyTTL := web.DefaultIdempotencyKeyTTL
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			log.Fatalf("Invalid IDEMPOTENCY_KEY_TTL_SECONDS: %s", v)
+		}
+		idempotencyKeyTTL = time.Duration(seconds) * time.Second
+	}
+
+	// Get the authentication mode: "trusted-proxy" (default) trusts the x-jwt-payload
+	// header of a front proxy, "verify" verifies Authorization: Bearer tokens itself
+	authMode := os.Getenv("AUTH_MODE")
+	if authMode == "" {
+		authMode = "trusted-proxy"
 	}
 
-	// Read events
-	events, err := persistence.ReadEvents(eventsFile)
+	// Open the store
+	store, err := openStore(storage, commandsFile, eventsFile, eventsDir, databaseFile, logOptions, segmentOptions)
 	if err != nil {
-		log.Fatalf("Failed to read events: %v", err)
+		log.Fatalf("Failed to open %s storage: %v", storage, err)
 	}
+	defer store.Close()
 
-	// Initialize repository
-	repo := domain.EventsToAuctionStates(events)
+	// Initialize repository from the latest snapshot and the events after it,
+	// seeking the event log to where the snapshot ends rather than reading it whole
+	snapshot, err := persistence.ReadLatestSnapshot(snapshotDir, math.MaxInt64)
+	if err != nil {
+		log.Fatalf("Failed to read snapshots: %v", err)
+	}
+	repo := make(domain.Repository)
+	closed := make(map[domain.AuctionId]bool)
+	var lastSeq int64
+	if snapshot != nil {
+		lastSeq, err = replayEvents(store, snapshot.Repo, snapshot.Position, snapshot.Closed)
+		switch {
+		case errors.Is(err, persistence.ErrPositionNotInLog):
+			// The snapshot is ahead of the event log, so it cannot be trusted
+			log.Printf("Ignoring snapshot covering %d events: %v", snapshot.Position.Seq, err)
+			snapshot = nil
+		case err != nil:
+			log.Fatalf("Failed to read events: %v", err)
+		default:
+			repo, closed = snapshot.Repo, snapshot.Closed
+			log.Printf("Restored snapshot covering %d of %d events", snapshot.Position.Seq, lastSeq)
+		}
+	}
+	if snapshot == nil {
+		if lastSeq, err = replayEvents(store, repo, persistence.LogPosition{}, closed); err != nil {
+			log.Fatalf("Failed to read events: %v", err)
+		}
+	}
 
 	onCommand := func(command domain.Command) error {
-		return persistence.WriteCommands(commandsFile, []domain.Command{command})
+		return store.WriteCommands([]domain.Command{command})
 	}
 
 	// Event handler
 	onEvent := func(event domain.Event) error {
-		return persistence.WriteEvents(eventsFile, []domain.Event{event})
+		return store.WriteEvents([]domain.Event{event})
 	}
 
 	// Get current time
@@ -61,7 +182,141 @@ func main() {
 	// Create web application
 	app := web.NewApp(repo, onCommand, onEvent, getCurrentTime)
 
+	// Number streamed events by their position in the event log so that clients can resume
+	app.Events.SetHistory(lastSeq, store.ScanEvents)
+
+	// Let support users look up commands and their outcomes
+	app.ScanCommands = store.ScanCommands
+
+	// Restore the responses to requests with idempotency keys that have not expired
+	app.Idempotency.SetTTL(idempotencyKeyTTL)
+	if idempotencyKeyTTL > 0 {
+		if err := app.Idempotency.Load(store.ScanCommands, getCurrentTime()); err != nil {
+			log.Fatalf("Failed to load idempotency keys: %v", err)
+		}
+	}
+
+	// Configure authentication
+	switch authMode {
+	case "trusted-proxy":
+		app.Authenticator = web.TrustedProxyAuthenticator
+	case "verify":
+		verifier, err := newJwtVerifier(getCurrentTime)
+		if err != nil {
+			log.Fatalf("Failed to configure JWT verification: %v", err)
+		}
+		app.Authenticator = web.NewBearerAuthenticator(verifier)
+	default:
+		log.Fatalf("Invalid AUTH_MODE: %s", authMode)
+	}
+
+	// Close auctions as they end so that downstream systems get a close signal;
+	// the closing events of an auction are written in one batch
+	scheduler := web.NewScheduler(app.State, closed, app.Events.ObserveAll(store.WriteEvents), getCurrentTime)
+	go scheduler.Run(closeCheckInterval, make(chan struct{}))
+
+	// Periodically snapshot the repository so that restarts only replay recent events
+	if snapshotInterval > 0 {
+		go runSnapshots(app, scheduler, store, snapshotDir, snapshotInterval)
+	}
+
 	// Start server
 	log.Printf("Starting server on port %s", port)
 	log.Fatal(app.Run(":" + port))
 }
+
+// openStore opens the configured storage backend
+func openStore(storage, commandsFile, eventsFile, eventsDir, databaseFile string, logOptions persistence.LogOptions, segmentOptions persistence.SegmentOptions) (persistence.Store, error) {
+	switch storage {
+	case "jsonl":
+		if eventsDir != "" {
+			log.Printf("Opening segmented event log: %s", eventsDir)
+			return persistence.OpenSegmentedJSONLStore(commandsFile, eventsDir, eventsFile, logOptions, segmentOptions)
+		}
+		log.Printf("Opening event log: %s", eventsFile)
+		return persistence.OpenJSONLStore(commandsFile, eventsFile, logOptions)
+	case "sqlite":
+		log.Printf("Opening database: %s", databaseFile)
+		return persistence.OpenSQLiteStore(databaseFile)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", storage)
+	}
+}
+
+// replayEvents streams the events after pos, folding them into repo and recording
+// the auctions they close, and returns the sequence number of the last event
+func replayEvents(store persistence.Store, repo domain.Repository, pos persistence.LogPosition, closed map[domain.AuctionId]bool) (int64, error) {
+	last := pos.Seq
+	err := store.ScanEventsAfter(pos, func(seq int64, event domain.Event) error {
+		last = seq
+		domain.ApplyEvent(repo, event)
+		domain.MarkClosed(closed, event)
+		return nil
+	})
+	return last, err
+}
+
+// runSnapshots writes a snapshot of the repository, the auctions closed by the scheduler and
+// the position of the event log every interval, skipping intervals in which no events were written
+func runSnapshots(app *web.App, scheduler *web.Scheduler, store persistence.Store, dir string, interval time.Duration) {
+	lastSeq := int64(-1)
+	for range time.Tick(interval) {
+		var snapshot persistence.Snapshot
+		var err error
+		app.State.Snapshot(func(current domain.Repository) {
+			snapshot.Repo = current
+			snapshot.Closed = scheduler.Closed()
+			snapshot.Position, err = store.EventsPosition()
+		})
+		if err != nil {
+			log.Printf("Failed to read the event log position: %v", err)
+			continue
+		}
+		if snapshot.Position.Seq == lastSeq {
+			continue
+		}
+
+		if err := persistence.WriteSnapshot(dir, snapshot); err != nil {
+			log.Printf("Failed to write snapshot: %v", err)
+			continue
+		}
+		lastSeq = snapshot.Position.Seq
+	}
+}
+
+// newJwtVerifier configures JWT verification from environment variables:
+// JWT_HS256_SECRET for a shared secret, JWT_KEY_FILE for a PEM or JWKS file with
+// RS256/ES256 public keys, and optionally JWT_ISSUER, JWT_AUDIENCE and JWT_LEEWAY_SECONDS.
+func newJwtVerifier(getCurrentTime func() time.Time) (*web.JwtVerifier, error) {
+	verifier := &web.JwtVerifier{
+		Issuer:         os.Getenv("JWT_ISSUER"),
+		Audience:       os.Getenv("JWT_AUDIENCE"),
+		GetCurrentTime: getCurrentTime,
+	}
+
+	if secret := os.Getenv("JWT_HS256_SECRET"); secret != "" {
+		verifier.Keys = append(verifier.Keys, web.NewHS256Key([]byte(secret)))
+	}
+
+	if keyFile := os.Getenv("JWT_KEY_FILE"); keyFile != "" {
+		keys, err := web.LoadJwtKeys(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Keys = append(verifier.Keys, keys...)
+	}
+
+	if len(verifier.Keys) == 0 {
+		return nil, errors.New("JWT_HS256_SECRET or JWT_KEY_FILE must be set")
+	}
+
+	if v := os.Getenv("JWT_LEEWAY_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid JWT_LEEWAY_SECONDS: %s", v)
+		}
+		verifier.Leeway = time.Duration(seconds) * time.Second
+	}
+
+	return verifier, nil
+}
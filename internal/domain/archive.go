@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuctionArchivedEvent replaces the history of an ended auction in a compacted log.
+// It carries the auction and its final state, so that replaying it gives the same
+// result as replaying the events it replaces.
+type AuctionArchivedEvent struct {
+	Time    time.Time
+	Auction Auction
+	State   State
+	// Events is the number of events the archive replaces
+	Events int
+}
+
+// GetTime returns the time of the last event the archive replaces
+func (e AuctionArchivedEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the archived auction
+func (e AuctionArchivedEvent) GetAuctionId() AuctionId {
+	return e.Auction.ID
+}
+
+type auctionArchivedEventJSON struct {
+	Type    string        `json:"$type"`
+	Time    time.Time     `json:"at"`
+	Auction Auction       `json:"auction"`
+	State   StateSnapshot `json:"state"`
+	Events  int           `json:"events"`
+}
+
+// MarshalJSON implements json.Marshaler interface for AuctionArchivedEvent;
+// the state must be a SnapshotableState
+func (e AuctionArchivedEvent) MarshalJSON() ([]byte, error) {
+	state, err := newStateSnapshot(e.State)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(auctionArchivedEventJSON{
+		Type:    "AuctionArchived",
+		Time:    e.Time,
+		Auction: e.Auction,
+		State:   state,
+		Events:  e.Events,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for AuctionArchivedEvent
+func (e *AuctionArchivedEvent) UnmarshalJSON(data []byte) error {
+	var archived auctionArchivedEventJSON
+	if err := json.Unmarshal(data, &archived); err != nil {
+		return err
+	}
+	state, err := decodeState(archived.State)
+	if err != nil {
+		return err
+	}
+
+	e.Time = archived.Time
+	e.Auction = archived.Auction
+	e.State = state
+	e.Events = archived.Events
+	return nil
+}
@@ -10,20 +10,21 @@ import (
 type AuctionTypeEnum int
 
 const (
-	TimedAscending  AuctionTypeEnum = iota
-	SingleSealedBid                 = 1
+	TimedAscending     AuctionTypeEnum = iota
+	SingleSealedBid                    = 1
+	TimedDescending                    = 2
+	MultiUnitSealedBid                 = 3
 )
 
 // String returns the string representation of the auction type enum
 func (t AuctionTypeEnum) String() string {
-	switch t {
-	case TimedAscending:
-		return "TimedAscending"
-	case SingleSealedBid:
-		return "SingleSealedBid"
-	default:
-		return "Unknown"
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if name, ok := auctionTypeEnum[t]; ok {
+		return name
 	}
+	return "Unknown"
 }
 
 // AuctionType represents the type of auction
@@ -48,11 +49,58 @@ func NewSingleSealedBidType(options SealedBidOptions) AuctionType {
 	}
 }
 
+// NewSingleSealedBidTypeWithReserve creates a new SingleSealedBid auction type with a reserve price
+func NewSingleSealedBidTypeWithReserve(options SealedBidOptions, reservePrice Amount) AuctionType {
+	return AuctionType{
+		Type:    SingleSealedBid,
+		Options: SealedBidAuctionOptions{Kind: options, ReservePrice: reservePrice}.String(),
+	}
+}
+
+// NewMultiUnitType creates a new MultiUnitSealedBid auction type
+func NewMultiUnitType(options MultiUnitOptions) AuctionType {
+	return AuctionType{
+		Type:    MultiUnitSealedBid,
+		Options: options.String(),
+	}
+}
+
+// NewTimedDescendingType creates a new TimedDescending auction type
+func NewTimedDescendingType(options TimedDescendingOptions) AuctionType {
+	return AuctionType{
+		Type:    TimedDescending,
+		Options: options.String(),
+	}
+}
+
 // String returns a string representation of the auction type
 func (t AuctionType) String() string {
 	return t.Options
 }
 
+// Name returns the name prefix of the auction type, e.g. "English" or "Vickrey"
+func (t AuctionType) Name() string {
+	return auctionTypeName(t.Options)
+}
+
+// ParseAuctionType parses serialized options using the registered auction types
+func ParseAuctionType(s string) (AuctionType, error) {
+	def, ok := LookupAuctionType(s)
+	if !ok {
+		return AuctionType{}, fmt.Errorf("unknown auction type: %s", s)
+	}
+
+	options, err := def.ParseOptions(s)
+	if err != nil {
+		return AuctionType{}, err
+	}
+
+	return AuctionType{
+		Type:    def.Type,
+		Options: def.FormatOptions(options),
+	}, nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler interface
 func (t *AuctionType) UnmarshalJSON(data []byte) error {
 	var s string
@@ -60,21 +108,12 @@ func (t *AuctionType) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	// Parse the options
-	if len(s) >= 7 && s[:7] == "English" {
-		options, err := ParseTimedAscendingOptions(s)
-		if err != nil {
-			return err
-		}
-		t.Type = TimedAscending
-		t.Options = options.String()
-	} else if s == "Vickrey" || s == "Blind" {
-		t.Type = SingleSealedBid
-		t.Options = s
-	} else {
-		return fmt.Errorf("unknown auction type: %s", s)
+	parsed, err := ParseAuctionType(s)
+	if err != nil {
+		return err
 	}
 
+	*t = parsed
 	return nil
 }
 
@@ -92,6 +131,8 @@ type Auction struct {
 	Seller   User        `json:"user"`
 	Type     AuctionType `json:"type"`
 	Currency Currency    `json:"currency"`
+	// ShowReserve is set when the seller allows the reserve price to be shown to bidders
+	ShowReserve bool `json:"showReserve,omitempty"`
 }
 
 // NewAuction creates a new auction
@@ -112,25 +153,79 @@ func (a Auction) ValidateBid(bid Bid) error {
 	if bid.Bidder.ID == a.Seller.ID {
 		return NewSellerCannotPlaceBidsError(bid.Bidder.ID, a.ID)
 	}
+	if !bid.Amount.IsPositive() {
+		return NewBidAmountNotPositiveError(bid.Amount)
+	}
+	if bid.MaxAmount.IsNegative() {
+		return NewBidAmountNotPositiveError(bid.MaxAmount)
+	}
+	if units := a.units(); bid.Quantity < 0 || bid.Units() > units {
+		return NewInvalidQuantityError(bid.Quantity, units)
+	}
 
 	return nil
 }
 
+// ValidateOptions checks the auction's options with the Validate hook of its type.
+// It returns an error if the type is unknown or the options cannot be parsed.
+func (a Auction) ValidateOptions() error {
+	def, ok := LookupAuctionType(a.Type.Options)
+	if !ok {
+		return fmt.Errorf("unknown auction type: %s", a.Type.Options)
+	}
+
+	options, err := def.ParseOptions(a.Type.Options)
+	if err != nil {
+		return err
+	}
+	if def.Validate == nil {
+		return nil
+	}
+	return def.Validate(options, a.Currency)
+}
+
+// units returns the number of units for sale, as given by the Units hook of the auction's type
+func (a Auction) units() int {
+	def, ok := LookupAuctionType(a.Type.Options)
+	if !ok || def.Units == nil {
+		return 1
+	}
+	options, err := def.ParseOptions(a.Type.Options)
+	if err != nil {
+		return 1
+	}
+	return def.Units(options)
+}
+
+// PublicType returns the auction type as it may be shown to bidders: without the
+// reserve price, unless the seller chose to show it
+func (a Auction) PublicType() AuctionType {
+	if a.ShowReserve {
+		return a.Type
+	}
+	def, ok := LookupAuctionType(a.Type.Options)
+	if !ok || def.Public == nil {
+		return a.Type
+	}
+	options, err := def.ParseOptions(a.Type.Options)
+	if err != nil {
+		return a.Type
+	}
+	return AuctionType{Type: a.Type.Type, Options: def.FormatOptions(def.Public(options))}
+}
+
 // CreateEmptyState creates a new state for the auction
 func (a Auction) CreateEmptyState() State {
-	if a.Type.Type == SingleSealedBid {
-		options := SealedBidOptions(a.Type.Options)
-		return NewSealedBidState(a.Expiry, options)
-	} else if a.Type.Type == TimedAscending {
-		options, err := ParseTimedAscendingOptions(a.Type.Options)
-		if err != nil {
-			// Fall back to default options if parsing fails
-			defaultOptions := DefaultTimedAscendingOptions()
-			return NewTimedAscendingState(a.StartsAt, a.Expiry, defaultOptions)
-		}
-		return NewTimedAscendingState(a.StartsAt, a.Expiry, *options)
-	}
-
-	// Default to a sealed bid auction if the type is unknown
-	return NewSealedBidState(a.Expiry, Blind)
+	def, ok := LookupAuctionType(a.Type.Options)
+	if !ok {
+		// Default to a sealed bid auction if the type is unknown
+		return NewSealedBidState(a.Expiry, Blind)
+	}
+
+	options, err := def.ParseOptions(a.Type.Options)
+	if err != nil {
+		// Fall back to default options if parsing fails
+		options = def.DefaultOptions()
+	}
+	return def.CreateEmptyState(a, options)
 }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -9,11 +10,17 @@ type Bid struct {
 	ForAuction AuctionId `json:"auction"`
 	Bidder     User      `json:"user"`
 	At         time.Time `json:"at"`
-	Amount     int64     `json:"amount"`
+	Amount     Amount    `json:"amount"`
+	// MaxAmount is an optional hidden maximum for proxy bidding.
+	// When set, timed ascending auctions bid on the bidder's behalf up to this amount.
+	MaxAmount Amount `json:"maxAmount,omitempty"`
+	// Quantity is the number of units bid for in multi-unit auctions, where Amount is
+	// the price per unit. Zero means a single unit.
+	Quantity int `json:"quantity,omitempty"`
 }
 
 // NewBid creates a new bid
-func NewBid(auctionId AuctionId, bidder User, at time.Time, amount int64) Bid {
+func NewBid(auctionId AuctionId, bidder User, at time.Time, amount Amount) Bid {
 	return Bid{
 		ForAuction: auctionId,
 		Bidder:     bidder,
@@ -21,3 +28,92 @@ func NewBid(auctionId AuctionId, bidder User, at time.Time, amount int64) Bid {
 		Amount:     amount,
 	}
 }
+
+// NewProxyBid creates a new bid with a hidden maximum amount
+func NewProxyBid(auctionId AuctionId, bidder User, at time.Time, amount Amount, maxAmount Amount) Bid {
+	return Bid{
+		ForAuction: auctionId,
+		Bidder:     bidder,
+		At:         at,
+		Amount:     amount,
+		MaxAmount:  maxAmount,
+	}
+}
+
+// Ceiling returns the highest amount the bidder is willing to pay
+func (b Bid) Ceiling() Amount {
+	if b.MaxAmount.GreaterThan(b.Amount) {
+		return b.MaxAmount
+	}
+	return b.Amount
+}
+
+// Units returns the number of units the bid is for
+func (b Bid) Units() int {
+	if b.Quantity == 0 {
+		return 1
+	}
+	return b.Quantity
+}
+
+// InCurrency returns the bid with its amounts in the given currency.
+// It returns a CurrencyMismatch error if an amount is in another currency.
+func (b Bid) InCurrency(currency Currency) (Bid, error) {
+	amount, err := b.Amount.InCurrency(currency)
+	if err != nil {
+		return b, err
+	}
+	b.Amount = amount
+
+	if b.MaxAmount != (Amount{}) {
+		maxAmount, err := b.MaxAmount.InCurrency(currency)
+		if err != nil {
+			return b, err
+		}
+		b.MaxAmount = maxAmount
+	}
+	return b, nil
+}
+
+type bidJSON struct {
+	ForAuction AuctionId `json:"auction"`
+	Bidder     User      `json:"user"`
+	At         time.Time `json:"at"`
+	Amount     Amount    `json:"amount"`
+	MaxAmount  *Amount   `json:"maxAmount,omitempty"`
+	Quantity   int       `json:"quantity,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler interface for Bid, leaving out an unset maximum
+func (b Bid) MarshalJSON() ([]byte, error) {
+	bid := bidJSON{
+		ForAuction: b.ForAuction,
+		Bidder:     b.Bidder,
+		At:         b.At,
+		Amount:     b.Amount,
+		Quantity:   b.Quantity,
+	}
+	if b.MaxAmount != (Amount{}) {
+		bid.MaxAmount = &b.MaxAmount
+	}
+	return json.Marshal(bid)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for Bid
+func (b *Bid) UnmarshalJSON(data []byte) error {
+	var bid bidJSON
+	if err := json.Unmarshal(data, &bid); err != nil {
+		return err
+	}
+
+	b.ForAuction = bid.ForAuction
+	b.Bidder = bid.Bidder
+	b.At = bid.At
+	b.Amount = bid.Amount
+	b.Quantity = bid.Quantity
+	b.MaxAmount = Amount{}
+	if bid.MaxAmount != nil {
+		b.MaxAmount = *bid.MaxAmount
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"time"
+)
+
+// CancelledState represents an auction that was cancelled before it ended.
+// It keeps the state at the time of cancellation so that its bids remain visible.
+type CancelledState struct {
+	state State
+	at    time.Time
+}
+
+// NewCancelledState creates the state of an auction cancelled at the given time
+func NewCancelledState(state State, at time.Time) *CancelledState {
+	return &CancelledState{
+		state: state,
+		at:    at,
+	}
+}
+
+// CancelledAt returns the time the auction was cancelled
+func (s *CancelledState) CancelledAt() time.Time {
+	return s.at
+}
+
+// Increment advances the state based on the current time
+func (s *CancelledState) Increment(now time.Time) State {
+	// A cancelled auction never changes
+	return s
+}
+
+// AddBid attempts to add a bid to the state
+func (s *CancelledState) AddBid(bid Bid) (State, error) {
+	return s, NewAuctionCancelledError(bid.ForAuction)
+}
+
+// GetBids returns the bids placed before the auction was cancelled
+func (s *CancelledState) GetBids() []Bid {
+	return s.state.GetBids()
+}
+
+// TryGetAmountAndWinner attempts to get the winning amount and bidder
+// A cancelled auction has no winner
+func (s *CancelledState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	return Amount{}, "", false
+}
+
+// HasEnded returns true if the auction has ended
+func (s *CancelledState) HasEnded() bool {
+	return true
+}
+
+// RetractBids removes all bids of the bidder from the state
+func (s *CancelledState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	return retractFromEnded(s, bidder, NewAuctionCancelledError)
+}
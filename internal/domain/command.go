@@ -9,6 +9,8 @@ import (
 // Command interface represents a command in the system
 type Command interface {
 	GetTime() time.Time
+	// GetAuctionId returns the ID of the auction the command applies to
+	GetAuctionId() AuctionId
 }
 
 // AddAuctionCommand represents a command to add a new auction
@@ -22,6 +24,11 @@ func (c AddAuctionCommand) GetTime() time.Time {
 	return c.Time
 }
 
+// GetAuctionId returns the ID of the auction being added
+func (c AddAuctionCommand) GetAuctionId() AuctionId {
+	return c.Auction.ID
+}
+
 // PlaceBidCommand represents a command to place a bid on an auction
 type PlaceBidCommand struct {
 	Time time.Time `json:"at"`
@@ -33,9 +40,70 @@ func (c PlaceBidCommand) GetTime() time.Time {
 	return c.Time
 }
 
+// GetAuctionId returns the ID of the auction the bid is placed on
+func (c PlaceBidCommand) GetAuctionId() AuctionId {
+	return c.Bid.ForAuction
+}
+
+// BuyNowCommand represents a command to buy an item at the buy-now price of its auction
+type BuyNowCommand struct {
+	Time      time.Time `json:"at"`
+	AuctionId AuctionId `json:"auction"`
+	Buyer     User      `json:"buyer"`
+}
+
+// GetTime returns the time of the command
+func (c BuyNowCommand) GetTime() time.Time {
+	return c.Time
+}
+
+// GetAuctionId returns the ID of the auction the item is bought from
+func (c BuyNowCommand) GetAuctionId() AuctionId {
+	return c.AuctionId
+}
+
+// CancelAuctionCommand represents a command to cancel an auction before it ends
+type CancelAuctionCommand struct {
+	Time        time.Time `json:"at"`
+	AuctionId   AuctionId `json:"auction"`
+	CancelledBy User      `json:"cancelledBy"`
+	Reason      string    `json:"reason"`
+}
+
+// GetTime returns the time of the command
+func (c CancelAuctionCommand) GetTime() time.Time {
+	return c.Time
+}
+
+// GetAuctionId returns the ID of the auction being cancelled
+func (c CancelAuctionCommand) GetAuctionId() AuctionId {
+	return c.AuctionId
+}
+
+// RetractBidCommand represents a command to retract the bids of a bidder from an auction
+type RetractBidCommand struct {
+	Time        time.Time `json:"at"`
+	AuctionId   AuctionId `json:"auction"`
+	Bidder      UserId    `json:"bidder"`
+	RetractedBy User      `json:"retractedBy"`
+	Reason      string    `json:"reason"`
+}
+
+// GetTime returns the time of the command
+func (c RetractBidCommand) GetTime() time.Time {
+	return c.Time
+}
+
+// GetAuctionId returns the ID of the auction the bids are retracted from
+func (c RetractBidCommand) GetAuctionId() AuctionId {
+	return c.AuctionId
+}
+
 // Event interface represents an event in the system
 type Event interface {
 	GetTime() time.Time
+	// GetAuctionId returns the ID of the auction the event applies to
+	GetAuctionId() AuctionId
 }
 
 // AuctionAddedEvent represents an event indicating an auction was added
@@ -49,6 +117,11 @@ func (e AuctionAddedEvent) GetTime() time.Time {
 	return e.Time
 }
 
+// GetAuctionId returns the ID of the auction that was added
+func (e AuctionAddedEvent) GetAuctionId() AuctionId {
+	return e.Auction.ID
+}
+
 // BidAcceptedEvent represents an event indicating a bid was accepted
 type BidAcceptedEvent struct {
 	Time time.Time `json:"at"`
@@ -60,6 +133,102 @@ func (e BidAcceptedEvent) GetTime() time.Time {
 	return e.Time
 }
 
+// GetAuctionId returns the ID of the auction the bid was accepted for
+func (e BidAcceptedEvent) GetAuctionId() AuctionId {
+	return e.Bid.ForAuction
+}
+
+// AuctionEndedEvent represents an event indicating an auction has closed
+type AuctionEndedEvent struct {
+	Time      time.Time `json:"at"`
+	AuctionId AuctionId `json:"auction"`
+}
+
+// GetTime returns the time of the event
+func (e AuctionEndedEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the auction that has closed
+func (e AuctionEndedEvent) GetAuctionId() AuctionId {
+	return e.AuctionId
+}
+
+// AuctionWonEvent represents an event indicating a closed auction has a winner
+type AuctionWonEvent struct {
+	Time      time.Time `json:"at"`
+	AuctionId AuctionId `json:"auction"`
+	Winner    UserId    `json:"winner"`
+	Price     Amount    `json:"price"`
+	// Quantity is the number of units won in a multi-unit auction, where Price is per unit
+	Quantity int `json:"quantity,omitempty"`
+}
+
+// GetTime returns the time of the event
+func (e AuctionWonEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the auction that was won
+func (e AuctionWonEvent) GetAuctionId() AuctionId {
+	return e.AuctionId
+}
+
+// AuctionUnsoldEvent represents an event indicating a closed auction has no winner
+type AuctionUnsoldEvent struct {
+	Time      time.Time `json:"at"`
+	AuctionId AuctionId `json:"auction"`
+	// ReserveNotMet is true when there were bids but none reached the reserve price
+	ReserveNotMet bool `json:"reserveNotMet"`
+}
+
+// GetTime returns the time of the event
+func (e AuctionUnsoldEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the auction that was not sold
+func (e AuctionUnsoldEvent) GetAuctionId() AuctionId {
+	return e.AuctionId
+}
+
+// AuctionCancelledEvent represents an event indicating an auction was cancelled
+type AuctionCancelledEvent struct {
+	Time        time.Time `json:"at"`
+	AuctionId   AuctionId `json:"auction"`
+	CancelledBy User      `json:"cancelledBy"`
+	Reason      string    `json:"reason"`
+}
+
+// GetTime returns the time of the event
+func (e AuctionCancelledEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the auction that was cancelled
+func (e AuctionCancelledEvent) GetAuctionId() AuctionId {
+	return e.AuctionId
+}
+
+// BidRetractedEvent represents an event indicating the bids of a bidder were retracted
+type BidRetractedEvent struct {
+	Time        time.Time `json:"at"`
+	AuctionId   AuctionId `json:"auction"`
+	Bidder      UserId    `json:"bidder"`
+	RetractedBy User      `json:"retractedBy"`
+	Reason      string    `json:"reason"`
+}
+
+// GetTime returns the time of the event
+func (e BidRetractedEvent) GetTime() time.Time {
+	return e.Time
+}
+
+// GetAuctionId returns the ID of the auction the bids were retracted from
+func (e BidRetractedEvent) GetAuctionId() AuctionId {
+	return e.AuctionId
+}
+
 // UnmarshalJSON implements json.Unmarshaler interface for Command
 func UnmarshalCommand(data []byte) (Command, error) {
 	var typeCheck struct {
@@ -82,6 +251,36 @@ func UnmarshalCommand(data []byte) (Command, error) {
 			return nil, err
 		}
 		return cmd, nil
+	case "BuyNow":
+		var cmd BuyNowCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return nil, err
+		}
+		return cmd, nil
+	case "CancelAuction":
+		var cmd CancelAuctionCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return nil, err
+		}
+		return cmd, nil
+	case "RetractBid":
+		var cmd RetractBidCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return nil, err
+		}
+		return cmd, nil
+	case "CommandOutcome":
+		var outcome CommandOutcome
+		if err := json.Unmarshal(data, &outcome); err != nil {
+			return nil, err
+		}
+		return outcome, nil
+	case "IdempotentResponse":
+		var response IdempotentResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		return response, nil
 	default:
 		return nil, fmt.Errorf("unknown command type: %s", typeCheck.Type)
 	}
@@ -115,6 +314,60 @@ func (c PlaceBidCommand) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// MarshalJSON implements json.Marshaler interface for BuyNowCommand
+func (c BuyNowCommand) MarshalJSON() ([]byte, error) {
+	type buyNowCommandJSON struct {
+		Type      string    `json:"$type"`
+		Time      time.Time `json:"at"`
+		AuctionId AuctionId `json:"auction"`
+		Buyer     User      `json:"buyer"`
+	}
+	return json.Marshal(buyNowCommandJSON{
+		Type:      "BuyNow",
+		Time:      c.Time,
+		AuctionId: c.AuctionId,
+		Buyer:     c.Buyer,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for CancelAuctionCommand
+func (c CancelAuctionCommand) MarshalJSON() ([]byte, error) {
+	type cancelAuctionCommandJSON struct {
+		Type        string    `json:"$type"`
+		Time        time.Time `json:"at"`
+		AuctionId   AuctionId `json:"auction"`
+		CancelledBy User      `json:"cancelledBy"`
+		Reason      string    `json:"reason"`
+	}
+	return json.Marshal(cancelAuctionCommandJSON{
+		Type:        "CancelAuction",
+		Time:        c.Time,
+		AuctionId:   c.AuctionId,
+		CancelledBy: c.CancelledBy,
+		Reason:      c.Reason,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for RetractBidCommand
+func (c RetractBidCommand) MarshalJSON() ([]byte, error) {
+	type retractBidCommandJSON struct {
+		Type        string    `json:"$type"`
+		Time        time.Time `json:"at"`
+		AuctionId   AuctionId `json:"auction"`
+		Bidder      UserId    `json:"bidder"`
+		RetractedBy User      `json:"retractedBy"`
+		Reason      string    `json:"reason"`
+	}
+	return json.Marshal(retractBidCommandJSON{
+		Type:        "RetractBid",
+		Time:        c.Time,
+		AuctionId:   c.AuctionId,
+		Bidder:      c.Bidder,
+		RetractedBy: c.RetractedBy,
+		Reason:      c.Reason,
+	})
+}
+
 // UnmarshalJSON implements json.Unmarshaler interface for Event
 func UnmarshalEvent(data []byte) (Event, error) {
 	var typeCheck struct {
@@ -137,6 +390,42 @@ func UnmarshalEvent(data []byte) (Event, error) {
 			return nil, err
 		}
 		return evt, nil
+	case "AuctionEnded":
+		var evt AuctionEndedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case "AuctionWon":
+		var evt AuctionWonEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case "AuctionUnsold":
+		var evt AuctionUnsoldEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case "AuctionCancelled":
+		var evt AuctionCancelledEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case "BidRetracted":
+		var evt BidRetractedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
+	case "AuctionArchived":
+		var evt AuctionArchivedEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return nil, err
+		}
+		return evt, nil
 	default:
 		return nil, fmt.Errorf("unknown event type: %s", typeCheck.Type)
 	}
@@ -170,6 +459,94 @@ func (e BidAcceptedEvent) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// MarshalJSON implements json.Marshaler interface for AuctionEndedEvent
+func (e AuctionEndedEvent) MarshalJSON() ([]byte, error) {
+	type auctionEndedEventJSON struct {
+		Type      string    `json:"$type"`
+		Time      time.Time `json:"at"`
+		AuctionId AuctionId `json:"auction"`
+	}
+	return json.Marshal(auctionEndedEventJSON{
+		Type:      "AuctionEnded",
+		Time:      e.Time,
+		AuctionId: e.AuctionId,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for AuctionWonEvent
+func (e AuctionWonEvent) MarshalJSON() ([]byte, error) {
+	type auctionWonEventJSON struct {
+		Type      string    `json:"$type"`
+		Time      time.Time `json:"at"`
+		AuctionId AuctionId `json:"auction"`
+		Winner    UserId    `json:"winner"`
+		Price     Amount    `json:"price"`
+		Quantity  int       `json:"quantity,omitempty"`
+	}
+	return json.Marshal(auctionWonEventJSON{
+		Type:      "AuctionWon",
+		Time:      e.Time,
+		AuctionId: e.AuctionId,
+		Winner:    e.Winner,
+		Price:     e.Price,
+		Quantity:  e.Quantity,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for AuctionUnsoldEvent
+func (e AuctionUnsoldEvent) MarshalJSON() ([]byte, error) {
+	type auctionUnsoldEventJSON struct {
+		Type          string    `json:"$type"`
+		Time          time.Time `json:"at"`
+		AuctionId     AuctionId `json:"auction"`
+		ReserveNotMet bool      `json:"reserveNotMet"`
+	}
+	return json.Marshal(auctionUnsoldEventJSON{
+		Type:          "AuctionUnsold",
+		Time:          e.Time,
+		AuctionId:     e.AuctionId,
+		ReserveNotMet: e.ReserveNotMet,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for AuctionCancelledEvent
+func (e AuctionCancelledEvent) MarshalJSON() ([]byte, error) {
+	type auctionCancelledEventJSON struct {
+		Type        string    `json:"$type"`
+		Time        time.Time `json:"at"`
+		AuctionId   AuctionId `json:"auction"`
+		CancelledBy User      `json:"cancelledBy"`
+		Reason      string    `json:"reason"`
+	}
+	return json.Marshal(auctionCancelledEventJSON{
+		Type:        "AuctionCancelled",
+		Time:        e.Time,
+		AuctionId:   e.AuctionId,
+		CancelledBy: e.CancelledBy,
+		Reason:      e.Reason,
+	})
+}
+
+// MarshalJSON implements json.Marshaler interface for BidRetractedEvent
+func (e BidRetractedEvent) MarshalJSON() ([]byte, error) {
+	type bidRetractedEventJSON struct {
+		Type        string    `json:"$type"`
+		Time        time.Time `json:"at"`
+		AuctionId   AuctionId `json:"auction"`
+		Bidder      UserId    `json:"bidder"`
+		RetractedBy User      `json:"retractedBy"`
+		Reason      string    `json:"reason"`
+	}
+	return json.Marshal(bidRetractedEventJSON{
+		Type:        "BidRetracted",
+		Time:        e.Time,
+		AuctionId:   e.AuctionId,
+		Bidder:      e.Bidder,
+		RetractedBy: e.RetractedBy,
+		Reason:      e.Reason,
+	})
+}
+
 // Repository represents a repository of auctions
 type Repository map[AuctionId]struct {
 	Auction Auction
@@ -178,35 +555,87 @@ type Repository map[AuctionId]struct {
 
 // EventsToAuctionStates folds a list of events into a repository
 func EventsToAuctionStates(events []Event) Repository {
-	repo := make(Repository)
-	
+	return ApplyEvents(make(Repository), events)
+}
+
+// ApplyEvents folds a list of events into an existing repository, which is updated in place
+func ApplyEvents(repo Repository, events []Event) Repository {
 	for _, event := range events {
-		switch e := event.(type) {
-		case AuctionAddedEvent:
-			auction := e.Auction
-			state := auction.CreateEmptyState()
-			repo[auction.ID] = struct {
+		ApplyEvent(repo, event)
+	}
+	return repo
+}
+
+// ApplyEvent folds a single event into an existing repository, which is updated in place,
+// so that a log can be replayed one event at a time without holding it in memory
+func ApplyEvent(repo Repository, event Event) Repository {
+	switch e := event.(type) {
+	case AuctionAddedEvent:
+		auction := e.Auction
+		state := auction.CreateEmptyState()
+		repo[auction.ID] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: auction,
+			State:   state,
+		}
+	case BidAcceptedEvent:
+		bid := e.Bid
+		if entry, ok := repo[bid.ForAuction]; ok {
+			// Bids recorded before amounts carried a currency are in the auction's currency
+			if inCurrency, err := bid.InCurrency(entry.Auction.Currency); err == nil {
+				bid = inCurrency
+			}
+			nextState, _ := entry.State.AddBid(bid)
+			repo[bid.ForAuction] = struct {
+				Auction Auction
+				State   State
+			}{
+				Auction: entry.Auction,
+				State:   nextState,
+			}
+		}
+	case AuctionEndedEvent:
+		if entry, ok := repo[e.AuctionId]; ok {
+			repo[e.AuctionId] = struct {
+				Auction Auction
+				State   State
+			}{
+				Auction: entry.Auction,
+				State:   entry.State.Increment(e.Time),
+			}
+		}
+	case AuctionCancelledEvent:
+		if entry, ok := repo[e.AuctionId]; ok {
+			repo[e.AuctionId] = struct {
 				Auction Auction
 				State   State
 			}{
-				Auction: auction,
-				State:   state,
+				Auction: entry.Auction,
+				State:   NewCancelledState(entry.State, e.Time),
 			}
-		case BidAcceptedEvent:
-			bid := e.Bid
-			if entry, ok := repo[bid.ForAuction]; ok {
-				nextState, _ := entry.State.AddBid(bid)
-				repo[bid.ForAuction] = struct {
-					Auction Auction
-					State   State
-				}{
-					Auction: entry.Auction,
-					State:   nextState,
-				}
+		}
+	case BidRetractedEvent:
+		if entry, ok := repo[e.AuctionId]; ok {
+			nextState, _ := entry.State.RetractBids(e.Bidder, e.Time)
+			repo[e.AuctionId] = struct {
+				Auction Auction
+				State   State
+			}{
+				Auction: entry.Auction,
+				State:   nextState,
 			}
 		}
+	case AuctionArchivedEvent:
+		repo[e.Auction.ID] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: e.Auction,
+			State:   e.State,
+		}
 	}
-	
 	return repo
 }
 
@@ -218,6 +647,13 @@ func Handle(cmd Command, repo Repository) (Event, Repository, error) {
 		if _, exists := repo[auction.ID]; exists {
 			return nil, repo, NewAuctionAlreadyExistsError(auction.ID)
 		}
+
+		if !auction.Currency.IsKnown() {
+			return nil, repo, NewUnknownCurrencyError(auction.Currency)
+		}
+		if err := auction.ValidateOptions(); err != nil {
+			return nil, repo, err
+		}
 		
 		// Create new state
 		state := auction.CreateEmptyState()
@@ -250,6 +686,12 @@ func Handle(cmd Command, repo Repository) (Event, Repository, error) {
 		if err := entry.Auction.ValidateBid(bid); err != nil {
 			return nil, repo, err
 		}
+
+		// Amounts without a currency are in the auction's currency
+		bid, err := bid.InCurrency(entry.Auction.Currency)
+		if err != nil {
+			return nil, repo, err
+		}
 		
 		// Add bid to state
 		nextState, err := entry.State.AddBid(bid)
@@ -271,11 +713,201 @@ func Handle(cmd Command, repo Repository) (Event, Repository, error) {
 			Time: c.Time,
 			Bid:  bid,
 		}, newRepo, nil
+
+	case BuyNowCommand:
+		entry, exists := repo[c.AuctionId]
+		if !exists {
+			return nil, repo, NewAuctionNotFoundError(c.AuctionId)
+		}
+
+		if c.Buyer.ID == entry.Auction.Seller.ID {
+			return nil, repo, NewSellerCannotPlaceBidsError(c.Buyer.ID, c.AuctionId)
+		}
+
+		// The purchase is a bid at the buy-now price, so that it is replayed like any other bid
+		var price Amount
+		switch current := entry.State.Increment(c.Time).(type) {
+		case *OngoingState:
+			buyNowPrice, ok := current.BuyNowPrice()
+			if !ok {
+				return nil, repo, NewBuyNowNotAvailableError(c.AuctionId)
+			}
+			price = buyNowPrice
+		case *AwaitingStartState:
+			return nil, repo, NewAuctionHasNotStartedError(c.AuctionId)
+		case *CancelledState:
+			return nil, repo, NewAuctionCancelledError(c.AuctionId)
+		default:
+			if current.HasEnded() {
+				return nil, repo, NewAuctionHasEndedError(c.AuctionId)
+			}
+			return nil, repo, NewBuyNowNotAvailableError(c.AuctionId)
+		}
+
+		bid := NewBid(c.AuctionId, c.Buyer, c.Time, price)
+		nextState, err := entry.State.AddBid(bid)
+		if err != nil {
+			return nil, repo, err
+		}
+
+		newRepo := copyRepository(repo)
+		newRepo[c.AuctionId] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: entry.Auction,
+			State:   nextState,
+		}
+
+		return BidAcceptedEvent{
+			Time: c.Time,
+			Bid:  bid,
+		}, newRepo, nil
+
+	case CancelAuctionCommand:
+		entry, exists := repo[c.AuctionId]
+		if !exists {
+			return nil, repo, NewAuctionNotFoundError(c.AuctionId)
+		}
+
+		if c.Reason == "" {
+			return nil, repo, NewReasonRequiredError()
+		}
+
+		// Cancellation is only possible while the auction is running
+		if _, cancelled := entry.State.(*CancelledState); cancelled {
+			return nil, repo, NewAuctionCancelledError(c.AuctionId)
+		}
+		current := entry.State.Increment(c.Time)
+		if current.HasEnded() {
+			return nil, repo, NewAuctionHasEndedError(c.AuctionId)
+		}
+
+		// Support may always cancel; the seller only before the first bid
+		isSeller := c.CancelledBy.ID == entry.Auction.Seller.ID
+		if !c.CancelledBy.IsSupport() && !(isSeller && len(current.GetBids()) == 0) {
+			return nil, repo, NewNotAuthorizedError(c.CancelledBy.ID, c.AuctionId)
+		}
+
+		newRepo := copyRepository(repo)
+		newRepo[c.AuctionId] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: entry.Auction,
+			State:   NewCancelledState(entry.State, c.Time),
+		}
+
+		return AuctionCancelledEvent{
+			Time:        c.Time,
+			AuctionId:   c.AuctionId,
+			CancelledBy: c.CancelledBy,
+			Reason:      c.Reason,
+		}, newRepo, nil
+
+	case RetractBidCommand:
+		entry, exists := repo[c.AuctionId]
+		if !exists {
+			return nil, repo, NewAuctionNotFoundError(c.AuctionId)
+		}
+
+		if !c.RetractedBy.IsSupport() {
+			return nil, repo, NewNotAuthorizedError(c.RetractedBy.ID, c.AuctionId)
+		}
+
+		if c.Reason == "" {
+			return nil, repo, NewReasonRequiredError()
+		}
+
+		nextState, err := entry.State.RetractBids(c.Bidder, c.Time)
+		if err != nil {
+			return nil, repo, err
+		}
+
+		newRepo := copyRepository(repo)
+		newRepo[c.AuctionId] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: entry.Auction,
+			State:   nextState,
+		}
+
+		return BidRetractedEvent{
+			Time:        c.Time,
+			AuctionId:   c.AuctionId,
+			Bidder:      c.Bidder,
+			RetractedBy: c.RetractedBy,
+			Reason:      c.Reason,
+		}, newRepo, nil
 	}
 	
 	return nil, repo, fmt.Errorf("unknown command type")
 }
 
+// CloseAuction returns the events announcing that an auction has closed:
+// an AuctionEndedEvent followed by either an AuctionWonEvent for every winner or an
+// AuctionUnsoldEvent. The state is expected to have been incremented to the closing time.
+func CloseAuction(at time.Time, auction Auction, state State) []Event {
+	events := []Event{AuctionEndedEvent{
+		Time:      at,
+		AuctionId: auction.ID,
+	}}
+
+	if multiUnit, ok := state.(allocating); ok {
+		for _, allocation := range multiUnit.Allocations() {
+			events = append(events, AuctionWonEvent{
+				Time:      at,
+				AuctionId: auction.ID,
+				Winner:    allocation.Bidder,
+				Price:     allocation.Price,
+				Quantity:  allocation.Quantity,
+			})
+		}
+		if len(events) > 1 {
+			return events
+		}
+	}
+
+	if amount, winner, found := state.TryGetAmountAndWinner(); found {
+		return append(events, AuctionWonEvent{
+			Time:      at,
+			AuctionId: auction.ID,
+			Winner:    winner,
+			Price:     amount,
+		})
+	}
+
+	return append(events, AuctionUnsoldEvent{
+		Time:          at,
+		AuctionId:     auction.ID,
+		ReserveNotMet: len(state.GetBids()) > 0,
+	})
+}
+
+// ClosedAuctions returns the IDs of the auctions for which an AuctionEndedEvent,
+// an AuctionCancelledEvent or an AuctionArchivedEvent has been recorded
+func ClosedAuctions(events []Event) map[AuctionId]bool {
+	closed := make(map[AuctionId]bool)
+	for _, event := range events {
+		MarkClosed(closed, event)
+	}
+	return closed
+}
+
+// MarkClosed records the auction of an AuctionEndedEvent, an AuctionCancelledEvent
+// or an AuctionArchivedEvent as closed
+func MarkClosed(closed map[AuctionId]bool, event Event) {
+	switch e := event.(type) {
+	case AuctionEndedEvent:
+		closed[e.AuctionId] = true
+	case AuctionCancelledEvent:
+		closed[e.AuctionId] = true
+	case AuctionArchivedEvent:
+		closed[e.Auction.ID] = true
+	}
+}
+
 // copyRepository creates a copy of the repository
 func copyRepository(repo Repository) Repository {
 	newRepo := make(Repository)
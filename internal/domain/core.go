@@ -36,6 +36,11 @@ func NewSupport(id UserId) User {
 	}
 }
 
+// IsSupport returns true if the user is a support user
+func (u User) IsSupport() bool {
+	return u.Type == "Support"
+}
+
 // MarshalJSON implements json.Marshaler interface
 func (u User) MarshalJSON() ([]byte, error) {
 	var s string
@@ -89,6 +94,21 @@ const (
 	ErrorSellerCannotPlaceBids   ErrorType = "SellerCannotPlaceBids"
 	ErrorMustPlaceBidOverHighest ErrorType = "MustPlaceBidOverHighestBid"
 	ErrorAlreadyPlacedBid        ErrorType = "AlreadyPlacedBid"
+	ErrorAuctionCancelled        ErrorType = "AuctionCancelled"
+	ErrorBidNotFound             ErrorType = "BidNotFound"
+	ErrorNotAuthorized           ErrorType = "NotAuthorized"
+	ErrorReasonRequired          ErrorType = "ReasonRequired"
+	ErrorCurrencyMismatch        ErrorType = "CurrencyMismatch"
+	ErrorUnknownCurrency         ErrorType = "UnknownCurrency"
+	ErrorAmountOverflow          ErrorType = "AmountOverflow"
+	ErrorBidAmountNotPositive    ErrorType = "BidAmountMustBePositive"
+	ErrorNegativeAmount          ErrorType = "AmountMustNotBeNegative"
+	ErrorAmountNotPositive       ErrorType = "AmountMustBePositive"
+	ErrorFloorAboveStartPrice    ErrorType = "FloorPriceMustNotExceedStartPrice"
+	ErrorBuyNowBelowReserve      ErrorType = "BuyNowPriceMustExceedReserve"
+	ErrorBuyNowNotAvailable      ErrorType = "BuyNowNotAvailable"
+	ErrorIncrementsNotAscending  ErrorType = "IncrementTableMustAscend"
+	ErrorInvalidQuantity         ErrorType = "InvalidQuantity"
 )
 
 // DomainError carries a stable code (Type) and optional structured Data.
@@ -147,7 +167,7 @@ func NewSellerCannotPlaceBidsError(userId UserId, auctionId AuctionId) error {
 }
 
 // NewMustPlaceBidOverHighestError creates a new MustPlaceBidOverHighest error
-func NewMustPlaceBidOverHighestError(amount int64) error {
+func NewMustPlaceBidOverHighestError(amount Amount) error {
 	return DomainError{
 		Type: ErrorMustPlaceBidOverHighest,
 		Data: amount,
@@ -160,3 +180,161 @@ func NewAlreadyPlacedBidError() error {
 		Type: ErrorAlreadyPlacedBid,
 	}
 }
+
+// NewAuctionCancelledError creates a new AuctionCancelled error
+func NewAuctionCancelledError(id AuctionId) error {
+	return DomainError{
+		Type: ErrorAuctionCancelled,
+		Data: id,
+	}
+}
+
+// NewBidNotFoundError creates a new BidNotFound error
+func NewBidNotFoundError(userId UserId) error {
+	return DomainError{
+		Type: ErrorBidNotFound,
+		Data: userId,
+	}
+}
+
+// NewNotAuthorizedError creates a new NotAuthorized error
+func NewNotAuthorizedError(userId UserId, auctionId AuctionId) error {
+	return DomainError{
+		Type: ErrorNotAuthorized,
+		Data: map[string]interface{}{
+			"userId":    userId,
+			"auctionId": auctionId,
+		},
+	}
+}
+
+// NewSupportRequiredError creates a NotAuthorized error for something only support users may do
+func NewSupportRequiredError(userId UserId) error {
+	return DomainError{
+		Type: ErrorNotAuthorized,
+		Data: map[string]interface{}{
+			"userId": userId,
+		},
+	}
+}
+
+// NewReasonRequiredError creates a new ReasonRequired error
+func NewReasonRequiredError() error {
+	return DomainError{
+		Type: ErrorReasonRequired,
+	}
+}
+
+// NewCurrencyMismatchError creates a new CurrencyMismatch error for an amount in
+// another currency than the expected one
+func NewCurrencyMismatchError(expected, actual Currency) error {
+	return DomainError{
+		Type: ErrorCurrencyMismatch,
+		Data: map[string]interface{}{
+			"expected": expected,
+			"actual":   actual,
+		},
+	}
+}
+
+// NewUnknownCurrencyError creates a new UnknownCurrency error
+func NewUnknownCurrencyError(currency Currency) error {
+	return DomainError{
+		Type: ErrorUnknownCurrency,
+		Data: currency,
+	}
+}
+
+// NewAmountOverflowError creates a new AmountOverflow error for a calculation on
+// amounts whose result is too large to be represented
+func NewAmountOverflowError(amount, operand Amount) error {
+	return DomainError{
+		Type: ErrorAmountOverflow,
+		Data: map[string]interface{}{
+			"amount":  amount,
+			"operand": operand,
+		},
+	}
+}
+
+// NewBidAmountNotPositiveError creates a new BidAmountMustBePositive error
+func NewBidAmountNotPositiveError(amount Amount) error {
+	return DomainError{
+		Type: ErrorBidAmountNotPositive,
+		Data: amount,
+	}
+}
+
+// NewNegativeAmountError creates a new AmountMustNotBeNegative error for an
+// auction option that was given a negative amount
+func NewNegativeAmountError(option string, amount Amount) error {
+	return DomainError{
+		Type: ErrorNegativeAmount,
+		Data: map[string]interface{}{
+			"option": option,
+			"amount": amount,
+		},
+	}
+}
+
+// NewAmountNotPositiveError creates a new AmountMustBePositive error for an auction option
+func NewAmountNotPositiveError(option string, amount Amount) error {
+	return DomainError{
+		Type: ErrorAmountNotPositive,
+		Data: map[string]interface{}{
+			"option": option,
+			"amount": amount,
+		},
+	}
+}
+
+// NewFloorAboveStartPriceError creates a new FloorPriceMustNotExceedStartPrice error
+func NewFloorAboveStartPriceError(floorPrice, startPrice Amount) error {
+	return DomainError{
+		Type: ErrorFloorAboveStartPrice,
+		Data: map[string]interface{}{
+			"floorPrice": floorPrice,
+			"startPrice": startPrice,
+		},
+	}
+}
+
+// NewBuyNowBelowReserveError creates a new BuyNowPriceMustExceedReserve error
+func NewBuyNowBelowReserveError(buyNowPrice, reservePrice Amount) error {
+	return DomainError{
+		Type: ErrorBuyNowBelowReserve,
+		Data: map[string]interface{}{
+			"buyNowPrice":  buyNowPrice,
+			"reservePrice": reservePrice,
+		},
+	}
+}
+
+// NewIncrementsNotAscendingError creates a new IncrementTableMustAscend error for a tier
+// of an increment table that does not start above zero and above the tier before it
+func NewIncrementsNotAscendingError(from Amount) error {
+	return DomainError{
+		Type: ErrorIncrementsNotAscending,
+		Data: map[string]interface{}{"from": from},
+	}
+}
+
+// NewInvalidQuantityError creates a new InvalidQuantity error for a bid for a quantity
+// that is negative or more than the units for sale
+func NewInvalidQuantityError(quantity, units int) error {
+	return DomainError{
+		Type: ErrorInvalidQuantity,
+		Data: map[string]interface{}{
+			"quantity": quantity,
+			"units":    units,
+		},
+	}
+}
+
+// NewBuyNowNotAvailableError creates a new BuyNowNotAvailable error
+func NewBuyNowNotAvailableError(id AuctionId) error {
+	return DomainError{
+		Type: ErrorBuyNowNotAvailable,
+		Data: id,
+	}
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotentResponse is recorded in the command log after a request that carried an
+// idempotency key, so that a retry of the request with the same key can be answered
+// with the same response without handling its command again
+type IdempotentResponse struct {
+	Time      time.Time
+	AuctionId AuctionId
+	UserId    UserId
+	Key       string
+	// RequestHash identifies the request the key was first used with
+	RequestHash string
+	Status      int
+	Body        json.RawMessage
+}
+
+// GetTime returns the time the response was recorded
+func (r IdempotentResponse) GetTime() time.Time {
+	return r.Time
+}
+
+// GetAuctionId returns the ID of the auction the request was for
+func (r IdempotentResponse) GetAuctionId() AuctionId {
+	return r.AuctionId
+}
+
+type idempotentResponseJSON struct {
+	Type        string          `json:"$type"`
+	Time        time.Time       `json:"at"`
+	AuctionId   AuctionId       `json:"auction"`
+	UserId      UserId          `json:"user"`
+	Key         string          `json:"key"`
+	RequestHash string          `json:"requestHash"`
+	Status      int             `json:"status"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// MarshalJSON implements json.Marshaler interface for IdempotentResponse
+func (r IdempotentResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(idempotentResponseJSON{
+		Type:        "IdempotentResponse",
+		Time:        r.Time,
+		AuctionId:   r.AuctionId,
+		UserId:      r.UserId,
+		Key:         r.Key,
+		RequestHash: r.RequestHash,
+		Status:      r.Status,
+		Body:        r.Body,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for IdempotentResponse
+func (r *IdempotentResponse) UnmarshalJSON(data []byte) error {
+	var response idempotentResponseJSON
+	if err := json.Unmarshal(data, &response); err != nil {
+		return err
+	}
+
+	r.Time = response.Time
+	r.AuctionId = response.AuctionId
+	r.UserId = response.UserId
+	r.Key = response.Key
+	r.RequestHash = response.RequestHash
+	r.Status = response.Status
+	r.Body = response.Body
+	return nil
+}
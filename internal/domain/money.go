@@ -1,99 +1,256 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 )
 
-// Currency represents a monetary currency
-type Currency string
-
-const (
-	// VAC is virtual auction currency
-	VAC Currency = "VAC"
-	// SEK is Swedish Krona
-	SEK Currency = "SEK"
-	// DKK is Danish Krone
-	DKK Currency = "DKK"
-)
-
-// Amount represents a monetary amount in a specific currency
+// Amount represents a monetary amount in a specific currency.
+// The value is in the minor unit of the currency, so EUR12.50 has the value 1250.
+// Amounts read from logs and requests as plain numbers, like those written before amounts
+// carried a currency, have no currency: they are whole units of the currency of the auction
+// they belong to, and InCurrency scales them to its minor unit.
 type Amount struct {
 	Currency Currency `json:"currency"`
 	Value    int64    `json:"value"`
 }
 
-// String returns a string representation of the amount
+// NewAmount creates an amount of value minor units of the given currency
+func NewAmount(currency Currency, value int64) Amount {
+	return Amount{Currency: currency, Value: value}
+}
+
+// String returns a string representation of the amount, with as many decimals
+// as the currency has, like "EUR12.50" or "JPY1200"
 func (a Amount) String() string {
-	return fmt.Sprintf("%s%d", a.Currency, a.Value)
+	decimals := a.Currency.MinorUnits()
+	if decimals == 0 {
+		return fmt.Sprintf("%s%d", a.Currency, a.Value)
+	}
+
+	sign := ""
+	value := uint64(a.Value)
+	if a.Value < 0 {
+		sign = "-"
+		value = -value
+	}
+	unit := uint64(pow10(decimals))
+	return fmt.Sprintf("%s%s%d.%0*d", a.Currency, sign, value/unit, decimals, value%unit)
 }
 
-// ParseAmount parses a string into an Amount
+// amountPattern matches a currency code followed by a decimal number, like "VAC10" or "EUR12.50".
+// Negative numbers parse, so that the domain can reject them with a meaningful error.
+var amountPattern = regexp.MustCompile("^([A-Z]+)(-?)(\\d+)(?:\\.(\\d+))?$")
+
+// ParseAmount parses a string into an Amount. The number may have up to as many
+// decimals as the currency has.
 func ParseAmount(s string) (*Amount, error) {
 	if s == "" {
 		return nil, fmt.Errorf("empty amount string")
 	}
 
-	// Regex pattern to match currency letter and digit
-	// Like "VAC10" or "SEK100"
-	pattern := "^([A-Z]+)(\\d+)$"
-	re := regexp.MustCompile(pattern)
-
-	matches := re.FindStringSubmatch(s)
-	if len(matches) != 3 {
+	matches := amountPattern.FindStringSubmatch(s)
+	if len(matches) != 5 {
 		return nil, fmt.Errorf("invalid amount format: %s", s)
 	}
 
-	currencyStr := matches[1]
-	valueStr := matches[2]
+	currency := Currency(matches[1])
+	sign, wholeStr, fractionStr := matches[2], matches[3], matches[4]
 
-	value, err := strconv.ParseInt(valueStr, 10, 64)
+	decimals := currency.MinorUnits()
+	if len(fractionStr) > decimals {
+		return nil, fmt.Errorf("too many decimals for %s: %s", currency, s)
+	}
+
+	whole, err := strconv.ParseInt(wholeStr, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid amount value: %s", valueStr)
+		return nil, fmt.Errorf("invalid amount value: %s", wholeStr)
+	}
+	var fraction int64
+	if fractionStr != "" {
+		fraction, _ = strconv.ParseInt(fractionStr, 10, 64)
+		fraction *= pow10(decimals - len(fractionStr))
 	}
 
+	unit := pow10(decimals)
+	if whole > (math.MaxInt64-fraction)/unit {
+		return nil, fmt.Errorf("amount out of range: %s", s)
+	}
+
+	value := whole*unit + fraction
+	if sign == "-" {
+		value = -value
+	}
 	return &Amount{
-		Currency: Currency(currencyStr),
+		Currency: currency,
 		Value:    value,
 	}, nil
 }
 
-// Add adds two amounts together, returning a new amount
-// Both amounts must have the same currency
+// pow10 returns 10 to the power of n, for the few decimals currencies have
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// parseAmountOrValue parses an amount like "SEK100", or a plain value like "100"
+// as an amount without a currency, in whole units
+func parseAmountOrValue(s string) (Amount, error) {
+	if value, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Amount{Value: value}, nil
+	}
+
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return Amount{}, err
+	}
+	return *amount, nil
+}
+
+// InCurrency returns the amount in the given currency. An amount without a currency
+// is whole units of the currency, so in SEK the plain value 100 becomes SEK100.00;
+// an amount in another currency is a CurrencyMismatch error.
+func (a Amount) InCurrency(currency Currency) (Amount, error) {
+	if a.Currency == "" {
+		unit := pow10(currency.MinorUnits())
+		if a.Value > math.MaxInt64/unit || a.Value < math.MinInt64/unit {
+			return a, NewAmountOverflowError(a, NewAmount(currency, unit))
+		}
+		return Amount{Currency: currency, Value: a.Value * unit}, nil
+	}
+	if a.Currency != currency {
+		return a, NewCurrencyMismatchError(currency, a.Currency)
+	}
+	return a, nil
+}
+
+// currencyWith returns the currency of an operation on a and b.
+// An amount without a currency is in the currency of the other amount.
+func (a Amount) currencyWith(b Amount) (Currency, bool) {
+	switch {
+	case a.Currency == b.Currency || b.Currency == "":
+		return a.Currency, true
+	case a.Currency == "":
+		return b.Currency, true
+	default:
+		return "", false
+	}
+}
+
+// Add adds two amounts together, returning a new amount.
+// Both amounts must have the same currency, and the sum must fit in an int64;
+// otherwise a CurrencyMismatch or AmountOverflow error is returned.
 func (a Amount) Add(b Amount) (Amount, error) {
-	if a.Currency != b.Currency {
-		return Amount{}, fmt.Errorf("cannot add amounts with different currencies: %s and %s", a.Currency, b.Currency)
+	currency, ok := a.currencyWith(b)
+	if !ok {
+		return Amount{}, NewCurrencyMismatchError(a.Currency, b.Currency)
+	}
+	if (b.Value > 0 && a.Value > math.MaxInt64-b.Value) || (b.Value < 0 && a.Value < math.MinInt64-b.Value) {
+		return Amount{}, NewAmountOverflowError(a, b)
 	}
 	return Amount{
-		Currency: a.Currency,
+		Currency: currency,
 		Value:    a.Value + b.Value,
 	}, nil
 }
 
-// GreaterThan returns true if a is greater than b
+// Sub subtracts b from a, returning a new amount.
+// It fails like Add if the currencies differ or the difference does not fit in an int64.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	currency, ok := a.currencyWith(b)
+	if !ok {
+		return Amount{}, NewCurrencyMismatchError(a.Currency, b.Currency)
+	}
+	if (b.Value < 0 && a.Value > math.MaxInt64+b.Value) || (b.Value > 0 && a.Value < math.MinInt64+b.Value) {
+		return Amount{}, NewAmountOverflowError(a, b)
+	}
+	return Amount{
+		Currency: currency,
+		Value:    a.Value - b.Value,
+	}, nil
+}
+
+// isAmountOverflow returns true if err is an AmountOverflow error
+func isAmountOverflow(err error) bool {
+	domainErr, ok := err.(DomainError)
+	return ok && domainErr.Type == ErrorAmountOverflow
+}
+
+// Compare returns -1, 0 or 1 as a is less than, equal to or greater than b.
+// Amounts in different currencies cannot be compared and give a CurrencyMismatch error.
+func (a Amount) Compare(b Amount) (int, error) {
+	if _, ok := a.currencyWith(b); !ok {
+		return 0, NewCurrencyMismatchError(a.Currency, b.Currency)
+	}
+	switch {
+	case a.Value < b.Value:
+		return -1, nil
+	case a.Value > b.Value:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// GreaterThan returns true if a is greater than b.
+// Amounts in different currencies are not greater than each other.
 func (a Amount) GreaterThan(b Amount) bool {
-	return a.Currency == b.Currency && a.Value > b.Value
+	c, err := a.Compare(b)
+	return err == nil && c > 0
 }
 
-// MarshalJSON implements the json.Marshaler interface
+// LessThan returns true if a is less than b.
+// Amounts in different currencies are not less than each other.
+func (a Amount) LessThan(b Amount) bool {
+	c, err := a.Compare(b)
+	return err == nil && c < 0
+}
+
+// IsPositive returns true if the amount is greater than zero
+func (a Amount) IsPositive() bool {
+	return a.Value > 0
+}
+
+// IsNegative returns true if the amount is less than zero
+func (a Amount) IsNegative() bool {
+	return a.Value < 0
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// Amounts without a currency are written as plain numbers, as they were read.
 func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.Currency == "" {
+		return []byte(strconv.FormatInt(a.Value, 10)), nil
+	}
 	return []byte(fmt.Sprintf(`"%s"`, a.String())), nil
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Besides strings like "SEK100" it accepts plain numbers, which have no currency
+// and are whole units of the currency they are later put in.
 func (a *Amount) UnmarshalJSON(data []byte) error {
-	// Remove quotes
-	s := string(data)
-	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-		s = s[1 : len(s)-1]
+	var value int64
+	if err := json.Unmarshal(data, &value); err == nil {
+		*a = Amount{Value: value}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid amount: %s", data)
 	}
 
-	parsed, err := ParseAmount(s)
+	parsed, err := parseAmountOrValue(s)
 	if err != nil {
 		return err
 	}
 
-	*a = *parsed
+	*a = parsed
 	return nil
 }
@@ -0,0 +1,323 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MultiUnitPricing defines what the winners of a multi-unit auction pay per unit
+type MultiUnitPricing string
+
+const (
+	// LowestAccepted is a uniform price: every winner pays the lowest bid that won units
+	LowestAccepted MultiUnitPricing = "LowestAccepted"
+
+	// HighestRejected is a uniform price: every winner pays the highest bid that won no units
+	HighestRejected MultiUnitPricing = "HighestRejected"
+
+	// PayAsBid is a discriminatory price: every winner pays what they bid
+	PayAsBid MultiUnitPricing = "PayAsBid"
+)
+
+func init() {
+	MustRegisterAuctionType(AuctionTypeDefinition{
+		Name: "MultiUnit",
+		Type: MultiUnitSealedBid,
+		ParseOptions: func(s string) (interface{}, error) {
+			options, err := ParseMultiUnitOptions(s)
+			if err != nil {
+				return nil, err
+			}
+			return *options, nil
+		},
+		FormatOptions: func(options interface{}) string {
+			return options.(MultiUnitOptions).String()
+		},
+		DefaultOptions: func() interface{} {
+			return DefaultMultiUnitOptions()
+		},
+		CreateEmptyState: func(auction Auction, options interface{}) State {
+			multiUnitOptions := options.(MultiUnitOptions)
+			// A reserve price without a currency is in the auction's currency
+			if reservePrice, err := multiUnitOptions.ReservePrice.InCurrency(auction.Currency); err == nil {
+				multiUnitOptions.ReservePrice = reservePrice
+			}
+			return NewMultiUnitState(auction.Expiry, multiUnitOptions)
+		},
+		Validate: func(options interface{}, currency Currency) error {
+			return options.(MultiUnitOptions).Validate(currency)
+		},
+		Public: func(options interface{}) interface{} {
+			o := options.(MultiUnitOptions)
+			o.ReservePrice = Amount{Currency: o.ReservePrice.Currency}
+			return o
+		},
+		Units: func(options interface{}) int {
+			return options.(MultiUnitOptions).Units
+		},
+	})
+}
+
+// MultiUnitOptions defines the options for a sealed bid auction of several identical units,
+// where every bid is for a quantity of units at a price per unit
+type MultiUnitOptions struct {
+	// The number of units for sale
+	Units int `json:"units"`
+
+	// What the winners pay per unit
+	Pricing MultiUnitPricing `json:"pricing"`
+
+	// Bids below the reserve price per unit win no units.
+	// A zero amount means the auction has no reserve price.
+	ReservePrice Amount `json:"reservePrice"`
+}
+
+// String returns a string representation of the options. The reserve price is only
+// included when there is one.
+func (o MultiUnitOptions) String() string {
+	s := fmt.Sprintf("MultiUnit|%d|%s", o.Units, o.Pricing)
+	if o.ReservePrice.Value != 0 {
+		s += fmt.Sprintf("|%s", o.ReservePrice)
+	}
+	return s
+}
+
+// Validate checks that the reserve price is not negative and is in the given currency
+func (o MultiUnitOptions) Validate(currency Currency) error {
+	if o.ReservePrice.IsNegative() {
+		return NewNegativeAmountError("reservePrice", o.ReservePrice)
+	}
+	_, err := o.ReservePrice.InCurrency(currency)
+	return err
+}
+
+// ParseMultiUnitOptions parses a string like "MultiUnit|50|PayAsBid" or
+// "MultiUnit|50|LowestAccepted|SEK100" into MultiUnitOptions
+func ParseMultiUnitOptions(s string) (*MultiUnitOptions, error) {
+	parts := strings.Split(s, "|")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != "MultiUnit" {
+		return nil, fmt.Errorf("invalid multi-unit options format: %s", s)
+	}
+
+	// Parse the number of units
+	units, err := strconv.Atoi(parts[1])
+	if err != nil || units < 1 {
+		return nil, fmt.Errorf("invalid units format: %s", parts[1])
+	}
+
+	// Parse pricing
+	pricing := MultiUnitPricing(parts[2])
+	if pricing != LowestAccepted && pricing != HighestRejected && pricing != PayAsBid {
+		return nil, fmt.Errorf("invalid pricing format: %s", parts[2])
+	}
+
+	options := &MultiUnitOptions{Units: units, Pricing: pricing}
+
+	// Parse reserve price, either an amount like "SEK100" or a plain value in the auction's currency
+	if len(parts) > 3 {
+		reserveAmount, err := parseAmountOrValue(parts[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserve price format: %s", parts[3])
+		}
+		options.ReservePrice = reserveAmount
+	}
+
+	return options, nil
+}
+
+// DefaultMultiUnitOptions creates default options
+func DefaultMultiUnitOptions() MultiUnitOptions {
+	return MultiUnitOptions{
+		Units:   1,
+		Pricing: PayAsBid,
+	}
+}
+
+// MultiUnitState represents the state of a multi-unit sealed bid auction
+type MultiUnitState struct {
+	// bids holds the bids in the order they were placed, and highest first once disclosing
+	bids       []Bid
+	disclosing bool
+	expiry     time.Time
+	options    MultiUnitOptions
+}
+
+// NewMultiUnitState creates a new multi-unit sealed bid auction state
+func NewMultiUnitState(expiry time.Time, options MultiUnitOptions) *MultiUnitState {
+	return &MultiUnitState{
+		bids:       []Bid{},
+		disclosing: false,
+		expiry:     expiry,
+		options:    options,
+	}
+}
+
+// Increment advances the state based on the current time
+func (s *MultiUnitState) Increment(now time.Time) State {
+	if s.disclosing || now.Before(s.expiry) {
+		return s
+	}
+
+	// Sort bids by price per unit in descending order; earlier bids win ties
+	bids := make([]Bid, len(s.bids))
+	copy(bids, s.bids)
+	sort.SliceStable(bids, func(i, j int) bool {
+		return bids[i].Amount.GreaterThan(bids[j].Amount)
+	})
+
+	return &MultiUnitState{
+		bids:       bids,
+		disclosing: true,
+		expiry:     s.expiry,
+		options:    s.options,
+	}
+}
+
+// AddBid attempts to add a bid to the state
+func (s *MultiUnitState) AddBid(bid Bid) (State, error) {
+	next := s.Increment(bid.At)
+	if next.HasEnded() {
+		return next, NewAuctionHasEndedError(bid.ForAuction)
+	}
+
+	if len(bidsOf(s.bids, bid.Bidder.ID)) > 0 {
+		return s, NewAlreadyPlacedBidError()
+	}
+	if bid.Quantity < 0 || bid.Units() > s.options.Units {
+		return s, NewInvalidQuantityError(bid.Quantity, s.options.Units)
+	}
+
+	bids := make([]Bid, 0, len(s.bids)+1)
+	bids = append(bids, s.bids...)
+	bids = append(bids, bid)
+
+	return &MultiUnitState{
+		bids:       bids,
+		disclosing: s.disclosing,
+		expiry:     s.expiry,
+		options:    s.options,
+	}, nil
+}
+
+// GetBids returns all bids in the state
+func (s *MultiUnitState) GetBids() []Bid {
+	return s.bids
+}
+
+// Allocations returns the units won by the highest bids once the auction has ended,
+// highest bid first. The lowest winning bid may win fewer units than it was for.
+func (s *MultiUnitState) Allocations() []Allocation {
+	if !s.disclosing {
+		return nil
+	}
+
+	var allocations []Allocation
+	remaining := s.options.Units
+	rejected := 0
+	for i, bid := range s.bids {
+		if remaining == 0 || bid.Amount.LessThan(s.options.ReservePrice) {
+			rejected = i
+			break
+		}
+		quantity := bid.Units()
+		if quantity > remaining {
+			quantity = remaining
+		}
+		remaining -= quantity
+		allocations = append(allocations, Allocation{Bidder: bid.Bidder.ID, Quantity: quantity, Price: bid.Amount})
+		rejected = i + 1
+	}
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	price, uniform := s.uniformPrice(allocations, rejected)
+	if uniform {
+		for i := range allocations {
+			allocations[i].Price = price
+		}
+	}
+	return allocations
+}
+
+// uniformPrice returns the price every winner pays, and false if winners pay what they bid.
+// The bid at index rejected is the highest bid that won no units, if there is one.
+func (s *MultiUnitState) uniformPrice(allocations []Allocation, rejected int) (Amount, bool) {
+	switch s.options.Pricing {
+	case LowestAccepted:
+		return allocations[len(allocations)-1].Price, true
+	case HighestRejected:
+		// The winners pay no less than the reserve price. If every bid won units and
+		// there is no reserve price, they pay the lowest winning bid.
+		if rejected < len(s.bids) && !s.bids[rejected].Amount.LessThan(s.options.ReservePrice) {
+			return s.bids[rejected].Amount, true
+		}
+		if s.hasReserve() {
+			return s.options.ReservePrice, true
+		}
+		return allocations[len(allocations)-1].Price, true
+	default:
+		return Amount{}, false
+	}
+}
+
+// TryGetAmountAndWinner returns the price per unit and the bidder of the highest winning
+// bid. Use GetAllocations for all winners.
+func (s *MultiUnitState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	allocations := s.Allocations()
+	if len(allocations) == 0 {
+		return Amount{}, "", false
+	}
+	return allocations[0].Price, allocations[0].Bidder, true
+}
+
+// ReservePrice returns the reserve price per unit, and false if the auction has none
+func (s *MultiUnitState) ReservePrice() (Amount, bool) {
+	return s.options.ReservePrice, s.hasReserve()
+}
+
+// ReserveMet returns true if the highest bid reaches the reserve price. Bids are only
+// taken into account once they are disclosed.
+func (s *MultiUnitState) ReserveMet() bool {
+	if !s.disclosing || len(s.bids) == 0 {
+		return false
+	}
+	return !s.bids[0].Amount.LessThan(s.options.ReservePrice)
+}
+
+// hasReserve returns true if the auction has a reserve price
+func (s *MultiUnitState) hasReserve() bool {
+	return s.options.ReservePrice.Value != 0
+}
+
+// BidsSealed returns true until the bids are disclosed
+func (s *MultiUnitState) BidsSealed() bool {
+	return !s.disclosing
+}
+
+// HasEnded returns true if the auction has ended
+func (s *MultiUnitState) HasEnded() bool {
+	return s.disclosing
+}
+
+// RetractBids removes the bid of the bidder from the state
+func (s *MultiUnitState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	next := s.Increment(at)
+	if next.HasEnded() {
+		return retractFromEnded(next, bidder, NewAuctionHasEndedError)
+	}
+
+	if len(bidsOf(s.bids, bidder)) == 0 {
+		return s, NewBidNotFoundError(bidder)
+	}
+
+	return &MultiUnitState{
+		bids:       withoutBidsOf(s.bids, bidder),
+		disclosing: s.disclosing,
+		expiry:     s.expiry,
+		options:    s.options,
+	}, nil
+}
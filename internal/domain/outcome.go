@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CommandOutcome is recorded in the command log after a command to say whether it was
+// accepted, together with the position of its event in the event log, or rejected,
+// together with the DomainError it was rejected with. Commands for an auction are
+// handled one at a time, so an outcome describes the last command for the same
+// auction before it.
+type CommandOutcome struct {
+	// Time is the time of the command
+	Time      time.Time
+	AuctionId AuctionId
+	// Command is the type of the command, as in its "$type"
+	Command string
+	// EventSeq is the sequence number of the event of an accepted command
+	EventSeq int64
+	// Error is the error a rejected command was rejected with
+	Error *DomainError
+}
+
+// NewCommandOutcome returns the outcome of a command that was accepted with the
+// event at eventSeq, or rejected with err
+func NewCommandOutcome(cmd Command, eventSeq int64, err error) CommandOutcome {
+	outcome := CommandOutcome{
+		Time:      cmd.GetTime(),
+		AuctionId: cmd.GetAuctionId(),
+		Command:   CommandType(cmd),
+		EventSeq:  eventSeq,
+	}
+	if err != nil {
+		domainErr, ok := err.(DomainError)
+		if !ok {
+			domainErr = DomainError{Type: ErrorType(err.Error())}
+		}
+		outcome.Error = &domainErr
+	}
+	return outcome
+}
+
+// Accepted returns true if the command was accepted
+func (o CommandOutcome) Accepted() bool {
+	return o.Error == nil
+}
+
+// GetTime returns the time of the command
+func (o CommandOutcome) GetTime() time.Time {
+	return o.Time
+}
+
+// GetAuctionId returns the ID of the auction the command was for
+func (o CommandOutcome) GetAuctionId() AuctionId {
+	return o.AuctionId
+}
+
+type commandOutcomeJSON struct {
+	Type      string      `json:"$type"`
+	Time      time.Time   `json:"at"`
+	AuctionId AuctionId   `json:"auction"`
+	Command   string      `json:"command"`
+	Accepted  bool        `json:"accepted"`
+	EventSeq  int64       `json:"event,omitempty"`
+	ErrorType ErrorType   `json:"errorType,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler interface for CommandOutcome
+func (o CommandOutcome) MarshalJSON() ([]byte, error) {
+	outcome := commandOutcomeJSON{
+		Type:      "CommandOutcome",
+		Time:      o.Time,
+		AuctionId: o.AuctionId,
+		Command:   o.Command,
+		Accepted:  o.Accepted(),
+		EventSeq:  o.EventSeq,
+	}
+	if o.Error != nil {
+		outcome.ErrorType = o.Error.Type
+		outcome.Data = o.Error.Data
+	}
+	return json.Marshal(outcome)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface for CommandOutcome.
+// Error data is restored as generic JSON values.
+func (o *CommandOutcome) UnmarshalJSON(data []byte) error {
+	var outcome commandOutcomeJSON
+	if err := json.Unmarshal(data, &outcome); err != nil {
+		return err
+	}
+
+	o.Time = outcome.Time
+	o.AuctionId = outcome.AuctionId
+	o.Command = outcome.Command
+	o.EventSeq = outcome.EventSeq
+	o.Error = nil
+	if !outcome.Accepted {
+		o.Error = &DomainError{Type: outcome.ErrorType, Data: outcome.Data}
+	}
+	return nil
+}
+
+// CommandType returns the "$type" a command is recorded with
+func CommandType(cmd Command) string {
+	switch cmd.(type) {
+	case AddAuctionCommand:
+		return "AddAuction"
+	case PlaceBidCommand:
+		return "PlaceBid"
+	case BuyNowCommand:
+		return "BuyNow"
+	case CancelAuctionCommand:
+		return "CancelAuction"
+	case RetractBidCommand:
+		return "RetractBid"
+	case CommandOutcome:
+		return "CommandOutcome"
+	case IdempotentResponse:
+		return "IdempotentResponse"
+	default:
+		return ""
+	}
+}
+
+// CommandIssuer returns the ID of the user who issued a command
+func CommandIssuer(cmd Command) (UserId, bool) {
+	switch c := cmd.(type) {
+	case AddAuctionCommand:
+		return c.Auction.Seller.ID, true
+	case PlaceBidCommand:
+		return c.Bid.Bidder.ID, true
+	case BuyNowCommand:
+		return c.Buyer.ID, true
+	case CancelAuctionCommand:
+		return c.CancelledBy.ID, true
+	case RetractBidCommand:
+		return c.RetractedBy.ID, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AuctionTypeDefinition describes how an auction type is parsed, formatted and started.
+// Auction types register a definition with RegisterAuctionType, typically from an init function,
+// so that new formats can be added from other packages without changing the domain.
+type AuctionTypeDefinition struct {
+	// Name is the prefix of the serialized options, e.g. "English" in "English|0|0|0"
+	Name string
+
+	// Type is the enum value reported for auctions of this type.
+	// Several definitions may share a value, e.g. Blind and Vickrey are both SingleSealedBid.
+	Type AuctionTypeEnum
+
+	// ParseOptions parses the full serialized options, including the name prefix
+	ParseOptions func(s string) (interface{}, error)
+
+	// FormatOptions serializes parsed options, including the name prefix
+	FormatOptions func(options interface{}) string
+
+	// DefaultOptions returns the options used when stored options cannot be parsed
+	DefaultOptions func() interface{}
+
+	// CreateEmptyState creates the initial state of an auction with the given parsed options
+	CreateEmptyState func(auction Auction, options interface{}) State
+
+	// Validate checks parsed options for an auction in the given currency; optional
+	Validate func(options interface{}, currency Currency) error
+
+	// Public returns parsed options as they may be shown to bidders, without a reserve
+	// price the seller keeps to themselves; optional, options are shown as they are without it
+	Public func(options interface{}) interface{}
+
+	// Units returns the number of units for sale with the parsed options; optional,
+	// auctions are for a single unit without it
+	Units func(options interface{}) int
+}
+
+var (
+	registryMu      sync.RWMutex
+	auctionTypes    = make(map[string]AuctionTypeDefinition)
+	auctionTypeEnum = map[AuctionTypeEnum]string{
+		TimedAscending:     "TimedAscending",
+		SingleSealedBid:    "SingleSealedBid",
+		TimedDescending:    "TimedDescending",
+		MultiUnitSealedBid: "MultiUnitSealedBid",
+	}
+)
+
+// NewAuctionTypeEnum allocates a new enum value for an auction type family
+func NewAuctionTypeEnum(name string) AuctionTypeEnum {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	next := AuctionTypeEnum(len(auctionTypeEnum))
+	for {
+		if _, taken := auctionTypeEnum[next]; !taken {
+			break
+		}
+		next++
+	}
+	auctionTypeEnum[next] = name
+	return next
+}
+
+// RegisterAuctionType registers an auction type definition.
+// It returns an error if the definition is incomplete or the name is already registered.
+func RegisterAuctionType(def AuctionTypeDefinition) error {
+	if def.Name == "" || strings.Contains(def.Name, "|") {
+		return fmt.Errorf("invalid auction type name: %q", def.Name)
+	}
+	if def.ParseOptions == nil || def.FormatOptions == nil || def.DefaultOptions == nil || def.CreateEmptyState == nil {
+		return fmt.Errorf("incomplete auction type definition: %s", def.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := auctionTypes[def.Name]; exists {
+		return fmt.Errorf("auction type already registered: %s", def.Name)
+	}
+	if _, exists := auctionTypeEnum[def.Type]; !exists {
+		return fmt.Errorf("unknown auction type enum for %s: %d", def.Name, def.Type)
+	}
+	auctionTypes[def.Name] = def
+	return nil
+}
+
+// MustRegisterAuctionType registers an auction type definition and panics on error
+func MustRegisterAuctionType(def AuctionTypeDefinition) {
+	if err := RegisterAuctionType(def); err != nil {
+		panic(err)
+	}
+}
+
+// LookupAuctionType returns the definition registered under the name prefix of the serialized options
+func LookupAuctionType(options string) (AuctionTypeDefinition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := auctionTypes[auctionTypeName(options)]
+	return def, ok
+}
+
+// auctionTypeName returns the name prefix of serialized options
+func auctionTypeName(options string) string {
+	return strings.SplitN(options, "|", 2)[0]
+}
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -16,6 +18,98 @@ const (
 	Vickrey SealedBidOptions = "Vickrey"
 )
 
+// SealedBidAuctionOptions are the options of a sealed bid auction: whether it is a Blind or
+// a Vickrey auction, and the lowest price the item is sold for
+type SealedBidAuctionOptions struct {
+	Kind SealedBidOptions `json:"kind"`
+
+	// The item remains unsold if no bid reaches the reserve price.
+	// A zero amount means the auction has no reserve price.
+	ReservePrice Amount `json:"reservePrice"`
+}
+
+// String returns a string representation of the options. The reserve price is only
+// included when there is one, so that options without it keep their original format.
+func (o SealedBidAuctionOptions) String() string {
+	if o.ReservePrice.Value == 0 {
+		return string(o.Kind)
+	}
+	return fmt.Sprintf("%s|%s", o.Kind, o.ReservePrice)
+}
+
+// Validate checks that the reserve price is not negative and is in the given currency
+func (o SealedBidAuctionOptions) Validate(currency Currency) error {
+	if o.ReservePrice.IsNegative() {
+		return NewNegativeAmountError("reservePrice", o.ReservePrice)
+	}
+	_, err := o.ReservePrice.InCurrency(currency)
+	return err
+}
+
+// ParseSealedBidOptions parses a string like "Vickrey" or "Vickrey|SEK100" into SealedBidAuctionOptions
+func ParseSealedBidOptions(s string) (*SealedBidAuctionOptions, error) {
+	parts := strings.Split(s, "|")
+	kind := SealedBidOptions(parts[0])
+	if len(parts) > 2 || (kind != Blind && kind != Vickrey) {
+		return nil, fmt.Errorf("invalid sealed bid options format: %s", s)
+	}
+
+	options := &SealedBidAuctionOptions{Kind: kind}
+
+	// Parse reserve price, either an amount like "SEK100" or a plain value in the auction's currency
+	if len(parts) > 1 {
+		reserveAmount, err := parseAmountOrValue(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserve price format: %s", parts[1])
+		}
+		options.ReservePrice = reserveAmount
+	}
+
+	return options, nil
+}
+
+func init() {
+	for _, options := range []SealedBidOptions{Blind, Vickrey} {
+		options := options
+		MustRegisterAuctionType(AuctionTypeDefinition{
+			Name: string(options),
+			Type: SingleSealedBid,
+			ParseOptions: func(s string) (interface{}, error) {
+				parsed, err := ParseSealedBidOptions(s)
+				if err != nil {
+					return nil, err
+				}
+				if parsed.Kind != options {
+					return nil, fmt.Errorf("invalid sealed bid options format: %s", s)
+				}
+				return *parsed, nil
+			},
+			FormatOptions: func(options interface{}) string {
+				return options.(SealedBidAuctionOptions).String()
+			},
+			DefaultOptions: func() interface{} {
+				return SealedBidAuctionOptions{Kind: options}
+			},
+			CreateEmptyState: func(auction Auction, options interface{}) State {
+				sealedOptions := options.(SealedBidAuctionOptions)
+				// A reserve price without a currency is in the auction's currency
+				if reservePrice, err := sealedOptions.ReservePrice.InCurrency(auction.Currency); err == nil {
+					sealedOptions.ReservePrice = reservePrice
+				}
+				return NewSealedBidStateWithReserve(auction.Expiry, sealedOptions.Kind, sealedOptions.ReservePrice)
+			},
+			Validate: func(options interface{}, currency Currency) error {
+				return options.(SealedBidAuctionOptions).Validate(currency)
+			},
+			Public: func(options interface{}) interface{} {
+				o := options.(SealedBidAuctionOptions)
+				o.ReservePrice = Amount{Currency: o.ReservePrice.Currency}
+				return o
+			},
+		})
+	}
+}
+
 // SealedBidState represents the state of a sealed bid auction
 type SealedBidState struct {
 	// bids maps user IDs to their bids
@@ -24,16 +118,24 @@ type SealedBidState struct {
 	disclosing bool
 	expiry     time.Time
 	options    SealedBidOptions
+	// reservePrice is the lowest price the item is sold for, or zero if there is none
+	reservePrice Amount
 }
 
 // NewSealedBidState creates a new sealed bid auction state
 func NewSealedBidState(expiry time.Time, options SealedBidOptions) *SealedBidState {
+	return NewSealedBidStateWithReserve(expiry, options, Amount{})
+}
+
+// NewSealedBidStateWithReserve creates a new sealed bid auction state with a reserve price
+func NewSealedBidStateWithReserve(expiry time.Time, options SealedBidOptions, reservePrice Amount) *SealedBidState {
 	return &SealedBidState{
-		bids:       make(map[UserId]Bid),
-		bidsList:   []Bid{},
-		disclosing: false,
-		expiry:     expiry,
-		options:    options,
+		bids:         make(map[UserId]Bid),
+		bidsList:     []Bid{},
+		disclosing:   false,
+		expiry:       expiry,
+		options:      options,
+		reservePrice: reservePrice,
 	}
 }
 
@@ -54,16 +156,17 @@ func (s *SealedBidState) Increment(now time.Time) State {
 
 		// Sort bids by amount in descending order
 		sort.Slice(bids, func(i, j int) bool {
-			return bids[i].Amount > bids[j].Amount
+			return bids[i].Amount.GreaterThan(bids[j].Amount)
 		})
 
 		// Create new state with disclosing = true
 		return &SealedBidState{
-			bids:       s.bids,
-			bidsList:   bids,
-			disclosing: true,
-			expiry:     s.expiry,
-			options:    s.options,
+			bids:         s.bids,
+			bidsList:     bids,
+			disclosing:   true,
+			expiry:       s.expiry,
+			options:      s.options,
+			reservePrice: s.reservePrice,
 		}
 	}
 
@@ -100,18 +203,18 @@ func (s *SealedBidState) AddBid(bid Bid) (State, error) {
 	}
 	newBids[userId] = bid
 
-	// Update bidsList
+	// Update bidsList, keeping the order in which bids were placed
 	newBidsList := make([]Bid, 0, len(newBids))
-	for _, b := range newBids {
-		newBidsList = append(newBidsList, b)
-	}
+	newBidsList = append(newBidsList, sealedState.bidsList...)
+	newBidsList = append(newBidsList, bid)
 
 	return &SealedBidState{
-		bids:       newBids,
-		bidsList:   newBidsList,
-		disclosing: sealedState.disclosing,
-		expiry:     sealedState.expiry,
-		options:    sealedState.options,
+		bids:         newBids,
+		bidsList:     newBidsList,
+		disclosing:   sealedState.disclosing,
+		expiry:       sealedState.expiry,
+		options:      sealedState.options,
+		reservePrice: sealedState.reservePrice,
 	}, nil
 }
 
@@ -128,28 +231,85 @@ func (s *SealedBidState) GetBids() []Bid {
 	return bids
 }
 
-// TryGetAmountAndWinner attempts to get the winning amount and bidder
-func (s *SealedBidState) TryGetAmountAndWinner() (int64, UserId, bool) {
-	if !s.disclosing || len(s.bidsList) == 0 {
-		return 0, "", false
+// TryGetAmountAndWinner attempts to get the winning amount and bidder.
+// The highest bid only wins if it reaches the reserve price.
+func (s *SealedBidState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	if !s.ReserveMet() {
+		return Amount{}, "", false
 	}
 
 	highestBid := s.bidsList[0]
 
 	if s.options == Vickrey {
+		// The winner pays the second highest bid, but no less than the reserve price.
+		// If there's only one bid and no reserve price, the winner pays their own bid.
+		price := highestBid.Amount
 		if len(s.bidsList) > 1 {
-			// Second highest bid price
-			return s.bidsList[1].Amount, highestBid.Bidder.ID, true
+			price = s.bidsList[1].Amount
+		}
+		if s.hasReserve() && (len(s.bidsList) == 1 || price.LessThan(s.reservePrice)) {
+			price = s.reservePrice
 		}
-		// If there's only one bid, the winner pays their own bid
-		return highestBid.Amount, highestBid.Bidder.ID, true
+		return price, highestBid.Bidder.ID, true
 	}
 
 	// Blind auction - highest bidder pays their bid
 	return highestBid.Amount, highestBid.Bidder.ID, true
 }
 
+// ReservePrice returns the reserve price of the auction, and false if it has none
+func (s *SealedBidState) ReservePrice() (Amount, bool) {
+	return s.reservePrice, s.hasReserve()
+}
+
+// ReserveMet returns true if the highest bid reaches the reserve price. Bids are only
+// taken into account once they are disclosed, so that nothing is revealed about them before.
+func (s *SealedBidState) ReserveMet() bool {
+	if !s.disclosing || len(s.bidsList) == 0 {
+		return false
+	}
+	return !s.bidsList[0].Amount.LessThan(s.reservePrice)
+}
+
+// hasReserve returns true if the auction has a reserve price
+func (s *SealedBidState) hasReserve() bool {
+	return s.reservePrice.Value != 0
+}
+
+// BidsSealed returns true until the bids are disclosed
+func (s *SealedBidState) BidsSealed() bool {
+	return !s.disclosing
+}
+
 // HasEnded returns true if the auction has ended
 func (s *SealedBidState) HasEnded() bool {
 	return s.disclosing
 }
+
+// RetractBids removes the bid of the bidder from the state
+func (s *SealedBidState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	next := s.Increment(at)
+	if next.HasEnded() {
+		return retractFromEnded(next, bidder, NewAuctionHasEndedError)
+	}
+
+	if _, exists := s.bids[bidder]; !exists {
+		return s, NewBidNotFoundError(bidder)
+	}
+
+	newBids := make(map[UserId]Bid, len(s.bids)-1)
+	for k, v := range s.bids {
+		if k != bidder {
+			newBids[k] = v
+		}
+	}
+
+	return &SealedBidState{
+		bids:         newBids,
+		bidsList:     withoutBidsOf(s.bidsList, bidder),
+		disclosing:   s.disclosing,
+		expiry:       s.expiry,
+		options:      s.options,
+		reservePrice: s.reservePrice,
+	}, nil
+}
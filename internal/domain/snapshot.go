@@ -0,0 +1,369 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SnapshotableState is a State that can be captured in a repository snapshot.
+// The JSON produced by MarshalJSON is passed back to the decoder registered for its kind.
+type SnapshotableState interface {
+	State
+	json.Marshaler
+	// SnapshotKind returns the kind the state's decoder is registered under
+	SnapshotKind() string
+}
+
+var (
+	stateKindsMu sync.RWMutex
+	stateKinds   = make(map[string]func(data []byte) (State, error))
+)
+
+// RegisterStateKind registers the decoder for snapshots of states of the given kind
+func RegisterStateKind(kind string, decode func(data []byte) (State, error)) error {
+	stateKindsMu.Lock()
+	defer stateKindsMu.Unlock()
+
+	if _, exists := stateKinds[kind]; exists {
+		return fmt.Errorf("state kind already registered: %s", kind)
+	}
+	stateKinds[kind] = decode
+	return nil
+}
+
+// MustRegisterStateKind registers a state decoder and panics on error
+func MustRegisterStateKind(kind string, decode func(data []byte) (State, error)) {
+	if err := RegisterStateKind(kind, decode); err != nil {
+		panic(err)
+	}
+}
+
+// StateSnapshot is the serialized form of a State
+type StateSnapshot struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// SnapshotEntry is the serialized form of an auction and its state
+type SnapshotEntry struct {
+	Auction Auction       `json:"auction"`
+	State   StateSnapshot `json:"state"`
+}
+
+// RepositorySnapshot is the serialized form of a Repository, ordered by auction ID
+type RepositorySnapshot []SnapshotEntry
+
+// NewRepositorySnapshot captures a repository; every state must be a SnapshotableState
+func NewRepositorySnapshot(repo Repository) (RepositorySnapshot, error) {
+	snapshot := make(RepositorySnapshot, 0, len(repo))
+	for id, entry := range repo {
+		state, err := newStateSnapshot(entry.State)
+		if err != nil {
+			return nil, fmt.Errorf("error snapshotting state of auction %d: %v", id, err)
+		}
+
+		snapshot = append(snapshot, SnapshotEntry{
+			Auction: entry.Auction,
+			State:   state,
+		})
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Auction.ID < snapshot[j].Auction.ID
+	})
+	return snapshot, nil
+}
+
+// Restore rebuilds the repository captured in the snapshot
+func (s RepositorySnapshot) Restore() (Repository, error) {
+	repo := make(Repository, len(s))
+	for _, entry := range s {
+		state, err := decodeState(entry.State)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling state of auction %d: %v", entry.Auction.ID, err)
+		}
+
+		repo[entry.Auction.ID] = struct {
+			Auction Auction
+			State   State
+		}{
+			Auction: entry.Auction,
+			State:   state,
+		}
+	}
+	return repo, nil
+}
+
+// newStateSnapshot captures a single state
+func newStateSnapshot(state State) (StateSnapshot, error) {
+	snapshotable, ok := state.(SnapshotableState)
+	if !ok {
+		return StateSnapshot{}, fmt.Errorf("state cannot be snapshotted: %T", state)
+	}
+
+	data, err := snapshotable.MarshalJSON()
+	if err != nil {
+		return StateSnapshot{}, err
+	}
+	return StateSnapshot{Kind: snapshotable.SnapshotKind(), Data: data}, nil
+}
+
+// decodeState restores a single state using the decoder registered for its kind
+func decodeState(snapshot StateSnapshot) (State, error) {
+	stateKindsMu.RLock()
+	decode, ok := stateKinds[snapshot.Kind]
+	stateKindsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown state kind: %s", snapshot.Kind)
+	}
+	return decode(snapshot.Data)
+}
+
+func init() {
+	MustRegisterStateKind("AwaitingStart", func(data []byte) (State, error) {
+		var s awaitingStartStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return &AwaitingStartState{start: s.Start, startingExpiry: s.StartingExpiry, options: s.Options}, nil
+	})
+	MustRegisterStateKind("Ongoing", func(data []byte) (State, error) {
+		var s ongoingStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		scheduledExpiry := s.ScheduledExpiry
+		if scheduledExpiry.IsZero() {
+			// Snapshots taken before extensions were counted
+			scheduledExpiry = s.NextExpiry
+		}
+		return &OngoingState{bids: nonNilBids(s.Bids), leaderMax: s.LeaderMax, nextExpiry: s.NextExpiry, scheduledExpiry: scheduledExpiry, extensions: s.Extensions, options: s.Options}, nil
+	})
+	MustRegisterStateKind("Ended", func(data []byte) (State, error) {
+		var s endedStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return &EndedState{bids: nonNilBids(s.Bids), expiry: s.Expiry, options: s.Options}, nil
+	})
+	MustRegisterStateKind("SealedBid", func(data []byte) (State, error) {
+		var s sealedBidStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		bids := make(map[UserId]Bid, len(s.Bids))
+		for _, bid := range s.Bids {
+			bids[bid.Bidder.ID] = bid
+		}
+		return &SealedBidState{bids: bids, bidsList: nonNilBids(s.BidsList), disclosing: s.Disclosing, expiry: s.Expiry, options: s.Options, reservePrice: s.ReservePrice}, nil
+	})
+	MustRegisterStateKind("MultiUnit", func(data []byte) (State, error) {
+		var s multiUnitStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return &MultiUnitState{bids: nonNilBids(s.Bids), disclosing: s.Disclosing, expiry: s.Expiry, options: s.Options}, nil
+	})
+	MustRegisterStateKind("TimedDescending", func(data []byte) (State, error) {
+		var s timedDescendingStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return &TimedDescendingState{start: s.Start, expiry: s.Expiry, options: s.Options, winner: s.Winner, ended: s.Ended}, nil
+	})
+	MustRegisterStateKind("Cancelled", func(data []byte) (State, error) {
+		var s cancelledStateJSON
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		state, err := decodeState(s.State)
+		if err != nil {
+			return nil, err
+		}
+		return &CancelledState{state: state, at: s.At}, nil
+	})
+}
+
+// nonNilBids returns an empty slice in place of nil so that restored states match fresh ones
+func nonNilBids(bids []Bid) []Bid {
+	if bids == nil {
+		return []Bid{}
+	}
+	return bids
+}
+
+type awaitingStartStateJSON struct {
+	Start          time.Time             `json:"start"`
+	StartingExpiry time.Time             `json:"startingExpiry"`
+	Options        TimedAscendingOptions `json:"options"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *AwaitingStartState) SnapshotKind() string {
+	return "AwaitingStart"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *AwaitingStartState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(awaitingStartStateJSON{
+		Start:          s.start,
+		StartingExpiry: s.startingExpiry,
+		Options:        s.options,
+	})
+}
+
+type ongoingStateJSON struct {
+	Bids       []Bid                 `json:"bids"`
+	LeaderMax  Amount                `json:"leaderMax"`
+	NextExpiry time.Time             `json:"nextExpiry"`
+	Options    TimedAscendingOptions `json:"options"`
+
+	ScheduledExpiry time.Time `json:"scheduledExpiry"`
+	Extensions      int       `json:"extensions,omitempty"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *OngoingState) SnapshotKind() string {
+	return "Ongoing"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *OngoingState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ongoingStateJSON{
+		Bids:       s.bids,
+		LeaderMax:  s.leaderMax,
+		NextExpiry: s.nextExpiry,
+		Options:    s.options,
+
+		ScheduledExpiry: s.scheduledExpiry,
+		Extensions:      s.extensions,
+	})
+}
+
+type endedStateJSON struct {
+	Bids    []Bid                 `json:"bids"`
+	Expiry  time.Time             `json:"expiry"`
+	Options TimedAscendingOptions `json:"options"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *EndedState) SnapshotKind() string {
+	return "Ended"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *EndedState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(endedStateJSON{
+		Bids:    s.bids,
+		Expiry:  s.expiry,
+		Options: s.options,
+	})
+}
+
+type sealedBidStateJSON struct {
+	Bids       []Bid            `json:"bids"`
+	BidsList   []Bid            `json:"bidsList"`
+	Disclosing bool             `json:"disclosing"`
+	Expiry     time.Time        `json:"expiry"`
+	Options    SealedBidOptions `json:"options"`
+	// ReservePrice is absent from snapshots taken before sealed bid auctions had reserves
+	ReservePrice Amount `json:"reservePrice"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *SealedBidState) SnapshotKind() string {
+	return "SealedBid"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *SealedBidState) MarshalJSON() ([]byte, error) {
+	bids := make([]Bid, 0, len(s.bids))
+	for _, bid := range s.bids {
+		bids = append(bids, bid)
+	}
+	sort.Slice(bids, func(i, j int) bool {
+		return bids[i].Bidder.ID < bids[j].Bidder.ID
+	})
+
+	return json.Marshal(sealedBidStateJSON{
+		Bids:       bids,
+		BidsList:   s.bidsList,
+		Disclosing: s.disclosing,
+		Expiry:     s.expiry,
+		Options:    s.options,
+
+		ReservePrice: s.reservePrice,
+	})
+}
+
+type multiUnitStateJSON struct {
+	Bids       []Bid            `json:"bids"`
+	Disclosing bool             `json:"disclosing"`
+	Expiry     time.Time        `json:"expiry"`
+	Options    MultiUnitOptions `json:"options"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *MultiUnitState) SnapshotKind() string {
+	return "MultiUnit"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *MultiUnitState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(multiUnitStateJSON{
+		Bids:       s.bids,
+		Disclosing: s.disclosing,
+		Expiry:     s.expiry,
+		Options:    s.options,
+	})
+}
+
+type timedDescendingStateJSON struct {
+	Start   time.Time              `json:"start"`
+	Expiry  time.Time              `json:"expiry"`
+	Options TimedDescendingOptions `json:"options"`
+	Winner  *Bid                   `json:"winner,omitempty"`
+	Ended   bool                   `json:"ended"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *TimedDescendingState) SnapshotKind() string {
+	return "TimedDescending"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *TimedDescendingState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timedDescendingStateJSON{
+		Start:   s.start,
+		Expiry:  s.expiry,
+		Options: s.options,
+		Winner:  s.winner,
+		Ended:   s.ended,
+	})
+}
+
+type cancelledStateJSON struct {
+	State StateSnapshot `json:"state"`
+	At    time.Time     `json:"at"`
+}
+
+// SnapshotKind returns the snapshot kind of the state
+func (s *CancelledState) SnapshotKind() string {
+	return "Cancelled"
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *CancelledState) MarshalJSON() ([]byte, error) {
+	state, err := newStateSnapshot(s.state)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cancelledStateJSON{
+		State: state,
+		At:    s.at,
+	})
+}
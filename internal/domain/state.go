@@ -18,8 +18,198 @@ type State interface {
 
 	// TryGetAmountAndWinner attempts to get the winning amount and bidder
 	// Returns the amount, the winner's ID, and whether a winner was found
-	TryGetAmountAndWinner() (int64, UserId, bool)
+	TryGetAmountAndWinner() (Amount, UserId, bool)
 
 	// HasEnded returns true if the auction has ended
 	HasEnded() bool
+
+	// RetractBids removes all bids of the bidder from the state at the given time
+	// Returns the next state and an error if the bidder has no bids or the auction has ended
+	RetractBids(bidder UserId, at time.Time) (State, error)
+}
+
+// AuctionStatus describes where an auction is in its lifecycle
+type AuctionStatus string
+
+const (
+	// AuctionAwaiting is an auction that has not started yet
+	AuctionAwaiting AuctionStatus = "awaiting"
+	// AuctionOngoing is an auction that accepts bids
+	AuctionOngoing AuctionStatus = "ongoing"
+	// AuctionEnded is an auction that no longer accepts bids
+	AuctionEnded AuctionStatus = "ended"
+	// AuctionCancelled is an auction that was cancelled before it ended
+	AuctionCancelled AuctionStatus = "cancelled"
+)
+
+// GetStatus returns the status of an auction at the given time
+func GetStatus(auction Auction, state State, now time.Time) AuctionStatus {
+	if _, ok := state.(*CancelledState); ok {
+		return AuctionCancelled
+	}
+
+	next := state.Increment(now)
+	if next.HasEnded() {
+		return AuctionEnded
+	}
+	if _, ok := next.(*AwaitingStartState); ok || !now.After(auction.StartsAt) {
+		return AuctionAwaiting
+	}
+	return AuctionOngoing
+}
+
+// GetHighestVisibleBid returns the highest bid that may be shown to everyone,
+// which excludes the bids of sealed bid auctions that are not yet disclosing
+func GetHighestVisibleBid(state State) (Bid, bool) {
+	if cancelled, ok := state.(*CancelledState); ok {
+		state = cancelled.state
+	}
+	if BidsSealed(state) {
+		return Bid{}, false
+	}
+
+	bids := state.GetBids()
+	if len(bids) == 0 {
+		return Bid{}, false
+	}
+
+	highest := bids[0]
+	for _, bid := range bids[1:] {
+		if bid.Amount.GreaterThan(highest.Amount) {
+			highest = bid
+		}
+	}
+	return highest, true
+}
+
+// sealedBidding is implemented by the states of auctions whose bids are kept secret
+// until the auction ends
+type sealedBidding interface {
+	// BidsSealed returns true while the bids are kept secret
+	BidsSealed() bool
+}
+
+// BidsSealed returns true if the amounts of the bids of an auction must not be shown yet
+func BidsSealed(state State) bool {
+	if cancelled, ok := state.(*CancelledState); ok {
+		state = cancelled.state
+	}
+	sealed, ok := state.(sealedBidding)
+	return ok && sealed.BidsSealed()
+}
+
+// Allocation is what a winner of an auction won: a quantity of units at a price per unit
+type Allocation struct {
+	Bidder   UserId `json:"bidder"`
+	Quantity int    `json:"quantity"`
+	Price    Amount `json:"price"`
+}
+
+// allocating is implemented by the states of auctions that can have several winners
+type allocating interface {
+	// Allocations returns the winners, highest bid first
+	Allocations() []Allocation
+}
+
+// GetAllocations returns the winners of an auction with what they won. Auctions of a
+// single item have at most one winner, who wins one unit.
+func GetAllocations(state State) []Allocation {
+	if a, ok := state.(allocating); ok {
+		return a.Allocations()
+	}
+	if amount, winner, found := state.TryGetAmountAndWinner(); found {
+		return []Allocation{{Bidder: winner, Quantity: 1, Price: amount}}
+	}
+	return nil
+}
+
+// extendable is implemented by the states of auctions whose expiry bids can change,
+// either by extending it or by ending the auction early
+type extendable interface {
+	// NextExpiry returns the time at which the auction ends given the bids so far
+	NextExpiry() time.Time
+}
+
+// NextExpiry returns the time at which an auction ends given the bids so far, and false
+// if it ends at the expiry of the auction
+func NextExpiry(state State) (time.Time, bool) {
+	if cancelled, ok := state.(*CancelledState); ok {
+		state = cancelled.state
+	}
+	if e, ok := state.(extendable); ok {
+		return e.NextExpiry(), true
+	}
+	return time.Time{}, false
+}
+
+// EndsAt returns the time at which an auction ends given the bids so far, or the time
+// at which it ended
+func EndsAt(auction Auction, state State) time.Time {
+	if next, ok := NextExpiry(state); ok {
+		return next
+	}
+	return auction.Expiry
+}
+
+// reservePriced is implemented by the states of auctions that can have a reserve price
+type reservePriced interface {
+	// ReservePrice returns the reserve price, and false if the auction has none
+	ReservePrice() (Amount, bool)
+	// ReserveMet returns true if the highest bid meets the reserve price
+	ReserveMet() bool
+}
+
+// ReservePrice returns the reserve price of an auction, and false if it has none
+func ReservePrice(state State) (Amount, bool) {
+	if cancelled, ok := state.(*CancelledState); ok {
+		state = cancelled.state
+	}
+	if priced, ok := state.(reservePriced); ok {
+		return priced.ReservePrice()
+	}
+	return Amount{}, false
+}
+
+// ReserveMet returns true if the highest bid of an auction meets its reserve price, so
+// that the auction would be won if it ended now. For auctions without a reserve price
+// any bid does. The bids of sealed bid auctions only count once they are disclosed.
+func ReserveMet(state State) bool {
+	if cancelled, ok := state.(*CancelledState); ok {
+		state = cancelled.state
+	}
+	if priced, ok := state.(reservePriced); ok {
+		return priced.ReserveMet()
+	}
+	return len(state.GetBids()) > 0
+}
+
+// bidsOf returns the bids placed by the bidder
+func bidsOf(bids []Bid, bidder UserId) []Bid {
+	var found []Bid
+	for _, bid := range bids {
+		if bid.Bidder.ID == bidder {
+			found = append(found, bid)
+		}
+	}
+	return found
+}
+
+// withoutBidsOf returns the bids not placed by the bidder, keeping their order
+func withoutBidsOf(bids []Bid, bidder UserId) []Bid {
+	remaining := make([]Bid, 0, len(bids))
+	for _, bid := range bids {
+		if bid.Bidder.ID != bidder {
+			remaining = append(remaining, bid)
+		}
+	}
+	return remaining
+}
+
+// retractFromEnded returns the error for retracting bids from a state that no longer changes
+func retractFromEnded(state State, bidder UserId, hasEnded func(AuctionId) error) (State, error) {
+	found := bidsOf(state.GetBids(), bidder)
+	if len(found) == 0 {
+		return state, NewBidNotFoundError(bidder)
+	}
+	return state, hasEnded(found[0].ForAuction)
 }
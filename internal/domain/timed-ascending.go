@@ -7,64 +7,384 @@ import (
 	"time"
 )
 
+func init() {
+	MustRegisterAuctionType(AuctionTypeDefinition{
+		Name: "English",
+		Type: TimedAscending,
+		ParseOptions: func(s string) (interface{}, error) {
+			options, err := ParseTimedAscendingOptions(s)
+			if err != nil {
+				return nil, err
+			}
+			return *options, nil
+		},
+		FormatOptions: func(options interface{}) string {
+			return options.(TimedAscendingOptions).String()
+		},
+		DefaultOptions: func() interface{} {
+			return DefaultTimedAscendingOptions()
+		},
+		CreateEmptyState: func(auction Auction, options interface{}) State {
+			// Options of auctions added before amounts carried a currency are in the auction's currency
+			inCurrency, err := options.(TimedAscendingOptions).InCurrency(auction.Currency)
+			if err != nil {
+				inCurrency = options.(TimedAscendingOptions)
+			}
+			return NewTimedAscendingState(auction.StartsAt, auction.Expiry, inCurrency)
+		},
+		Validate: func(options interface{}, currency Currency) error {
+			return options.(TimedAscendingOptions).Validate(currency)
+		},
+		Public: func(options interface{}) interface{} {
+			o := options.(TimedAscendingOptions)
+			o.ReservePrice = Amount{Currency: o.ReservePrice.Currency}
+			return o
+		},
+	})
+}
+
 // TimedAscendingOptions defines the options for a timed ascending auction
 type TimedAscendingOptions struct {
 	// The seller has set a minimum sale price in advance (the 'reserve' price)
 	// If the final bid does not reach that price, the item remains unsold
-	ReservePrice int64 `json:"reservePrice"`
+	ReservePrice Amount `json:"reservePrice"`
 
 	// The minimum amount by which the next bid must exceed the current highest bid
-	MinRaise int64 `json:"minRaise"`
+	MinRaise Amount `json:"minRaise"`
+
+	// An optional increment table: once the highest bid reaches the start of a tier,
+	// the tier's increment is used instead of MinRaise. Tiers are in ascending order.
+	Increments []IncrementTier `json:"increments,omitempty"`
 
 	// If no competing bidder challenges the standing bid within a given time frame,
 	// the standing bid becomes the winner
 	TimeFrame time.Duration `json:"timeFrame"`
+
+	// How bids close to the expiry extend the auction
+	SoftClose SoftClosePolicy `json:"softClose"`
+
+	// An optional price at which a bidder can buy the item immediately, ending the auction.
+	// A zero amount means the auction has no buy-now price.
+	BuyNowPrice Amount `json:"buyNowPrice"`
+
+	// If set, the buy-now price is withdrawn once the highest bid reaches the reserve price
+	BuyNowUntilReserveMet bool `json:"buyNowUntilReserveMet,omitempty"`
+}
+
+// SoftClosePolicy defines how bids close to the expiry of a timed ascending auction
+// extend it. The zero policy extends the expiry to the time of a bid plus the time
+// frame whenever that is later than the expiry.
+type SoftClosePolicy struct {
+	// If set, the auction is never extended
+	HardClose bool `json:"hardClose,omitempty"`
+
+	// If set, only bids within this duration of the expiry extend the auction,
+	// instead of bids within the time frame
+	Window time.Duration `json:"window,omitempty"`
+
+	// If set, the expiry is extended by this duration, instead of to the time of the bid
+	// plus the time frame
+	Extension time.Duration `json:"extension,omitempty"`
+
+	// If set, the auction is extended at most this many times
+	MaxExtensions int `json:"maxExtensions,omitempty"`
+
+	// If set, the auction is extended at most this long beyond its original expiry
+	MaxExtension time.Duration `json:"maxExtension,omitempty"`
+}
+
+// String returns a string representation of the policy for the time frame segment of
+// the options, like ",within:300,by:120", or "" for the zero policy
+func (p SoftClosePolicy) String() string {
+	s := ""
+	if p.Window > 0 {
+		s += fmt.Sprintf(",within:%d", int(p.Window.Seconds()))
+	}
+	if p.Extension > 0 {
+		s += fmt.Sprintf(",by:%d", int(p.Extension.Seconds()))
+	}
+	if p.MaxExtensions > 0 {
+		s += fmt.Sprintf(",max:%d", p.MaxExtensions)
+	}
+	if p.MaxExtension > 0 {
+		s += fmt.Sprintf(",upto:%d", int(p.MaxExtension.Seconds()))
+	}
+	return s
+}
+
+// IncrementTier is a tier of an increment table: bids over a highest bid of at
+// least From must exceed it by at least Increment
+type IncrementTier struct {
+	From      Amount `json:"from"`
+	Increment Amount `json:"increment"`
 }
 
-// String returns a string representation of the options
+// String returns a string representation of the tier, like "SEK100:SEK5"
+func (t IncrementTier) String() string {
+	return fmt.Sprintf("%s:%s", t.From, t.Increment)
+}
+
+// String returns a string representation of the options. The increment table and the
+// buy-now price are only included when there are any, so that options without them keep
+// their original format.
 func (o TimedAscendingOptions) String() string {
 	seconds := int(o.TimeFrame.Seconds())
-	return fmt.Sprintf("English|%d|%d|%d", o.ReservePrice, o.MinRaise, seconds)
+	minRaise := o.MinRaise.String()
+	for _, tier := range o.Increments {
+		minRaise += "," + tier.String()
+	}
+	timeFrame := fmt.Sprintf("%d%s", seconds, o.SoftClose)
+	if o.SoftClose.HardClose {
+		timeFrame = "hard"
+	}
+	s := fmt.Sprintf("English|%s|%s|%s", o.ReservePrice, minRaise, timeFrame)
+	if o.HasBuyNow() {
+		s += fmt.Sprintf("|%s", o.BuyNowPrice)
+		if o.BuyNowUntilReserveMet {
+			s += "|1"
+		}
+	}
+	return s
+}
+
+// reservePrice returns the reserve price, and false if there is none
+func (o TimedAscendingOptions) reservePrice() (Amount, bool) {
+	return o.ReservePrice, o.ReservePrice.Value != 0
+}
+
+// IncrementAt returns the minimum raise over a highest bid of the given amount:
+// the increment of the last tier it reaches, or MinRaise below the first tier
+func (o TimedAscendingOptions) IncrementAt(amount Amount) Amount {
+	increment := o.MinRaise
+	for _, tier := range o.Increments {
+		if amount.LessThan(tier.From) {
+			break
+		}
+		increment = tier.Increment
+	}
+	return increment
+}
+
+// extendedExpiry returns the expiry of an auction after a bid at the given time, and true
+// if the bid extended it. The auction was originally to end at scheduledExpiry and has
+// been extended the given number of times.
+func (o TimedAscendingOptions) extendedExpiry(now, expiry, scheduledExpiry time.Time, extensions int) (time.Time, bool) {
+	policy := o.SoftClose
+	if policy.HardClose || (policy.MaxExtensions > 0 && extensions >= policy.MaxExtensions) {
+		return expiry, false
+	}
+
+	window := o.TimeFrame
+	if policy.Window > 0 {
+		window = policy.Window
+	}
+	if !now.Add(window).After(expiry) {
+		return expiry, false
+	}
+
+	next := now.Add(o.TimeFrame)
+	if policy.Extension > 0 {
+		next = expiry.Add(policy.Extension)
+	}
+	if limit := scheduledExpiry.Add(policy.MaxExtension); policy.MaxExtension > 0 && next.After(limit) {
+		next = limit
+	}
+	if !next.After(expiry) {
+		return expiry, false
+	}
+	return next, true
+}
+
+// HasBuyNow returns true if the auction has a buy-now price
+func (o TimedAscendingOptions) HasBuyNow() bool {
+	return o.BuyNowPrice.Value != 0
+}
+
+// InCurrency returns the options with their amounts in the given currency.
+// It returns a CurrencyMismatch error if an amount is in another currency.
+func (o TimedAscendingOptions) InCurrency(currency Currency) (TimedAscendingOptions, error) {
+	reservePrice, err := o.ReservePrice.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+	minRaise, err := o.MinRaise.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+	buyNowPrice, err := o.BuyNowPrice.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+	var increments []IncrementTier
+	for _, tier := range o.Increments {
+		from, err := tier.From.InCurrency(currency)
+		if err != nil {
+			return o, err
+		}
+		increment, err := tier.Increment.InCurrency(currency)
+		if err != nil {
+			return o, err
+		}
+		increments = append(increments, IncrementTier{From: from, Increment: increment})
+	}
+
+	o.ReservePrice = reservePrice
+	o.MinRaise = minRaise
+	o.Increments = increments
+	o.BuyNowPrice = buyNowPrice
+	return o, nil
+}
+
+// Validate checks that the amounts of the options are in the given currency and are not
+// negative, that the tiers of an increment table ascend, and that a buy-now price exceeds
+// the reserve price
+func (o TimedAscendingOptions) Validate(currency Currency) error {
+	// Plain values are compared once they are in the minor unit of the auction's currency
+	o, err := o.InCurrency(currency)
+	if err != nil {
+		return err
+	}
+	if o.ReservePrice.IsNegative() {
+		return NewNegativeAmountError("reservePrice", o.ReservePrice)
+	}
+	if o.MinRaise.IsNegative() {
+		return NewNegativeAmountError("minRaise", o.MinRaise)
+	}
+	for i, tier := range o.Increments {
+		if tier.Increment.IsNegative() {
+			return NewNegativeAmountError("increment", tier.Increment)
+		}
+		if !tier.From.IsPositive() || (i > 0 && !tier.From.GreaterThan(o.Increments[i-1].From)) {
+			return NewIncrementsNotAscendingError(tier.From)
+		}
+	}
+	if o.BuyNowPrice.IsNegative() {
+		return NewNegativeAmountError("buyNowPrice", o.BuyNowPrice)
+	}
+	if o.HasBuyNow() && !o.BuyNowPrice.GreaterThan(o.ReservePrice) {
+		return NewBuyNowBelowReserveError(o.BuyNowPrice, o.ReservePrice)
+	}
+	return nil
 }
 
 // ParseTimedAscendingOptions parses a string into TimedAscendingOptions
 func ParseTimedAscendingOptions(s string) (*TimedAscendingOptions, error) {
 	// Split the string by '|'
+	// The buy-now price and whether it is withdrawn once the reserve is met are optional
 	parts := strings.Split(s, "|")
-	if len(parts) != 4 || parts[0] != "English" {
+	if len(parts) < 4 || len(parts) > 6 || parts[0] != "English" {
 		return nil, fmt.Errorf("invalid timed ascending options format: %s", s)
 	}
 
-	// Parse reserve price
-	reserveAmount, err := strconv.ParseInt(parts[1], 10, 64)
+	// Parse reserve price, either an amount like "SEK100" or a plain value in the auction's currency
+	reserveAmount, err := parseAmountOrValue(parts[1])
 	if err != nil {
 		return nil, fmt.Errorf("invalid reserve price format: %s", parts[1])
 	}
 
-	// Parse min raise
-	minRaiseAmount, err := strconv.ParseInt(parts[2], 10, 64)
+	// Parse min raise, optionally followed by an increment table like "SEK1,SEK100:SEK5,SEK1000:SEK50"
+	tiers := strings.Split(parts[2], ",")
+	minRaiseAmount, err := parseAmountOrValue(tiers[0])
 	if err != nil {
 		return nil, fmt.Errorf("invalid min raise format: %s", parts[2])
 	}
+	var increments []IncrementTier
+	for _, tier := range tiers[1:] {
+		fromAndIncrement := strings.Split(tier, ":")
+		if len(fromAndIncrement) != 2 {
+			return nil, fmt.Errorf("invalid increment tier format: %s", tier)
+		}
+		from, err := parseAmountOrValue(fromAndIncrement[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid increment tier format: %s", tier)
+		}
+		increment, err := parseAmountOrValue(fromAndIncrement[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid increment tier format: %s", tier)
+		}
+		increments = append(increments, IncrementTier{From: from, Increment: increment})
+	}
 
-	// Parse seconds
-	seconds, err := strconv.Atoi(parts[3])
+	// Parse seconds, optionally followed by a soft close policy like "120,within:300,max:5",
+	// or "hard" for an auction that is never extended
+	seconds, softClose, err := parseTimeFrame(parts[3])
 	if err != nil {
-		return nil, fmt.Errorf("invalid time frame format: %s", parts[3])
+		return nil, err
 	}
 
-	return &TimedAscendingOptions{
+	options := &TimedAscendingOptions{
 		ReservePrice: reserveAmount,
 		MinRaise:     minRaiseAmount,
+		Increments:   increments,
 		TimeFrame:    time.Duration(seconds) * time.Second,
-	}, nil
+		SoftClose:    softClose,
+	}
+
+	// Parse buy-now price
+	if len(parts) > 4 {
+		buyNowAmount, err := parseAmountOrValue(parts[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid buy-now price format: %s", parts[4])
+		}
+		options.BuyNowPrice = buyNowAmount
+	}
+
+	// Parse whether the buy-now price is withdrawn once the reserve is met
+	if len(parts) > 5 {
+		untilReserveMet, err := strconv.ParseBool(parts[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid buy-now withdrawal format: %s", parts[5])
+		}
+		options.BuyNowUntilReserveMet = untilReserveMet
+	}
+
+	return options, nil
+}
+
+// parseTimeFrame parses the time frame segment of timed ascending options into the
+// time frame in seconds and the soft close policy
+func parseTimeFrame(s string) (int, SoftClosePolicy, error) {
+	if s == "hard" {
+		return 0, SoftClosePolicy{HardClose: true}, nil
+	}
+
+	settings := strings.Split(s, ",")
+	seconds, err := strconv.Atoi(settings[0])
+	if err != nil || seconds < 0 {
+		return 0, SoftClosePolicy{}, fmt.Errorf("invalid time frame format: %s", s)
+	}
+
+	var policy SoftClosePolicy
+	for _, setting := range settings[1:] {
+		keyAndValue := strings.Split(setting, ":")
+		if len(keyAndValue) != 2 {
+			return 0, policy, fmt.Errorf("invalid soft close format: %s", setting)
+		}
+		value, err := strconv.Atoi(keyAndValue[1])
+		if err != nil || value <= 0 {
+			return 0, policy, fmt.Errorf("invalid soft close format: %s", setting)
+		}
+		switch keyAndValue[0] {
+		case "within":
+			policy.Window = time.Duration(value) * time.Second
+		case "by":
+			policy.Extension = time.Duration(value) * time.Second
+		case "max":
+			policy.MaxExtensions = value
+		case "upto":
+			policy.MaxExtension = time.Duration(value) * time.Second
+		default:
+			return 0, policy, fmt.Errorf("invalid soft close format: %s", setting)
+		}
+	}
+	return seconds, policy, nil
 }
 
 // DefaultTimedAscendingOptions creates default options
 func DefaultTimedAscendingOptions() TimedAscendingOptions {
 	return TimedAscendingOptions{
-		ReservePrice: 0,
-		MinRaise:     0,
+		ReservePrice: Amount{},
+		MinRaise:     Amount{},
 		TimeFrame:    0,
 	}
 }
@@ -86,9 +406,15 @@ func (s *AwaitingStartState) isTimedAscendingState() {}
 
 // OngoingState represents a timed ascending auction that is currently active
 type OngoingState struct {
-	bids       []Bid
+	// bids holds the visible, proxy-resolved bids with the leading bid first
+	bids []Bid
+	// leaderMax is the hidden maximum of the leading bidder
+	leaderMax  Amount
 	nextExpiry time.Time
-	options    TimedAscendingOptions
+	// scheduledExpiry is the expiry before the auction was extended by bids close to it
+	scheduledExpiry time.Time
+	extensions      int
+	options         TimedAscendingOptions
 }
 
 func (s *OngoingState) isTimedAscendingState() {}
@@ -117,9 +443,10 @@ func (s *AwaitingStartState) Increment(now time.Time) State {
 		if now.Before(s.startingExpiry) {
 			// Transition to OngoingState
 			return &OngoingState{
-				bids:       []Bid{},
-				nextExpiry: s.startingExpiry,
-				options:    s.options,
+				bids:            []Bid{},
+				nextExpiry:      s.startingExpiry,
+				scheduledExpiry: s.startingExpiry,
+				options:         s.options,
 			}
 		}
 		// Transition directly to EndedState
@@ -148,8 +475,8 @@ func (s *AwaitingStartState) GetBids() []Bid {
 }
 
 // TryGetAmountAndWinner attempts to get the winning amount and bidder
-func (s *AwaitingStartState) TryGetAmountAndWinner() (int64, UserId, bool) {
-	return 0, "", false
+func (s *AwaitingStartState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	return Amount{}, "", false
 }
 
 // HasEnded returns true if the auction has ended
@@ -157,6 +484,31 @@ func (s *AwaitingStartState) HasEnded() bool {
 	return false
 }
 
+// NextExpiry returns the time at which the auction is to end
+func (s *AwaitingStartState) NextExpiry() time.Time {
+	return s.startingExpiry
+}
+
+// ReservePrice returns the reserve price of the auction, and false if it has none
+func (s *AwaitingStartState) ReservePrice() (Amount, bool) {
+	return s.options.reservePrice()
+}
+
+// ReserveMet returns false, as no bids can have been placed before the start
+func (s *AwaitingStartState) ReserveMet() bool {
+	return false
+}
+
+// RetractBids removes all bids of the bidder from the AwaitingStartState
+func (s *AwaitingStartState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	next := s.Increment(at)
+	if _, ok := next.(*AwaitingStartState); ok {
+		// No bids can have been placed before the start
+		return s, NewBidNotFoundError(bidder)
+	}
+	return next.RetractBids(bidder, at)
+}
+
 // Increment advances the OngoingState based on the current time
 func (s *OngoingState) Increment(now time.Time) State {
 	if now.After(s.nextExpiry) || now.Equal(s.nextExpiry) {
@@ -171,50 +523,186 @@ func (s *OngoingState) Increment(now time.Time) State {
 	return s
 }
 
+// addUpTo returns a + b, but no more than limit. A sum too large to represent is above the limit.
+func addUpTo(a, b, limit Amount) (Amount, error) {
+	sum, err := a.Add(b)
+	if isAmountOverflow(err) {
+		return limit, nil
+	}
+	if err != nil {
+		return Amount{}, err
+	}
+	if sum.GreaterThan(limit) {
+		return limit, nil
+	}
+	return sum, nil
+}
+
 // AddBid attempts to add a bid to the OngoingState
 func (s *OngoingState) AddBid(bid Bid) (State, error) {
 	now := bid.At
-	bidAmount := bid.Amount
 
 	next := s.Increment(now)
 	if _, ok := next.(*EndedState); ok {
 		return next, NewAuctionHasEndedError(bid.ForAuction)
 	}
 
-	// We're still in OngoingState
-	newExpiry := s.nextExpiry
-	if now.Add(s.options.TimeFrame).After(newExpiry) {
-		newExpiry = now.Add(s.options.TimeFrame)
+	// A bid at or above the buy-now price buys the item at that price
+	if price, ok := s.BuyNowPrice(); ok {
+		if c, err := bid.Amount.Compare(price); err == nil && c >= 0 {
+			return s.buyNow(bid, price), nil
+		}
 	}
 
+	// We're still in OngoingState
 	if len(s.bids) == 0 {
 		// First bid is always accepted
+		newExpiry, extensions := s.extended(now)
 		return &OngoingState{
-			bids:       append([]Bid{bid}, s.bids...),
-			nextExpiry: newExpiry,
-			options:    s.options,
+			bids:            []Bid{visibleBid(bid, bid.Amount)},
+			leaderMax:       bid.Ceiling(),
+			nextExpiry:      newExpiry,
+			scheduledExpiry: s.scheduledExpiry,
+			extensions:      extensions,
+			options:         s.options,
 		}, nil
 	}
 
-	// Check if bid is higher than the current highest bid + minimum raise
+	// Check if the bidder's ceiling reaches the current highest bid + minimum raise
 	highestBid := s.bids[0]
 	highestAmount := highestBid.Amount
-	minRaiseAmount := s.options.MinRaise
 
-	// Calculate minimum acceptable bid
-	minAcceptableBid := highestAmount + minRaiseAmount
+	// Calculate minimum acceptable bid; one too large to represent is an AmountOverflow error,
+	// as no bid can reach it
+	minAcceptableBid, err := highestAmount.Add(s.options.IncrementAt(highestAmount))
+	if err != nil {
+		return s, err
+	}
+	ceiling := bid.Ceiling()
 
-	// Changed comparison from <= to <, and using >= for the check
-	if bidAmount >= minAcceptableBid {
-		// Bid is acceptable
-		return &OngoingState{
-			bids:       append([]Bid{bid}, s.bids...),
-			nextExpiry: newExpiry,
-			options:    s.options,
-		}, nil
+	if ceiling.LessThan(minAcceptableBid) {
+		return s, NewMustPlaceBidOverHighestError(minAcceptableBid)
+	}
+
+	leaderMax := s.leaderMax
+	if leaderMax.LessThan(highestAmount) {
+		leaderMax = highestAmount
+	}
+
+	var resolved []Bid
+	nextLeaderMax := ceiling
+
+	switch {
+	case highestBid.Bidder.ID == bid.Bidder.ID:
+		// The leader raises their own maximum; they do not bid against themselves,
+		// so the visible price stays where it is
+		if leaderMax.GreaterThan(nextLeaderMax) {
+			nextLeaderMax = leaderMax
+		}
+	case ceiling.GreaterThan(leaderMax) || !leaderMax.GreaterThan(highestAmount):
+		// The challenger takes the lead, outbidding the previous leader's proxy
+		price, err := addUpTo(leaderMax, s.options.IncrementAt(leaderMax), ceiling)
+		if err != nil {
+			return s, err
+		}
+		if bid.Amount.GreaterThan(price) {
+			price = bid.Amount
+		}
+		if leaderMax.GreaterThan(highestAmount) {
+			resolved = append(resolved, visibleBid(Bid{
+				ForAuction: highestBid.ForAuction,
+				Bidder:     highestBid.Bidder,
+				At:         bid.At,
+			}, leaderMax))
+		}
+		resolved = append(resolved, visibleBid(bid, price))
+	default:
+		// The previous leader's proxy defends; the earlier bidder wins ties
+		price, err := addUpTo(ceiling, s.options.IncrementAt(ceiling), leaderMax)
+		if err != nil {
+			return s, err
+		}
+		resolved = []Bid{
+			visibleBid(bid, ceiling),
+			visibleBid(Bid{
+				ForAuction: highestBid.ForAuction,
+				Bidder:     highestBid.Bidder,
+				At:         bid.At,
+			}, price),
+		}
+		nextLeaderMax = leaderMax
+	}
+
+	// Only a bid that changes the visible price or the leader extends the auction,
+	// so a leader raising their own maximum does not
+	newExpiry, extensions := s.nextExpiry, s.extensions
+	if len(resolved) > 0 {
+		newExpiry, extensions = s.extended(now)
+	}
+
+	// Prepend resolved bids so that the leading bid comes first
+	bids := make([]Bid, 0, len(resolved)+len(s.bids))
+	for i := len(resolved) - 1; i >= 0; i-- {
+		bids = append(bids, resolved[i])
 	}
+	bids = append(bids, s.bids...)
+
+	return &OngoingState{
+		bids:            bids,
+		leaderMax:       nextLeaderMax,
+		nextExpiry:      newExpiry,
+		scheduledExpiry: s.scheduledExpiry,
+		extensions:      extensions,
+		options:         s.options,
+	}, nil
+}
+
+// extended returns the expiry of the auction and the number of times it has been extended
+// after a bid at the given time that changes the visible price or the leader
+func (s *OngoingState) extended(now time.Time) (time.Time, int) {
+	newExpiry, extended := s.options.extendedExpiry(now, s.nextExpiry, s.scheduledExpiry, s.extensions)
+	if extended {
+		return newExpiry, s.extensions + 1
+	}
+	return newExpiry, s.extensions
+}
+
+// visibleBid returns a copy of the bid at the given amount with its hidden maximum removed
+func visibleBid(bid Bid, amount Amount) Bid {
+	return Bid{
+		ForAuction: bid.ForAuction,
+		Bidder:     bid.Bidder,
+		At:         bid.At,
+		Amount:     amount,
+	}
+}
+
+// BuyNowPrice returns the price at which the item can be bought immediately, and false
+// if the auction has no buy-now price or it was withdrawn, because the reserve was met
+// or because proxy bidding raised the highest bid to the buy-now price
+func (s *OngoingState) BuyNowPrice() (Amount, bool) {
+	if !s.options.HasBuyNow() {
+		return Amount{}, false
+	}
+	if s.options.BuyNowUntilReserveMet && s.ReserveMet() {
+		return Amount{}, false
+	}
+	if len(s.bids) > 0 && !s.bids[0].Amount.LessThan(s.options.BuyNowPrice) {
+		return Amount{}, false
+	}
+	return s.options.BuyNowPrice, true
+}
 
-	return s, NewMustPlaceBidOverHighestError(highestAmount)
+// buyNow ends the auction with the bidder winning at the buy-now price
+func (s *OngoingState) buyNow(bid Bid, price Amount) *EndedState {
+	bids := make([]Bid, 0, len(s.bids)+1)
+	bids = append(bids, visibleBid(bid, price))
+	bids = append(bids, s.bids...)
+	return &EndedState{
+		bids:    bids,
+		expiry:  bid.At,
+		options: s.options,
+	}
 }
 
 // GetBids returns all bids in the OngoingState
@@ -223,8 +711,8 @@ func (s *OngoingState) GetBids() []Bid {
 }
 
 // TryGetAmountAndWinner attempts to get the winning amount and bidder
-func (s *OngoingState) TryGetAmountAndWinner() (int64, UserId, bool) {
-	return 0, "", false
+func (s *OngoingState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	return Amount{}, "", false
 }
 
 // HasEnded returns true if the auction has ended
@@ -232,6 +720,53 @@ func (s *OngoingState) HasEnded() bool {
 	return false
 }
 
+// NextExpiry returns the time at which the auction ends unless a bid extends it
+func (s *OngoingState) NextExpiry() time.Time {
+	return s.nextExpiry
+}
+
+// ReservePrice returns the reserve price of the auction, and false if it has none
+func (s *OngoingState) ReservePrice() (Amount, bool) {
+	return s.options.reservePrice()
+}
+
+// ReserveMet returns true if the highest bid reaches the reserve price
+func (s *OngoingState) ReserveMet() bool {
+	return meetsReserve(s.bids, s.options)
+}
+
+// RetractBids removes all bids of the bidder from the OngoingState.
+// If the bidder was leading, the next-highest bid becomes the leader; since
+// only visible bids are kept, its bidder's hidden maximum is not restored.
+func (s *OngoingState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	next := s.Increment(at)
+	if ended, ok := next.(*EndedState); ok {
+		return ended.RetractBids(bidder, at)
+	}
+
+	if len(bidsOf(s.bids, bidder)) == 0 {
+		return s, NewBidNotFoundError(bidder)
+	}
+
+	bids := withoutBidsOf(s.bids, bidder)
+	leaderMax := s.leaderMax
+	if s.bids[0].Bidder.ID == bidder {
+		leaderMax = Amount{}
+		if len(bids) > 0 {
+			leaderMax = bids[0].Amount
+		}
+	}
+
+	return &OngoingState{
+		bids:            bids,
+		leaderMax:       leaderMax,
+		nextExpiry:      s.nextExpiry,
+		scheduledExpiry: s.scheduledExpiry,
+		extensions:      s.extensions,
+		options:         s.options,
+	}, nil
+}
+
 // Increment advances the EndedState based on the current time
 func (s *EndedState) Increment(now time.Time) State {
 	// EndedState doesn't change
@@ -249,22 +784,43 @@ func (s *EndedState) GetBids() []Bid {
 }
 
 // TryGetAmountAndWinner attempts to get the winning amount and bidder
-func (s *EndedState) TryGetAmountAndWinner() (int64, UserId, bool) {
-	if len(s.bids) == 0 {
-		return 0, "", false
+func (s *EndedState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	// Check if highest bid reaches reserve price
+	if !meetsReserve(s.bids, s.options) {
+		return Amount{}, "", false
 	}
 
 	highestBid := s.bids[0]
-
-	// Check if highest bid exceeds reserve price
-	if highestBid.Amount > s.options.ReservePrice {
-		return highestBid.Amount, highestBid.Bidder.ID, true
-	}
-
-	return 0, "", false
+	return highestBid.Amount, highestBid.Bidder.ID, true
 }
 
 // HasEnded returns true if the auction has ended
 func (s *EndedState) HasEnded() bool {
 	return true
 }
+
+// NextExpiry returns the time at which the auction ended
+func (s *EndedState) NextExpiry() time.Time {
+	return s.expiry
+}
+
+// ReservePrice returns the reserve price of the auction, and false if it has none
+func (s *EndedState) ReservePrice() (Amount, bool) {
+	return s.options.reservePrice()
+}
+
+// ReserveMet returns true if the highest bid reaches the reserve price
+func (s *EndedState) ReserveMet() bool {
+	return meetsReserve(s.bids, s.options)
+}
+
+// meetsReserve returns true if the leading bid reaches the reserve price,
+// as in sealed-bid auctions, where a bid at the reserve price wins
+func meetsReserve(bids []Bid, options TimedAscendingOptions) bool {
+	return len(bids) > 0 && !bids[0].Amount.LessThan(options.ReservePrice)
+}
+
+// RetractBids removes all bids of the bidder from the EndedState
+func (s *EndedState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	return retractFromEnded(s, bidder, NewAuctionHasEndedError)
+}
@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	MustRegisterAuctionType(AuctionTypeDefinition{
+		Name: "Dutch",
+		Type: TimedDescending,
+		ParseOptions: func(s string) (interface{}, error) {
+			options, err := ParseTimedDescendingOptions(s)
+			if err != nil {
+				return nil, err
+			}
+			return *options, nil
+		},
+		FormatOptions: func(options interface{}) string {
+			return options.(TimedDescendingOptions).String()
+		},
+		DefaultOptions: func() interface{} {
+			return DefaultTimedDescendingOptions()
+		},
+		CreateEmptyState: func(auction Auction, options interface{}) State {
+			// Plain values in the options are whole units of the auction's currency
+			inCurrency, err := options.(TimedDescendingOptions).InCurrency(auction.Currency)
+			if err != nil {
+				inCurrency = options.(TimedDescendingOptions)
+			}
+			return NewTimedDescendingState(auction.StartsAt, auction.Expiry, inCurrency)
+		},
+		Validate: func(options interface{}, currency Currency) error {
+			return options.(TimedDescendingOptions).Validate(currency)
+		},
+	})
+}
+
+// TimedDescendingOptions defines the options for a timed descending (Dutch) auction
+type TimedDescendingOptions struct {
+	// The price at which the clock starts when the auction opens
+	StartPrice Amount `json:"startPrice"`
+
+	// The amount by which the price drops for every elapsed tick
+	Decrement Amount `json:"decrement"`
+
+	// The interval between price drops
+	TickInterval time.Duration `json:"tickInterval"`
+
+	// The price never drops below the floor; the item stays at this price until expiry
+	FloorPrice Amount `json:"floorPrice"`
+}
+
+// String returns a string representation of the options
+func (o TimedDescendingOptions) String() string {
+	seconds := int(o.TickInterval.Seconds())
+	return fmt.Sprintf("Dutch|%s|%s|%d|%s", o.StartPrice, o.Decrement, seconds, o.FloorPrice)
+}
+
+// InCurrency returns the options with their amounts in the given currency.
+// It returns a CurrencyMismatch error if an amount is in another currency.
+func (o TimedDescendingOptions) InCurrency(currency Currency) (TimedDescendingOptions, error) {
+	startPrice, err := o.StartPrice.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+	decrement, err := o.Decrement.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+	floorPrice, err := o.FloorPrice.InCurrency(currency)
+	if err != nil {
+		return o, err
+	}
+
+	o.StartPrice = startPrice
+	o.Decrement = decrement
+	o.FloorPrice = floorPrice
+	return o, nil
+}
+
+// Validate checks that the amounts of the options are in the given currency, and that the
+// clock starts and drops by positive amounts down to a floor no higher than the start price
+func (o TimedDescendingOptions) Validate(currency Currency) error {
+	o, err := o.InCurrency(currency)
+	if err != nil {
+		return err
+	}
+	if !o.StartPrice.IsPositive() {
+		return NewAmountNotPositiveError("startPrice", o.StartPrice)
+	}
+	if !o.Decrement.IsPositive() {
+		return NewAmountNotPositiveError("decrement", o.Decrement)
+	}
+	if o.FloorPrice.IsNegative() {
+		return NewNegativeAmountError("floorPrice", o.FloorPrice)
+	}
+	// The clock cannot run down from the start price to a floor above it
+	if o.FloorPrice.GreaterThan(o.StartPrice) {
+		return NewFloorAboveStartPriceError(o.FloorPrice, o.StartPrice)
+	}
+	return nil
+}
+
+// ParseTimedDescendingOptions parses a string into TimedDescendingOptions
+func ParseTimedDescendingOptions(s string) (*TimedDescendingOptions, error) {
+	// Split the string by '|'
+	parts := strings.Split(s, "|")
+	if len(parts) != 5 || parts[0] != "Dutch" {
+		return nil, fmt.Errorf("invalid timed descending options format: %s", s)
+	}
+
+	// Parse start price, either an amount like "SEK100" or a plain value in the auction's currency
+	startPrice, err := parseAmountOrValue(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid start price format: %s", parts[1])
+	}
+
+	// Parse decrement
+	decrement, err := parseAmountOrValue(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid decrement format: %s", parts[2])
+	}
+
+	// Parse seconds
+	seconds, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid tick interval format: %s", parts[3])
+	}
+
+	// Parse floor price
+	floorPrice, err := parseAmountOrValue(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid floor price format: %s", parts[4])
+	}
+
+	return &TimedDescendingOptions{
+		StartPrice:   startPrice,
+		Decrement:    decrement,
+		TickInterval: time.Duration(seconds) * time.Second,
+		FloorPrice:   floorPrice,
+	}, nil
+}
+
+// DefaultTimedDescendingOptions creates default options
+func DefaultTimedDescendingOptions() TimedDescendingOptions {
+	return TimedDescendingOptions{
+		StartPrice:   Amount{},
+		Decrement:    Amount{},
+		TickInterval: 0,
+		FloorPrice:   Amount{},
+	}
+}
+
+// PriceAt returns the clock price at the given time for an auction starting at start
+// A clock that does not drop by a positive amount stays at the start price.
+func (o TimedDescendingOptions) PriceAt(start, now time.Time) Amount {
+	if !now.After(start) || o.TickInterval <= 0 || !o.Decrement.IsPositive() {
+		return o.StartPrice
+	}
+
+	// The clock has run down to the floor once the ticks cover the range between the
+	// start and floor prices; checked first so that the drop cannot overflow
+	ticks := int64(now.Sub(start) / o.TickInterval)
+	priceRange, err := o.StartPrice.Sub(o.FloorPrice)
+	if err != nil || ticks > priceRange.Value/o.Decrement.Value {
+		return o.FloorPrice
+	}
+	price, err := o.StartPrice.Sub(Amount{Currency: o.Decrement.Currency, Value: ticks * o.Decrement.Value})
+	if err != nil || price.LessThan(o.FloorPrice) {
+		return o.FloorPrice
+	}
+	return price
+}
+
+// TimedDescendingState represents the state of a timed descending (Dutch) auction
+type TimedDescendingState struct {
+	start   time.Time
+	expiry  time.Time
+	options TimedDescendingOptions
+	// winner is the first accepted bid, if any
+	winner *Bid
+	ended  bool
+}
+
+// NewTimedDescendingState creates a new timed descending auction state
+func NewTimedDescendingState(start, expiry time.Time, options TimedDescendingOptions) *TimedDescendingState {
+	return &TimedDescendingState{
+		start:   start,
+		expiry:  expiry,
+		options: options,
+	}
+}
+
+// CurrentPrice returns the clock price at the given time
+func (s *TimedDescendingState) CurrentPrice(now time.Time) Amount {
+	return s.options.PriceAt(s.start, now)
+}
+
+// Increment advances the state based on the current time
+func (s *TimedDescendingState) Increment(now time.Time) State {
+	// If already ended, nothing changes
+	if s.ended {
+		return s
+	}
+
+	if now.After(s.expiry) || now.Equal(s.expiry) {
+		return &TimedDescendingState{
+			start:   s.start,
+			expiry:  s.expiry,
+			options: s.options,
+			ended:   true,
+		}
+	}
+
+	// No change needed
+	return s
+}
+
+// AddBid attempts to add a bid to the state
+// The first bid at or above the current clock price wins the auction
+func (s *TimedDescendingState) AddBid(bid Bid) (State, error) {
+	now := bid.At
+
+	next := s.Increment(now)
+	descendingState, ok := next.(*TimedDescendingState)
+	if !ok || descendingState.ended {
+		return next, NewAuctionHasEndedError(bid.ForAuction)
+	}
+
+	if !now.After(s.start) {
+		return s, NewAuctionHasNotStartedError(bid.ForAuction)
+	}
+
+	price := s.CurrentPrice(now)
+	if bid.Amount.LessThan(price) {
+		return s, NewMustPlaceBidOverHighestError(price)
+	}
+
+	winner := bid
+	return &TimedDescendingState{
+		start:   s.start,
+		expiry:  s.expiry,
+		options: s.options,
+		winner:  &winner,
+		ended:   true,
+	}, nil
+}
+
+// GetBids returns all bids in the state
+// The winning bid is shown at the clock price the winner pays, not at the amount bid.
+func (s *TimedDescendingState) GetBids() []Bid {
+	if s.winner == nil {
+		return []Bid{}
+	}
+	return []Bid{visibleBid(*s.winner, s.CurrentPrice(s.winner.At))}
+}
+
+// TryGetAmountAndWinner attempts to get the winning amount and bidder
+// The winner pays the clock price at the time of their bid
+func (s *TimedDescendingState) TryGetAmountAndWinner() (Amount, UserId, bool) {
+	if s.winner == nil {
+		return Amount{}, "", false
+	}
+	return s.CurrentPrice(s.winner.At), s.winner.Bidder.ID, true
+}
+
+// NextExpiry returns the time at which the auction ends: when the first bid at the
+// clock price was accepted, or else the expiry
+func (s *TimedDescendingState) NextExpiry() time.Time {
+	if s.winner != nil {
+		return s.winner.At
+	}
+	return s.expiry
+}
+
+// HasEnded returns true if the auction has ended
+func (s *TimedDescendingState) HasEnded() bool {
+	return s.ended
+}
+
+// RetractBids removes the bid of the bidder from the state.
+// The only bid of a Dutch auction ends it, so there is never a bid to retract while it is running.
+func (s *TimedDescendingState) RetractBids(bidder UserId, at time.Time) (State, error) {
+	next := s.Increment(at)
+	if next.HasEnded() {
+		return retractFromEnded(next, bidder, NewAuctionHasEndedError)
+	}
+	return s, NewBidNotFoundError(bidder)
+}
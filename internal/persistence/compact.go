@@ -0,0 +1,214 @@
+package persistence
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// CompactionResult summarizes a compaction of a segmented event log
+type CompactionResult struct {
+	// Auctions is the number of auctions whose history was archived
+	Auctions int
+	// Segments is the number of segments rewritten
+	Segments     int
+	EventsBefore int
+	EventsAfter  int
+}
+
+// auctionHistory tracks where the events of an auction are in the log
+type auctionHistory struct {
+	events  int
+	lastSeq int64
+	time    time.Time
+	// active is true if an event of the auction is in the active segment
+	active bool
+}
+
+// CompactSegments rewrites the sealed segments of the segmented event log in dir.
+// The history of every closed auction that has ended and whose events all lie in
+// sealed segments is replaced by a single AuctionArchivedEvent, recorded under the
+// sequence number of the auction's last event, so that replaying the compacted log
+// gives the same repository and snapshots taken before compaction remain valid.
+// The log must not be open for appending while it is compacted.
+func CompactSegments(dir string, options LogOptions) (CompactionResult, error) {
+	var result CompactionResult
+
+	index, err := ReadSegmentIndex(dir)
+	if err != nil {
+		return result, err
+	}
+
+	var sealedUpTo int64
+	for _, segment := range index {
+		if segment.Sealed {
+			sealedUpTo = segment.LastSeq
+		}
+	}
+
+	repo := make(domain.Repository)
+	closed := make(map[domain.AuctionId]bool)
+	histories := make(map[domain.AuctionId]*auctionHistory)
+	err = scanSegments(dir, options, index, decodeEvents(func(seq int64, event domain.Event) error {
+		domain.ApplyEvent(repo, event)
+		domain.MarkClosed(closed, event)
+
+		history, ok := histories[event.GetAuctionId()]
+		if !ok {
+			history = &auctionHistory{}
+			histories[event.GetAuctionId()] = history
+		}
+		history.events++
+		history.lastSeq = seq
+		history.time = event.GetTime()
+		history.active = history.active || seq > sealedUpTo
+
+		result.EventsBefore++
+		return nil
+	}))
+	if err != nil {
+		return result, err
+	}
+
+	archived := make(map[domain.AuctionId]*auctionHistory)
+	for id, history := range histories {
+		entry, ok := repo[id]
+		if !ok || !closed[id] || !entry.State.HasEnded() || history.active || history.events < 2 {
+			continue
+		}
+		if _, ok := entry.State.(domain.SnapshotableState); !ok {
+			continue
+		}
+		archived[id] = history
+	}
+	result.Auctions = len(archived)
+	if len(archived) == 0 {
+		result.EventsAfter = result.EventsBefore
+		return result, nil
+	}
+
+	// Write the compacted segments next to the current ones, then switch the index over
+	generation := time.Now().UnixNano()
+	compacted := append([]SegmentInfo{}, index...)
+	var written, replaced []string
+	removeWritten := func() {
+		for _, file := range written {
+			os.Remove(filepath.Join(dir, file))
+		}
+	}
+
+	for i, segment := range index {
+		if !segment.Sealed {
+			continue
+		}
+
+		rewritten := segment
+		rewritten.File = fmt.Sprintf("segment-%020d.compacted-%d.jsonl", segment.FirstSeq, generation)
+		rewritten.Compacted = true
+
+		changed, err := compactSegment(dir, options, segment, rewritten, repo, archived)
+		if err != nil {
+			removeWritten()
+			return result, err
+		}
+		if !changed {
+			os.Remove(filepath.Join(dir, rewritten.File))
+			continue
+		}
+
+		written = append(written, rewritten.File)
+		replaced = append(replaced, segment.File)
+		compacted[i] = rewritten
+	}
+	result.Segments = len(written)
+
+	// Verify that the compacted log replays to the same repository before using it
+	replayed := make(domain.Repository)
+	err = scanSegments(dir, options, compacted, decodeEvents(func(_ int64, event domain.Event) error {
+		domain.ApplyEvent(replayed, event)
+		result.EventsAfter++
+		return nil
+	}))
+	if err != nil {
+		removeWritten()
+		return result, fmt.Errorf("error reading compacted segments: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, repo) {
+		removeWritten()
+		return result, fmt.Errorf("compacted segments do not replay to the same repository")
+	}
+
+	if err := writeSegmentIndex(dir, compacted); err != nil {
+		removeWritten()
+		return result, err
+	}
+	for _, file := range replaced {
+		if err := os.Remove(filepath.Join(dir, file)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// compactSegment writes the records of segment to the file of rewritten, leaving out
+// the events of archived auctions and writing their archive in place of their last event.
+// It returns false when the segment holds no event of an archived auction.
+func compactSegment(dir string, options LogOptions, segment, rewritten SegmentInfo, repo domain.Repository, archived map[domain.AuctionId]*auctionHistory) (bool, error) {
+	file, err := os.OpenFile(filepath.Join(dir, rewritten.File), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	changed := false
+	err = scanFile(filepath.Join(dir, segment.File), options, segment.numbering(), func(pos recordPosition, data []byte) error {
+		event, err := domain.UnmarshalEvent(data)
+		if err != nil {
+			return &CorruptLogError{Path: pos.Path, Line: pos.Line, Offset: pos.Offset, Err: fmt.Errorf("error unmarshaling event: %v", err)}
+		}
+
+		id := event.GetAuctionId()
+		if history, ok := archived[id]; ok {
+			changed = true
+			if pos.Seq != history.lastSeq {
+				return nil
+			}
+
+			entry := repo[id]
+			data, err = json.Marshal(domain.AuctionArchivedEvent{
+				Time:    history.time,
+				Auction: entry.Auction,
+				State:   entry.State,
+				Events:  history.events,
+			})
+			if err != nil {
+				return fmt.Errorf("error archiving auction %d: %v", id, err)
+			}
+		}
+
+		line, err := encodeRecord(pos.Seq, data)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(line)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return false, err
+	}
+	if err := file.Sync(); err != nil {
+		return false, err
+	}
+	return changed, nil
+}
@@ -3,191 +3,140 @@ package persistence
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"auction-site-go/internal/domain"
 )
 
-// ReadCommands reads commands from a JSON file
-func ReadCommands(path string) ([]domain.Command, error) {
-	exists, err := fileExists(path)
-	if err != nil {
-		return nil, err
-	}
-	if !exists {
-		return []domain.Command{}, nil
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	lines := strings.Split(string(data), "\n")
-	commands := make([]domain.Command, 0, len(lines))
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+// ScanCommands calls fn with the sequence number of every command of a JSON file
+// and the command, in order, without loading the whole file. Records that cannot
+// be decoded are reported as a CorruptLogError with their line number and byte offset.
+func ScanCommands(path string, options LogOptions, fn func(seq int64, cmd domain.Command) error) error {
+	return scanFile(path, options, recordNumbering{}, decodeCommands(fn))
+}
 
-		cmd, err := domain.UnmarshalCommand([]byte(line))
+// decodeCommands returns a record callback that decodes commands and passes them to fn
+func decodeCommands(fn func(seq int64, cmd domain.Command) error) func(pos recordPosition, data []byte) error {
+	return func(pos recordPosition, data []byte) error {
+		cmd, err := domain.UnmarshalCommand(data)
 		if err != nil {
-			return nil, fmt.Errorf("error unmarshaling command: %v", err)
+			return &CorruptLogError{Path: pos.Path, Line: pos.Line, Offset: pos.Offset, Err: fmt.Errorf("error unmarshaling command: %v", err)}
 		}
+		return fn(pos.Seq, cmd)
+	}
+}
 
+// ReadCommands reads commands from a JSON file
+func ReadCommands(path string) ([]domain.Command, error) {
+	commands := []domain.Command{}
+	err := ScanCommands(path, LogOptions{}, func(_ int64, cmd domain.Command) error {
 		commands = append(commands, cmd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return commands, nil
 }
 
-// WriteCommands writes commands to a JSON file
+// WriteCommands appends commands to a JSON file.
+// The file is opened and recovered for every call; long-running writers should use an AppendLog.
 func WriteCommands(path string, commands []domain.Command) error {
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	// Check if file exists
-	exists, err := fileExists(path)
+	records, err := marshalCommands(commands)
 	if err != nil {
 		return err
 	}
+	return appendToFile(path, records)
+}
 
-	var file *os.File
-	if !exists {
-		file, err = os.Create(path)
-	} else {
-		file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	}
+// ScanEvents calls fn with the sequence number of every event of a JSON file
+// and the event, in order, without loading the whole file. Records that cannot
+// be decoded are reported as a CorruptLogError with their line number and byte offset.
+func ScanEvents(path string, options LogOptions, fn func(seq int64, event domain.Event) error) error {
+	return scanFile(path, options, recordNumbering{}, decodeEvents(fn))
+}
+
+// ScanSegmentedEvents calls fn with the sequence number of every event of the segmented
+// log in dir and the event, in order, without opening the log for appending
+func ScanSegmentedEvents(dir string, options LogOptions, fn func(seq int64, event domain.Event) error) error {
+	index, err := ReadSegmentIndex(dir)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	// Write commands
-	for _, cmd := range commands {
-		data, err := json.Marshal(cmd)
-		if err != nil {
-			return fmt.Errorf("error marshaling command: %v", err)
-		}
-
-		if exists {
-			_, err = file.WriteString("\n")
-			if err != nil {
-				return err
-			}
-		}
+	return scanSegments(dir, options, index, decodeEvents(fn))
+}
 
-		_, err = file.Write(data)
+// decodeEvents returns a record callback that decodes events and passes them to fn
+func decodeEvents(fn func(seq int64, event domain.Event) error) func(pos recordPosition, data []byte) error {
+	return func(pos recordPosition, data []byte) error {
+		event, err := domain.UnmarshalEvent(data)
 		if err != nil {
-			return err
+			return &CorruptLogError{Path: pos.Path, Line: pos.Line, Offset: pos.Offset, Err: fmt.Errorf("error unmarshaling event: %v", err)}
 		}
-
-		exists = true
+		return fn(pos.Seq, event)
 	}
-
-	return nil
 }
 
 // ReadEvents reads events from a JSON file
 func ReadEvents(path string) ([]domain.Event, error) {
-	exists, err := fileExists(path)
-	if err != nil {
-		return nil, err
-	}
-	if !exists {
-		return []domain.Event{}, nil
-	}
-
-	data, err := os.ReadFile(path)
+	events := []domain.Event{}
+	err := ScanEvents(path, LogOptions{}, func(_ int64, event domain.Event) error {
+		events = append(events, event)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(string(data), "\n")
-	events := make([]domain.Event, 0, len(lines))
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		event, err := domain.UnmarshalEvent([]byte(line))
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshaling event: %v", err)
-		}
-
-		events = append(events, event)
-	}
-
 	return events, nil
 }
 
-// WriteEvents writes events to a JSON file
+// WriteEvents appends events to a JSON file.
+// The file is opened and recovered for every call; long-running writers should use an AppendLog.
 func WriteEvents(path string, events []domain.Event) error {
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	// Check if file exists
-	exists, err := fileExists(path)
-	if err != nil {
-		return err
-	}
-
-	var file *os.File
-	if !exists {
-		file, err = os.Create(path)
-	} else {
-		file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	}
+	records, err := marshalEvents(events)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	return appendToFile(path, records)
+}
 
-	// Write events
-	for _, event := range events {
-		data, err := json.Marshal(event)
+// marshalCommands returns the JSON of each command
+func marshalCommands(commands []domain.Command) ([][]byte, error) {
+	records := make([][]byte, len(commands))
+	for i, cmd := range commands {
+		data, err := json.Marshal(cmd)
 		if err != nil {
-			return fmt.Errorf("error marshaling event: %v", err)
-		}
-
-		if exists {
-			_, err = file.WriteString("\n")
-			if err != nil {
-				return err
-			}
+			return nil, fmt.Errorf("error marshaling command: %v", err)
 		}
+		records[i] = data
+	}
+	return records, nil
+}
 
-		_, err = file.Write(data)
+// marshalEvents returns the JSON of each event
+func marshalEvents(events []domain.Event) ([][]byte, error) {
+	records := make([][]byte, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("error marshaling event: %v", err)
 		}
-
-		exists = true
+		records[i] = data
 	}
-
-	return nil
+	return records, nil
 }
 
-// fileExists checks if a file exists
-func fileExists(path string) (bool, error) {
-	_, err := os.Stat(path)
-	if err == nil {
-		return true, nil
+// appendToFile appends records to the log at path
+func appendToFile(path string, records [][]byte) error {
+	appendLog, err := OpenAppendLog(path, LogOptions{})
+	if err != nil {
+		return err
 	}
-	if os.IsNotExist(err) {
-		return false, nil
+
+	if err := appendLog.Append(records); err != nil {
+		appendLog.Close()
+		return err
 	}
-	return false, err
+	return appendLog.Close()
 }
@@ -0,0 +1,515 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Records of an append log are written one per line as
+//
+//	{"seq":1,"crc":123,"data":{...}}
+//
+// where seq numbers records consecutively from 1 and crc is the CRC-32C of data.
+// Lines without a seq are records written before checksums were introduced.
+// A segment of a segmented log numbers its records from the segment's first
+// sequence number, and a compacted segment may skip sequence numbers.
+
+// crcTable is the Castagnoli table used for record checksums
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// DefaultMaxRecordSize is the default limit on the size of a single log record
+const DefaultMaxRecordSize = 1 << 20
+
+// LogOptions configures reading and appending to a log
+type LogOptions struct {
+	// GroupCommitWindow is how long an fsync waits for concurrent appends to
+	// join it. With 0, appends that arrive while an fsync is running still share
+	// the next one, but no append is delayed.
+	GroupCommitWindow time.Duration
+
+	// MaxRecordSize is the largest record, including its line terminator, that
+	// can be read or appended; 0 means DefaultMaxRecordSize
+	MaxRecordSize int
+}
+
+// maxRecordSize returns the configured record size limit
+func (o LogOptions) maxRecordSize() int {
+	if o.MaxRecordSize <= 0 {
+		return DefaultMaxRecordSize
+	}
+	return o.MaxRecordSize
+}
+
+// CorruptLogError reports a record that cannot be read, with its line number
+// and byte offset in the log. A damaged last record is not reported, since it
+// is what an append interrupted by a crash leaves behind.
+type CorruptLogError struct {
+	Path   string
+	Line   int
+	Offset int64
+	Err    error
+}
+
+func (e *CorruptLogError) Error() string {
+	return fmt.Sprintf("%s: corrupt record at line %d (byte offset %d): %v", e.Path, e.Line, e.Offset, e.Err)
+}
+
+func (e *CorruptLogError) Unwrap() error {
+	return e.Err
+}
+
+// logScan is the result of scanning an append log
+type logScan struct {
+	// lastSeq is the sequence number of the last valid record
+	lastSeq int64
+	// validEnd is the byte offset just past the last valid record
+	validEnd int64
+	// size is the size of the scanned log
+	size int64
+	// unterminated is true if the last valid record is not followed by a newline
+	unterminated bool
+	// tailErr is the problem with the damaged tail, if there is one
+	tailErr error
+}
+
+// recordNumbering describes how the records of a log are numbered
+type recordNumbering struct {
+	// base is the sequence number before the first record
+	base int64
+	// sparse allows sequence numbers to be skipped, as in compacted segments
+	sparse bool
+}
+
+// recordPosition locates a record in a log
+type recordPosition struct {
+	Path   string
+	Seq    int64
+	Line   int
+	Offset int64
+}
+
+// scanLines is a bufio.SplitFunc returning lines including their newline,
+// so that byte offsets can be tracked and a missing final newline detected
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// scanLog calls fn with the position and data of every valid record read from r,
+// which starts at byte offset start of the log; lines are counted from there.
+// A damaged last record is reported in the scan result rather than as an error,
+// since it is what an append interrupted by a crash leaves behind.
+func scanLog(path string, r io.Reader, start int64, options LogOptions, numbering recordNumbering, fn func(pos recordPosition, data []byte) error) (logScan, error) {
+	scan := logScan{lastSeq: numbering.base, validEnd: start, size: start}
+	scanner := bufio.NewScanner(r)
+	// The scanner's limit is the larger of its initial buffer and the maximum
+	initial := 64 * 1024
+	if initial > options.maxRecordSize() {
+		initial = options.maxRecordSize()
+	}
+	scanner.Buffer(make([]byte, 0, initial), options.maxRecordSize())
+	scanner.Split(scanLines)
+
+	line := 0
+	offset := start
+	var damaged *CorruptLogError
+
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+
+		line++
+		start := offset
+		offset += int64(len(raw))
+		scan.size = offset
+
+		content := bytes.TrimSpace(raw)
+		if len(content) == 0 {
+			if damaged == nil {
+				scan.validEnd = offset
+			}
+			continue
+		}
+
+		// A damaged record followed by another record means the log was corrupted
+		// after it was written, not torn by a crash
+		if damaged != nil {
+			return scan, damaged
+		}
+
+		seq, data, err := decodeRecord(content, scan.lastSeq, numbering.sparse)
+		if err != nil {
+			damaged = &CorruptLogError{Path: path, Line: line, Offset: start, Err: err}
+			continue
+		}
+
+		if err := fn(recordPosition{Path: path, Seq: seq, Line: line, Offset: start}, data); err != nil {
+			return scan, err
+		}
+		scan.lastSeq = seq
+		scan.validEnd = offset
+		scan.unterminated = raw[len(raw)-1] != '\n'
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("record exceeds the maximum size of %d bytes", options.maxRecordSize())
+		}
+		return scan, &CorruptLogError{Path: path, Line: line + 1, Offset: offset, Err: err}
+	}
+
+	if damaged != nil {
+		scan.tailErr = damaged
+	}
+	return scan, nil
+}
+
+// decodeRecord validates a record and returns its sequence number and data
+func decodeRecord(content []byte, lastSeq int64, sparse bool) (int64, []byte, error) {
+	var record struct {
+		Seq  *int64          `json:"seq"`
+		CRC  uint32          `json:"crc"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(content, &record); err != nil {
+		return 0, nil, err
+	}
+
+	// Records written before checksums were introduced are numbered by position
+	if record.Seq == nil {
+		return lastSeq + 1, content, nil
+	}
+
+	if sparse && *record.Seq <= lastSeq {
+		return 0, nil, fmt.Errorf("expected sequence number after %d, got %d", lastSeq, *record.Seq)
+	}
+	if !sparse && *record.Seq != lastSeq+1 {
+		return 0, nil, fmt.Errorf("expected sequence number %d, got %d", lastSeq+1, *record.Seq)
+	}
+	if crc32.Checksum(record.Data, crcTable) != record.CRC {
+		return 0, nil, errors.New("checksum mismatch")
+	}
+	return *record.Seq, record.Data, nil
+}
+
+// encodeRecord returns the line for a record with the given sequence number
+func encodeRecord(seq int64, data []byte) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("{\"seq\":%d,\"crc\":%d,\"data\":%s}\n", seq, crc32.Checksum(compact.Bytes(), crcTable), compact.Bytes())), nil
+}
+
+// scanFile calls fn with the position and data of every valid record of the log at path.
+// A missing log is empty and a damaged tail is ignored.
+func scanFile(path string, options LogOptions, numbering recordNumbering, fn func(pos recordPosition, data []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = scanLog(path, file, 0, options, numbering, fn)
+	return err
+}
+
+// scanFileAfter calls fn with the position and data of every valid record of the log at
+// path after pos, reading from the byte offset of pos. It returns ErrPositionNotInLog if
+// the log is shorter than that offset.
+func scanFileAfter(path string, options LogOptions, numbering recordNumbering, pos LogPosition, fn func(pos recordPosition, data []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ErrPositionNotInLog
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if pos.Offset > info.Size() {
+		return ErrPositionNotInLog
+	}
+	if _, err := file.Seek(pos.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	numbering.base = pos.Seq
+	_, err = scanLog(path, file, pos.Offset, options, numbering, fn)
+	return err
+}
+
+// AppendLog is a durable, append-only log of JSON records.
+// Appends return once the records have been fsynced.
+type AppendLog struct {
+	path      string
+	options   LogOptions
+	numbering recordNumbering
+
+	// mu guards writes to the file
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+	size int64
+	// failed is the fsync failure after which the log refuses appends
+	failed error
+
+	// syncMu serializes fsyncs; synced is the last sequence number known to be durable
+	// and syncedSize the size of the log up to it
+	syncMu     sync.Mutex
+	synced     int64
+	syncedSize int64
+}
+
+// OpenAppendLog opens or creates the log at path. A damaged tail left by an
+// interrupted append is moved to a quarantine file next to the log and removed.
+func OpenAppendLog(path string, options LogOptions) (*AppendLog, error) {
+	return openAppendLog(path, options, recordNumbering{})
+}
+
+// openAppendLog opens or creates a log whose records are numbered as given
+func openAppendLog(path string, options LogOptions, numbering recordNumbering) (*AppendLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := scanLog(path, file, 0, options, numbering, func(recordPosition, []byte) error { return nil })
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if scan.tailErr != nil {
+		if err := quarantineTail(file, path, scan); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	size := scan.validEnd
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Terminate a last record written without a newline before appending to it
+	if scan.unterminated {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return nil, err
+		}
+		size++
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &AppendLog{
+		path:      path,
+		options:   options,
+		numbering: numbering,
+		file:      file,
+		seq:        scan.lastSeq,
+		size:       size,
+		synced:     scan.lastSeq,
+		syncedSize: size,
+	}, nil
+}
+
+// quarantineTail copies the damaged tail of a log into a quarantine file and truncates the log
+func quarantineTail(file *os.File, path string, scan logScan) error {
+	tail := make([]byte, scan.size-scan.validEnd)
+	if _, err := file.ReadAt(tail, scan.validEnd); err != nil && err != io.EOF {
+		return err
+	}
+
+	quarantine := fmt.Sprintf("%s.corrupt-%d", path, time.Now().UnixNano())
+	if err := os.WriteFile(quarantine, tail, 0644); err != nil {
+		return err
+	}
+	log.Printf("Quarantined damaged tail of %s (%d bytes) in %s: %v", path, len(tail), quarantine, scan.tailErr)
+
+	if err := file.Truncate(scan.validEnd); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Seq returns the sequence number of the last appended record
+func (l *AppendLog) Seq() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Size returns the size of the log in bytes
+func (l *AppendLog) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// scan calls fn with the position and data of every record appended so far
+func (l *AppendLog) scan(fn func(pos recordPosition, data []byte) error) error {
+	return scanFile(l.path, l.options, l.numbering, fn)
+}
+
+// position returns the position just past the last appended record
+func (l *AppendLog) position() LogPosition {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LogPosition{Seq: l.seq, Offset: l.size}
+}
+
+// scanAfter calls fn with the position and data of every record appended after pos,
+// reading from its byte offset
+func (l *AppendLog) scanAfter(pos LogPosition, fn func(pos recordPosition, data []byte) error) error {
+	if pos.Segment != "" || pos.Seq > l.Seq() {
+		return ErrPositionNotInLog
+	}
+	return scanFileAfter(l.path, l.options, l.numbering, pos, fn)
+}
+
+// Append appends records, each holding one JSON value, and waits until they are durable
+func (l *AppendLog) Append(records [][]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.failed != nil {
+		l.mu.Unlock()
+		return l.failed
+	}
+	var buf bytes.Buffer
+	for i, data := range records {
+		line, err := encodeRecord(l.seq+int64(i)+1, data)
+		if err != nil {
+			l.mu.Unlock()
+			return err
+		}
+		if len(line) > l.options.maxRecordSize() {
+			l.mu.Unlock()
+			return fmt.Errorf("record of %d bytes exceeds the maximum size of %d bytes", len(line), l.options.maxRecordSize())
+		}
+		buf.Write(line)
+	}
+
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		// Remove whatever part of the records made it to the file
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, io.SeekStart)
+		l.mu.Unlock()
+		return err
+	}
+	l.seq += int64(len(records))
+	l.size += int64(buf.Len())
+	target := l.seq
+	l.mu.Unlock()
+
+	return l.sync(target)
+}
+
+// sync waits until the record with the target sequence number is durable.
+// One fsync covers every record written before it starts, so concurrent
+// appenders queued behind a running fsync share the next one.
+func (l *AppendLog) sync(target int64) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+
+	if l.synced >= target {
+		return nil
+	}
+
+	if l.options.GroupCommitWindow > 0 {
+		time.Sleep(l.options.GroupCommitWindow)
+	}
+
+	l.mu.Lock()
+	if l.failed != nil {
+		l.mu.Unlock()
+		return l.failed
+	}
+	upTo, size := l.seq, l.size
+	l.mu.Unlock()
+
+	if err := l.file.Sync(); err != nil {
+		l.fail(err)
+		return l.failed
+	}
+	l.synced, l.syncedSize = upTo, size
+	return nil
+}
+
+// fail marks the log failed after an fsync failure. Once an fsync has failed, the kernel
+// may have dropped the written pages, so a later fsync succeeding proves nothing about
+// them; the log refuses further appends until it is reopened and scanned again.
+// The records that were not durable are removed as far as possible, so that the
+// appends reported as failed are not replayed. The caller holds syncMu.
+func (l *AppendLog) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failed = fmt.Errorf("%s: log failed to sync and accepts no more appends: %w", l.path, err)
+	if truncateErr := l.file.Truncate(l.syncedSize); truncateErr != nil {
+		log.Printf("Failed to remove records of %s that are not durable: %v", l.path, truncateErr)
+	}
+	l.file.Seek(l.syncedSize, io.SeekStart)
+	l.seq, l.size = l.synced, l.syncedSize
+}
+
+// err returns the failure after which the log refuses appends, if there was one
+func (l *AppendLog) err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.failed
+}
+
+// Close closes the log
+func (l *AppendLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// syncDir fsyncs a directory so that newly created files survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
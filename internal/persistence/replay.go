@@ -0,0 +1,275 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"auction-site-go/internal/domain"
+)
+
+// Divergence is a difference between the event log and the events regenerated
+// by replaying the command log
+type Divergence struct {
+	AuctionId domain.AuctionId
+	// Seq is the sequence number of the stored event, or 0 if the event is missing from the log
+	Seq int64
+	// Expected is the JSON of the regenerated event, or empty if replay produced no event
+	Expected string
+	// Actual is the JSON of the stored event, or empty if the event is missing from the log
+	Actual string
+}
+
+func (d Divergence) String() string {
+	switch {
+	case d.Actual == "":
+		return fmt.Sprintf("auction %d: missing event %s", d.AuctionId, d.Expected)
+	case d.Expected == "":
+		return fmt.Sprintf("auction %d: unexpected event %d %s", d.AuctionId, d.Seq, d.Actual)
+	default:
+		return fmt.Sprintf("auction %d: event %d is %s, expected %s", d.AuctionId, d.Seq, d.Actual, d.Expected)
+	}
+}
+
+// ReplayReport is the result of checking the event log against a replay of the command log
+type ReplayReport struct {
+	Commands int
+	Events   int
+	// Archived lists the auctions whose history was compacted and could not be checked
+	Archived    []domain.AuctionId
+	Divergences []Divergence
+}
+
+// storedEvent is an event of the log with its sequence number
+type storedEvent struct {
+	seq   int64
+	event domain.Event
+}
+
+// CheckReplay replays a command log through domain.Handle from an empty repository
+// and compares the events it produces with the event log. Closing events,
+// which the server records without a command, are regenerated at the time they were
+// recorded from the replayed state.
+//
+// Commands for different auctions may be logged in a different order than their events,
+// so the logs are compared auction by auction, in the order of each auction's events.
+func CheckReplay(scanCommands func(fn func(seq int64, cmd domain.Command) error) error, scanEvents func(fn func(seq int64, event domain.Event) error) error) (ReplayReport, error) {
+	var report ReplayReport
+
+	commands := make(map[domain.AuctionId][]domain.Command)
+	err := scanCommands(func(_ int64, cmd domain.Command) error {
+		// Outcomes and responses record what happened to the commands before them and are not replayed
+		switch cmd.(type) {
+		case domain.CommandOutcome, domain.IdempotentResponse:
+			return nil
+		}
+		commands[cmd.GetAuctionId()] = append(commands[cmd.GetAuctionId()], cmd)
+		report.Commands++
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("error reading commands: %v", err)
+	}
+
+	events := make(map[domain.AuctionId][]storedEvent)
+	err = scanEvents(func(seq int64, event domain.Event) error {
+		events[event.GetAuctionId()] = append(events[event.GetAuctionId()], storedEvent{seq: seq, event: event})
+		report.Events++
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("error reading events: %v", err)
+	}
+
+	ids := make([]domain.AuctionId, 0, len(commands)+len(events))
+	for id := range commands {
+		ids = append(ids, id)
+	}
+	for id := range events {
+		if _, ok := commands[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	repo := make(domain.Repository)
+	for _, id := range ids {
+		divergences, archived, err := checkAuction(repo, id, commands[id], events[id])
+		if err != nil {
+			return report, err
+		}
+		if archived {
+			report.Archived = append(report.Archived, id)
+		}
+		report.Divergences = append(report.Divergences, divergences...)
+	}
+	return report, nil
+}
+
+// checkAuction replays the commands of an auction and compares the resulting events
+// with its stored events. It returns true if the auction's history was archived.
+func checkAuction(repo domain.Repository, id domain.AuctionId, commands []domain.Command, stored []storedEvent) ([]Divergence, bool, error) {
+	var divergences []Divergence
+	diverge := func(seq int64, expected, actual domain.Event) error {
+		d := Divergence{AuctionId: id, Seq: seq}
+		if expected != nil {
+			data, err := json.Marshal(expected)
+			if err != nil {
+				return err
+			}
+			d.Expected = string(data)
+		}
+		if actual != nil {
+			data, err := json.Marshal(actual)
+			if err != nil {
+				return err
+			}
+			d.Actual = string(data)
+		}
+		if d.Expected != d.Actual {
+			divergences = append(divergences, d)
+		}
+		return nil
+	}
+
+	// handleNext handles the next command and returns its event, or nil if it is rejected
+	handleNext := func() domain.Event {
+		cmd := commands[0]
+		commands = commands[1:]
+
+		// Handle the command against the auction alone, as the server does
+		auction := make(domain.Repository)
+		if entry, ok := repo[id]; ok {
+			auction[id] = entry
+		}
+		event, newRepo, err := domain.Handle(cmd, auction)
+		if err != nil {
+			return nil
+		}
+		if entry, ok := newRepo[id]; ok {
+			repo[id] = entry
+		}
+		return event
+	}
+
+	// nextAccepted handles commands until one is accepted and returns its event
+	nextAccepted := func() domain.Event {
+		for len(commands) > 0 {
+			if event := handleNext(); event != nil {
+				return event
+			}
+		}
+		return nil
+	}
+
+	// Events stored before amounts carried a currency hold whole units of the auction's
+	// currency; compare them in its currency, as the server replays them
+	for _, s := range stored {
+		if added, ok := s.event.(domain.AuctionAddedEvent); ok {
+			stored = inCurrency(stored, added.Auction.Currency)
+			break
+		}
+	}
+
+	for i := 0; i < len(stored); i++ {
+		switch e := stored[i].event.(type) {
+		case domain.AuctionArchivedEvent:
+			return divergences, true, nil
+
+		case domain.AuctionEndedEvent:
+			// Commands up to the time the auction was closed were handled before it was closed
+			for len(commands) > 0 && !commands[0].GetTime().After(e.Time) {
+				if event := handleNext(); event != nil {
+					if err := diverge(0, event, nil); err != nil {
+						return nil, false, err
+					}
+				}
+			}
+
+			// Regenerate the closing events from the replayed state at the time the auction was closed
+			var expected []domain.Event
+			if entry, ok := repo[id]; ok {
+				state := entry.State.Increment(e.Time)
+				expected = domain.CloseAuction(e.Time, entry.Auction, state)
+				repo[id] = struct {
+					Auction domain.Auction
+					State   domain.State
+				}{
+					Auction: entry.Auction,
+					State:   state,
+				}
+			}
+
+			// The stored closing events are the AuctionEndedEvent and the outcome following it
+			end := i + 1
+			for end < len(stored) && isOutcomeEvent(stored[end].event) {
+				end++
+			}
+			for j := 0; j < len(expected) || i+j < end; j++ {
+				var seq int64
+				var want, got domain.Event
+				if j < len(expected) {
+					want = expected[j]
+				}
+				if i+j < end {
+					seq, got = stored[i+j].seq, stored[i+j].event
+				}
+				if err := diverge(seq, want, got); err != nil {
+					return nil, false, err
+				}
+			}
+			i = end - 1
+
+		case domain.AuctionWonEvent, domain.AuctionUnsoldEvent:
+			// Outcomes that do not follow an AuctionEndedEvent
+			if err := diverge(stored[i].seq, nil, e); err != nil {
+				return nil, false, err
+			}
+
+		default:
+			if err := diverge(stored[i].seq, nextAccepted(), e); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	// Commands accepted on replay after the last stored event
+	for event := nextAccepted(); event != nil; event = nextAccepted() {
+		if err := diverge(0, event, nil); err != nil {
+			return nil, false, err
+		}
+	}
+	return divergences, false, nil
+}
+
+// isOutcomeEvent returns true for the events announcing the outcome of a closed auction
+func isOutcomeEvent(event domain.Event) bool {
+	switch event.(type) {
+	case domain.AuctionWonEvent, domain.AuctionUnsoldEvent:
+		return true
+	default:
+		return false
+	}
+}
+
+// inCurrency returns the stored events with the amounts of accepted bids and won auctions
+// in the given currency, leaving amounts in another currency as they are
+func inCurrency(stored []storedEvent, currency domain.Currency) []storedEvent {
+	normalized := make([]storedEvent, len(stored))
+	for i, s := range stored {
+		switch e := s.event.(type) {
+		case domain.BidAcceptedEvent:
+			if bid, err := e.Bid.InCurrency(currency); err == nil {
+				e.Bid = bid
+				s.event = e
+			}
+		case domain.AuctionWonEvent:
+			if price, err := e.Price.InCurrency(currency); err == nil {
+				e.Price = price
+				s.event = e
+			}
+		}
+		normalized[i] = s
+	}
+	return normalized
+}
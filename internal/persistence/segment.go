@@ -0,0 +1,362 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A segmented log keeps its records in a directory of segment files, each an append
+// log numbering its records from the segment's first sequence number:
+//
+//	segment-00000000000000000001.jsonl
+//	segment-00000000000000000412.jsonl
+//	index.json
+//
+// The index lists the segments in order. Only the last segment is appended to;
+// once it reaches the size or age limit it is sealed and a new segment is started.
+
+// SegmentIndexVersion is the version of the index format written by this package
+const SegmentIndexVersion = 1
+
+// segmentIndexFile is the name of the index in a segment directory
+const segmentIndexFile = "index.json"
+
+// SegmentOptions configures when a segmented log rolls over to a new segment.
+// A limit of 0 disables it.
+type SegmentOptions struct {
+	// MaxBytes is the size at which a segment is sealed
+	MaxBytes int64
+
+	// MaxAge is how long a segment is appended to before it is sealed
+	MaxAge time.Duration
+}
+
+// SegmentInfo describes a segment in the index
+type SegmentInfo struct {
+	File string `json:"file"`
+	// FirstSeq is the sequence number of the first record the segment may hold
+	FirstSeq int64 `json:"firstSeq"`
+	// LastSeq is the sequence number of the last record of a sealed segment
+	LastSeq   int64     `json:"lastSeq,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Sealed    bool      `json:"sealed"`
+	// Compacted is true when the segment was rewritten by compaction and may skip sequence numbers
+	Compacted bool `json:"compacted,omitempty"`
+}
+
+// numbering returns how the records of the segment are numbered
+func (s SegmentInfo) numbering() recordNumbering {
+	return recordNumbering{base: s.FirstSeq - 1, sparse: s.Compacted}
+}
+
+type segmentIndex struct {
+	Version  int           `json:"version"`
+	Segments []SegmentInfo `json:"segments"`
+}
+
+// SegmentedLog is a durable, append-only log of JSON records split over segment files
+type SegmentedLog struct {
+	dir      string
+	options  LogOptions
+	segments SegmentOptions
+
+	// mu is held for reading while appending to the active segment and
+	// for writing while rolling over to a new one
+	mu     sync.RWMutex
+	index  []SegmentInfo
+	active *AppendLog
+}
+
+// OpenSegmentedLog opens or creates the segmented log in dir, recovering its
+// active segment from an interrupted append
+func OpenSegmentedLog(dir string, options LogOptions, segments SegmentOptions) (*SegmentedLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	index, err := ReadSegmentIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &SegmentedLog{dir: dir, options: options, segments: segments, index: index}
+	if len(index) == 0 {
+		if err := l.startSegment(index, 1); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	last := index[len(index)-1]
+	if last.Sealed {
+		if err := l.startSegment(index, last.LastSeq+1); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	active, err := openAppendLog(filepath.Join(dir, last.File), options, last.numbering())
+	if err != nil {
+		return nil, err
+	}
+	l.active = active
+	return l, nil
+}
+
+// ReadSegmentIndex reads the index of the segmented log in dir; a missing index is empty
+func ReadSegmentIndex(dir string) ([]SegmentInfo, error) {
+	data, err := os.ReadFile(filepath.Join(dir, segmentIndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index segmentIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("error reading segment index: %v", err)
+	}
+	if index.Version != SegmentIndexVersion {
+		return nil, fmt.Errorf("unsupported segment index version %d", index.Version)
+	}
+	return index.Segments, nil
+}
+
+// writeSegmentIndex atomically replaces the index of the segmented log in dir
+func writeSegmentIndex(dir string, segments []SegmentInfo) error {
+	data, err := json.MarshalIndent(segmentIndex{Version: SegmentIndexVersion, Segments: segments}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, segmentIndexFile), data)
+}
+
+// writeFileAtomic writes a file next to path and renames it over path once it is durable
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// segmentFileName returns the name of a segment starting at firstSeq
+func segmentFileName(firstSeq int64) string {
+	return fmt.Sprintf("segment-%020d.jsonl", firstSeq)
+}
+
+// startSegment creates a new active segment starting at firstSeq and records the
+// index of the segments before it followed by the new segment
+func (l *SegmentedLog) startSegment(index []SegmentInfo, firstSeq int64) error {
+	info := SegmentInfo{
+		File:      segmentFileName(firstSeq),
+		FirstSeq:  firstSeq,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	active, err := openAppendLog(filepath.Join(l.dir, info.File), l.options, info.numbering())
+	if err != nil {
+		return err
+	}
+
+	index = append(append([]SegmentInfo{}, index...), info)
+	if err := writeSegmentIndex(l.dir, index); err != nil {
+		active.Close()
+		return err
+	}
+
+	l.index = index
+	l.active = active
+	return nil
+}
+
+// AdoptLog moves the single-file log at path into dir as the first segment of a new
+// segmented log. It does nothing when dir already holds a segmented log or there
+// is no log at path.
+func AdoptLog(path, dir string) error {
+	index, err := ReadSegmentIndex(dir)
+	if err != nil {
+		return err
+	}
+	if len(index) > 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	info := SegmentInfo{File: segmentFileName(1), FirstSeq: 1, CreatedAt: time.Now().UTC()}
+	if err := os.Rename(path, filepath.Join(dir, info.File)); err != nil {
+		return err
+	}
+	if err := writeSegmentIndex(dir, []SegmentInfo{info}); err != nil {
+		return err
+	}
+	log.Printf("Moved %s into segmented log %s", path, dir)
+	return nil
+}
+
+// Seq returns the sequence number of the last appended record
+func (l *SegmentedLog) Seq() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active.Seq()
+}
+
+// Segments returns the segments listed in the index
+func (l *SegmentedLog) Segments() []SegmentInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]SegmentInfo{}, l.index...)
+}
+
+// Append appends records to the active segment, first rolling over to a new
+// segment if the active one has reached its limits, and waits until they are durable
+func (l *SegmentedLog) Append(records [][]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	l.mu.RLock()
+	full := l.isFull()
+	l.mu.RUnlock()
+
+	if full {
+		l.mu.Lock()
+		// Another append may have rolled over in the meantime
+		if l.isFull() {
+			if err := l.roll(); err != nil {
+				l.mu.Unlock()
+				return err
+			}
+		}
+		l.mu.Unlock()
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.active.Append(records)
+}
+
+// isFull returns true if the active segment has records and has reached a limit
+func (l *SegmentedLog) isFull() bool {
+	size := l.active.Size()
+	if size == 0 {
+		return false
+	}
+	if l.segments.MaxBytes > 0 && size >= l.segments.MaxBytes {
+		return true
+	}
+	created := l.index[len(l.index)-1].CreatedAt
+	return l.segments.MaxAge > 0 && time.Since(created) >= l.segments.MaxAge
+}
+
+// roll seals the active segment and starts a new one.
+// The active segment stays in use if the new one cannot be started, and a failed
+// one is not sealed, so that the log keeps refusing appends.
+func (l *SegmentedLog) roll() error {
+	if err := l.active.err(); err != nil {
+		return err
+	}
+	lastSeq := l.active.Seq()
+	index := append([]SegmentInfo{}, l.index...)
+	index[len(index)-1].Sealed = true
+	index[len(index)-1].LastSeq = lastSeq
+
+	sealed := l.active
+	if err := l.startSegment(index, lastSeq+1); err != nil {
+		return err
+	}
+	return sealed.Close()
+}
+
+// scan calls fn with the position and data of every record in every segment, in order
+func (l *SegmentedLog) scan(fn func(pos recordPosition, data []byte) error) error {
+	return scanSegments(l.dir, l.options, l.Segments(), fn)
+}
+
+// position returns the position just past the last record of the active segment
+func (l *SegmentedLog) position() LogPosition {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	pos := l.active.position()
+	pos.Segment = l.index[len(l.index)-1].File
+	return pos
+}
+
+// scanAfter calls fn with the position and data of every record after pos, in order.
+// It reads from the byte offset of pos when its segment is still listed in the index.
+// Otherwise the segment was rewritten by compaction, which keeps sequence numbers, or
+// pos is in a log that was moved into this one, so the records are read from the first
+// segment that may hold records after pos and those up to pos are skipped.
+func (l *SegmentedLog) scanAfter(pos LogPosition, fn func(pos recordPosition, data []byte) error) error {
+	if pos.Seq > l.Seq() {
+		return ErrPositionNotInLog
+	}
+
+	segments := l.Segments()
+	for i, segment := range segments {
+		if segment.File == pos.Segment {
+			if err := scanFileAfter(filepath.Join(l.dir, segment.File), l.options, segment.numbering(), pos, fn); err != nil {
+				return err
+			}
+			return scanSegments(l.dir, l.options, segments[i+1:], fn)
+		}
+	}
+
+	for i, segment := range segments {
+		if segment.Sealed && segment.LastSeq <= pos.Seq {
+			continue
+		}
+		return scanSegments(l.dir, l.options, segments[i:], func(record recordPosition, data []byte) error {
+			if record.Seq <= pos.Seq {
+				return nil
+			}
+			return fn(record, data)
+		})
+	}
+	return nil
+}
+
+// scanSegments calls fn with the position and data of every record of the
+// segmented log in dir, in order, without opening it for appending
+func scanSegments(dir string, options LogOptions, segments []SegmentInfo, fn func(pos recordPosition, data []byte) error) error {
+	for _, segment := range segments {
+		if err := scanFile(filepath.Join(dir, segment.File), options, segment.numbering(), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the active segment
+func (l *SegmentedLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active.Close()
+}
@@ -0,0 +1,216 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"auction-site-go/internal/domain"
+)
+
+// SnapshotVersion is the version of the snapshot format; snapshots of other versions are ignored
+const SnapshotVersion = 2
+
+// snapshotsToKeep is the number of most recent snapshots kept when a new one is written
+const snapshotsToKeep = 2
+
+// Snapshot is the repository as of a position in the event log, with the auctions
+// that had been closed by then
+type Snapshot struct {
+	Repo domain.Repository
+	// Position is just past the last event covered by the snapshot
+	Position LogPosition
+	// Closed holds the auctions whose closing events are covered by the snapshot
+	Closed map[domain.AuctionId]bool
+}
+
+// snapshotFile is the on-disk envelope of a repository snapshot
+type snapshotFile struct {
+	Version  int         `json:"version"`
+	Position LogPosition `json:"position"`
+	// Checksum is the CRC-32 (IEEE) of Closed followed by Auctions
+	Checksum uint32          `json:"checksum"`
+	Closed   json.RawMessage `json:"closed"`
+	Auctions json.RawMessage `json:"auctions"`
+}
+
+// WriteSnapshot atomically writes a snapshot
+func WriteSnapshot(dir string, snapshot Snapshot) error {
+	repo, err := domain.NewRepositorySnapshot(snapshot.Repo)
+	if err != nil {
+		return err
+	}
+
+	auctions, err := json.Marshal(repo)
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot: %v", err)
+	}
+
+	closedIds := make([]domain.AuctionId, 0, len(snapshot.Closed))
+	for id, isClosed := range snapshot.Closed {
+		if isClosed {
+			closedIds = append(closedIds, id)
+		}
+	}
+	sort.Slice(closedIds, func(i, j int) bool { return closedIds[i] < closedIds[j] })
+	closed, err := json.Marshal(closedIds)
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot: %v", err)
+	}
+
+	data, err := json.Marshal(snapshotFile{
+		Version:  SnapshotVersion,
+		Position: snapshot.Position,
+		Checksum: snapshotChecksum(closed, auctions),
+		Closed:   closed,
+		Auctions: auctions,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot: %v", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that readers never see a partial snapshot
+	tmp, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, snapshotName(snapshot.Position.Seq))); err != nil {
+		return err
+	}
+
+	return pruneSnapshots(dir)
+}
+
+// ReadLatestSnapshot returns the newest valid snapshot covering at most maxOffset events.
+// Corrupt or version-mismatched snapshots are skipped.
+// It returns nil if there is no usable snapshot.
+func ReadLatestSnapshot(dir string, maxOffset int64) (*Snapshot, error) {
+	names, err := listSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(names) - 1; i >= 0; i-- {
+		path := filepath.Join(dir, names[i])
+		snapshot, err := readSnapshot(path)
+		if err != nil {
+			log.Printf("Skipping snapshot %s: %v", path, err)
+			continue
+		}
+		if snapshot.Position.Seq > maxOffset {
+			log.Printf("Skipping snapshot %s: covers %d events but the log has %d", path, snapshot.Position.Seq, maxOffset)
+			continue
+		}
+		return snapshot, nil
+	}
+
+	return nil, nil
+}
+
+// readSnapshot reads and validates a single snapshot file
+func readSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file snapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot: %v", err)
+	}
+	if file.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", file.Version)
+	}
+	if snapshotChecksum(file.Closed, file.Auctions) != file.Checksum {
+		return nil, fmt.Errorf("snapshot checksum mismatch")
+	}
+
+	var repo domain.RepositorySnapshot
+	if err := json.Unmarshal(file.Auctions, &repo); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot: %v", err)
+	}
+	var closedIds []domain.AuctionId
+	if err := json.Unmarshal(file.Closed, &closedIds); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot: %v", err)
+	}
+
+	restored, err := repo.Restore()
+	if err != nil {
+		return nil, err
+	}
+	closed := make(map[domain.AuctionId]bool, len(closedIds))
+	for _, id := range closedIds {
+		closed[id] = true
+	}
+	return &Snapshot{Repo: restored, Position: file.Position, Closed: closed}, nil
+}
+
+// snapshotChecksum returns the checksum of the closed auctions and the auctions of a snapshot
+func snapshotChecksum(closed, auctions []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(closed), crc32.IEEETable, auctions)
+}
+
+// snapshotName returns the file name of a snapshot; names sort by offset
+func snapshotName(offset int64) string {
+	return fmt.Sprintf("snapshot-%020d.json", offset)
+}
+
+// listSnapshots returns the snapshot file names in dir, oldest first
+func listSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "snapshot-") && strings.HasSuffix(name, ".json") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pruneSnapshots removes all but the most recent snapshots
+func pruneSnapshots(dir string) error {
+	names, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(names) > snapshotsToKeep {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
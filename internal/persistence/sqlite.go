@@ -0,0 +1,233 @@
+package persistence
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// Pure-Go SQLite driver registered as "sqlite"
+	_ "modernc.org/sqlite"
+
+	"auction-site-go/internal/domain"
+)
+
+// sqliteSchema creates one table per log. seq is the position in the log, and
+// auction_id and type allow querying without decoding data.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS commands (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	auction_id INTEGER NOT NULL,
+	type       TEXT    NOT NULL,
+	at         TEXT    NOT NULL,
+	data       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS commands_auction_id ON commands (auction_id);
+
+CREATE TABLE IF NOT EXISTS events (
+	seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+	auction_id INTEGER NOT NULL,
+	type       TEXT    NOT NULL,
+	at         TEXT    NOT NULL,
+	data       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_auction_id ON events (auction_id);
+`
+
+// SQLiteStore stores commands and events in an embedded SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens or creates the SQLite database at path
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; sharing one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", "PRAGMA synchronous=FULL", sqliteSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error initializing database: %v", err)
+		}
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// ReadCommands reads all commands ordered by sequence number
+func (s *SQLiteStore) ReadCommands() ([]domain.Command, error) {
+	commands := []domain.Command{}
+	err := s.ScanCommands(func(_ int64, cmd domain.Command) error {
+		commands = append(commands, cmd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// ScanCommands streams all commands ordered by sequence number
+func (s *SQLiteStore) ScanCommands(fn func(seq int64, cmd domain.Command) error) error {
+	return s.readLog("commands", func(seq int64, data []byte) error {
+		cmd, err := domain.UnmarshalCommand(data)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling command %d: %v", seq, err)
+		}
+		return fn(seq, cmd)
+	})
+}
+
+// WriteCommands appends commands in a single transaction
+func (s *SQLiteStore) WriteCommands(commands []domain.Command) error {
+	entries := make([]logEntry, len(commands))
+	for i, cmd := range commands {
+		entries[i] = logEntry{value: cmd, auctionId: cmd.GetAuctionId(), at: cmd.GetTime()}
+	}
+	return s.appendLog("commands", entries)
+}
+
+// ReadEvents reads all events ordered by sequence number
+func (s *SQLiteStore) ReadEvents() ([]domain.Event, error) {
+	events := []domain.Event{}
+	err := s.ScanEvents(func(_ int64, event domain.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ScanEvents streams all events ordered by sequence number
+func (s *SQLiteStore) ScanEvents(fn func(seq int64, event domain.Event) error) error {
+	return s.readLog("events", func(seq int64, data []byte) error {
+		event, err := domain.UnmarshalEvent(data)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling event %d: %v", seq, err)
+		}
+		return fn(seq, event)
+	})
+}
+
+// EventsPosition returns the position of the last event, which is its sequence number
+func (s *SQLiteStore) EventsPosition() (LogPosition, error) {
+	var seq int64
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM events").Scan(&seq); err != nil {
+		return LogPosition{}, err
+	}
+	return LogPosition{Seq: seq}, nil
+}
+
+// ScanEventsAfter streams the events with sequence numbers after that of pos
+func (s *SQLiteStore) ScanEventsAfter(pos LogPosition, fn func(seq int64, event domain.Event) error) error {
+	last, err := s.EventsPosition()
+	if err != nil {
+		return err
+	}
+	if pos.Segment != "" || pos.Seq > last.Seq {
+		return ErrPositionNotInLog
+	}
+	return s.readLogAfter("events", pos.Seq, func(seq int64, data []byte) error {
+		event, err := domain.UnmarshalEvent(data)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling event %d: %v", seq, err)
+		}
+		return fn(seq, event)
+	})
+}
+
+// WriteEvents appends events in a single transaction
+func (s *SQLiteStore) WriteEvents(events []domain.Event) error {
+	entries := make([]logEntry, len(events))
+	for i, event := range events {
+		entries[i] = logEntry{value: event, auctionId: event.GetAuctionId(), at: event.GetTime()}
+	}
+	return s.appendLog("events", entries)
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// logEntry is a command or event with the columns stored next to its JSON
+type logEntry struct {
+	value     interface{}
+	auctionId domain.AuctionId
+	at        time.Time
+}
+
+// appendLog inserts entries into the commands or events table
+func (s *SQLiteStore) appendLog(table string, entries []logEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO " + table + " (auction_id, type, at, data) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.value)
+		if err != nil {
+			return fmt.Errorf("error marshaling %s: %v", table, err)
+		}
+
+		var typeCheck struct {
+			Type string `json:"$type"`
+		}
+		if err := json.Unmarshal(data, &typeCheck); err != nil {
+			return err
+		}
+
+		at := entry.at.UTC().Format(time.RFC3339Nano)
+		if _, err := stmt.Exec(int64(entry.auctionId), typeCheck.Type, at, string(data)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// readLog calls fn with the sequence number and JSON of every row of the commands or events table in order
+func (s *SQLiteStore) readLog(table string, fn func(seq int64, data []byte) error) error {
+	return s.readLogAfter(table, 0, fn)
+}
+
+// readLogAfter calls fn with every row of the commands or events table after the given sequence number, in order
+func (s *SQLiteStore) readLogAfter(table string, after int64, fn func(seq int64, data []byte) error) error {
+	rows, err := s.db.Query("SELECT seq, data FROM "+table+" WHERE seq > ? ORDER BY seq", after)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seq int64
+		var data string
+		if err := rows.Scan(&seq, &data); err != nil {
+			return err
+		}
+		if err := fn(seq, []byte(data)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
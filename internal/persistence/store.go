@@ -0,0 +1,235 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+
+	"auction-site-go/internal/domain"
+)
+
+// Store persists the command and event logs
+type Store interface {
+	// ReadCommands reads all commands in the order they were written
+	ReadCommands() ([]domain.Command, error)
+
+	// ScanCommands calls fn with the sequence number of every command and the command
+	// in the order they were written, stopping at the first error
+	ScanCommands(fn func(seq int64, cmd domain.Command) error) error
+
+	// WriteCommands appends commands to the command log
+	WriteCommands(commands []domain.Command) error
+
+	// ReadEvents reads all events in the order they were written
+	ReadEvents() ([]domain.Event, error)
+
+	// ScanEvents calls fn with the sequence number of every event and the event
+	// in the order they were written, stopping at the first error. Sequence numbers
+	// increase but may skip events removed by compaction.
+	ScanEvents(fn func(seq int64, event domain.Event) error) error
+
+	// WriteEvents appends events to the event log
+	WriteEvents(events []domain.Event) error
+
+	// EventsPosition returns the position just past the last event written
+	EventsPosition() (LogPosition, error)
+
+	// ScanEventsAfter calls fn like ScanEvents with the events after pos only, reading
+	// the log from pos rather than from its start. It returns ErrPositionNotInLog if
+	// the log does not reach pos.
+	ScanEventsAfter(pos LogPosition, fn func(seq int64, event domain.Event) error) error
+
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// LogPosition is the position in an event log just past the event with sequence number Seq.
+// In a JSONL log, Segment is the file of the segment holding that event, empty for a
+// single-file log, and Offset is the byte offset just past it in that file.
+type LogPosition struct {
+	Seq     int64  `json:"seq"`
+	Segment string `json:"segment,omitempty"`
+	Offset  int64  `json:"offset,omitempty"`
+}
+
+// ErrPositionNotInLog is returned when scanning from a position the event log does not reach,
+// such as the position of a snapshot taken of another log
+var ErrPositionNotInLog = errors.New("position is not in the event log")
+
+// recordLog is an append log of JSON records, either a single file or segmented
+type recordLog interface {
+	Append(records [][]byte) error
+	scan(fn func(pos recordPosition, data []byte) error) error
+	position() LogPosition
+	scanAfter(pos LogPosition, fn func(pos recordPosition, data []byte) error) error
+	Close() error
+}
+
+// JSONLStore stores commands and events in newline-delimited JSON append logs
+type JSONLStore struct {
+	CommandsPath string
+	// EventsPath is the event log file, or the directory of a segmented event log
+	EventsPath string
+
+	commands *AppendLog
+	events   recordLog
+}
+
+// OpenJSONLStore opens the command and event logs, recovering them from interrupted appends
+func OpenJSONLStore(commandsPath, eventsPath string, options LogOptions) (*JSONLStore, error) {
+	commands, err := OpenAppendLog(commandsPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := OpenAppendLog(eventsPath, options)
+	if err != nil {
+		commands.Close()
+		return nil, err
+	}
+
+	return &JSONLStore{
+		CommandsPath: commandsPath,
+		EventsPath:   eventsPath,
+		commands:     commands,
+		events:       events,
+	}, nil
+}
+
+// OpenSegmentedJSONLStore opens the command log and the segmented event log in eventsDir.
+// An event log file at legacyEventsPath is moved into eventsDir as its first segment
+// when eventsDir does not hold a segmented log yet.
+func OpenSegmentedJSONLStore(commandsPath, eventsDir, legacyEventsPath string, options LogOptions, segments SegmentOptions) (*JSONLStore, error) {
+	if legacyEventsPath != "" {
+		if err := AdoptLog(legacyEventsPath, eventsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	commands, err := OpenAppendLog(commandsPath, options)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := OpenSegmentedLog(eventsDir, options, segments)
+	if err != nil {
+		commands.Close()
+		return nil, err
+	}
+
+	return &JSONLStore{
+		CommandsPath: commandsPath,
+		EventsPath:   eventsDir,
+		commands:     commands,
+		events:       events,
+	}, nil
+}
+
+// ReadCommands reads all commands from the command log
+func (s *JSONLStore) ReadCommands() ([]domain.Command, error) {
+	commands := []domain.Command{}
+	err := s.ScanCommands(func(_ int64, cmd domain.Command) error {
+		commands = append(commands, cmd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// ScanCommands streams the command log
+func (s *JSONLStore) ScanCommands(fn func(seq int64, cmd domain.Command) error) error {
+	return s.commands.scan(decodeCommands(fn))
+}
+
+// WriteCommands appends commands to the command log
+func (s *JSONLStore) WriteCommands(commands []domain.Command) error {
+	records, err := marshalCommands(commands)
+	if err != nil {
+		return err
+	}
+	return s.commands.Append(records)
+}
+
+// ReadEvents reads all events from the event log
+func (s *JSONLStore) ReadEvents() ([]domain.Event, error) {
+	events := []domain.Event{}
+	err := s.ScanEvents(func(_ int64, event domain.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ScanEvents streams the event log across all of its segments
+func (s *JSONLStore) ScanEvents(fn func(seq int64, event domain.Event) error) error {
+	return s.events.scan(decodeEvents(fn))
+}
+
+// EventsPosition returns the position just past the last event in the event log
+func (s *JSONLStore) EventsPosition() (LogPosition, error) {
+	return s.events.position(), nil
+}
+
+// ScanEventsAfter streams the events after pos, seeking the event log to it
+func (s *JSONLStore) ScanEventsAfter(pos LogPosition, fn func(seq int64, event domain.Event) error) error {
+	return s.events.scanAfter(pos, decodeEvents(fn))
+}
+
+// WriteEvents appends events to the event log
+func (s *JSONLStore) WriteEvents(events []domain.Event) error {
+	records, err := marshalEvents(events)
+	if err != nil {
+		return err
+	}
+	return s.events.Append(records)
+}
+
+// Close closes the command and event logs
+func (s *JSONLStore) Close() error {
+	commandsErr := s.commands.Close()
+	if err := s.events.Close(); err != nil {
+		return err
+	}
+	return commandsErr
+}
+
+// Migrate copies all commands and events from one store into another, empty store.
+// It returns the number of commands and events copied.
+func Migrate(from, to Store) (int, int, error) {
+	existingCommands, err := to.ReadCommands()
+	if err != nil {
+		return 0, 0, err
+	}
+	existingEvents, err := to.ReadEvents()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(existingCommands) > 0 || len(existingEvents) > 0 {
+		return 0, 0, errors.New("destination store is not empty")
+	}
+
+	commands, err := from.ReadCommands()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading commands: %v", err)
+	}
+	events, err := from.ReadEvents()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading events: %v", err)
+	}
+
+	if len(commands) > 0 {
+		if err := to.WriteCommands(commands); err != nil {
+			return 0, 0, fmt.Errorf("error writing commands: %v", err)
+		}
+	}
+	if len(events) > 0 {
+		if err := to.WriteEvents(events); err != nil {
+			return 0, 0, fmt.Errorf("error writing events: %v", err)
+		}
+	}
+	return len(commands), len(events), nil
+}
@@ -15,22 +15,37 @@ import (
 type App struct {
 	Router         *mux.Router
 	State          *AppState
+	Authenticator  Authenticator
+	Events         *EventBroker
 	OnCommand      func(domain.Command) error
 	OnEvent        func(domain.Event) error
 	GetCurrentTime func() time.Time
+	// ScanCommands streams the command log for support queries; nil disables them
+	ScanCommands func(fn func(seq int64, cmd domain.Command) error) error
+	// Idempotency remembers responses to requests with an Idempotency-Key header
+	Idempotency *IdempotencyKeys
+
+	// publish observes an event and returns its position in the event log
+	publish func(domain.Event) (int64, error)
 }
 
 // NewApp creates a new web application
 func NewApp(repo domain.Repository, onCommand func(domain.Command) error, onEvent func(domain.Event) error, getCurrentTime func() time.Time) *App {
 	state := NewAppState(repo)
 	router := mux.NewRouter()
+	events := NewEventBroker(0, nil)
+	events.stateAfter = state.stateAfter
 
 	app := &App{
 		Router:         router,
 		State:          state,
+		Authenticator:  TrustedProxyAuthenticator,
+		Events:         events,
 		OnCommand:      onCommand,
-		OnEvent:        onEvent,
+		OnEvent:        events.Observe(onEvent),
 		GetCurrentTime: getCurrentTime,
+		Idempotency:    NewIdempotencyKeys(DefaultIdempotencyKeyTTL),
+		publish:        events.ObserveSeq(onEvent),
 	}
 
 	app.setupRoutes()
@@ -46,10 +61,30 @@ func (a *App) setupRoutes() {
 	})
 
 	// Routes
-	a.Router.HandleFunc("/auctions", getAuctions(a.State)).Methods("GET")
+	a.Router.HandleFunc("/auctions", getAuctions(a.State, a.GetCurrentTime)).Methods("GET")
 	a.Router.HandleFunc("/auctions/{id}", getAuction(a.State, a.GetCurrentTime)).Methods("GET")
-	a.Router.HandleFunc("/auctions", createAuction(a.State, a.OnCommand, a.OnEvent, a.GetCurrentTime)).Methods("POST")
-	a.Router.HandleFunc("/auctions/{id}/bids", placeBid(a.State, a.OnCommand, a.OnEvent, a.GetCurrentTime)).Methods("POST")
+	a.Router.HandleFunc("/auctions", idempotent(a.Idempotency, a.authenticate, a.OnCommand, a.GetCurrentTime, createAuction(a.State, a.authenticate, a.OnCommand, a.publish, a.GetCurrentTime))).Methods("POST")
+	a.Router.HandleFunc("/auctions/{id}/events", streamEvents(a.State, a.Events, a.GetCurrentTime)).Methods("GET")
+	a.Router.HandleFunc("/events", streamEvents(a.State, a.Events, a.GetCurrentTime)).Methods("GET")
+	a.Router.HandleFunc("/auctions/{id}/bids", idempotent(a.Idempotency, a.authenticate, a.OnCommand, a.GetCurrentTime, placeBid(a.State, a.authenticate, a.OnCommand, a.publish, a.GetCurrentTime))).Methods("POST")
+	a.Router.HandleFunc("/auctions/{id}/buy-now", idempotent(a.Idempotency, a.authenticate, a.OnCommand, a.GetCurrentTime, buyNow(a.State, a.authenticate, a.OnCommand, a.publish, a.GetCurrentTime))).Methods("POST")
+	a.Router.HandleFunc("/auctions/{id}/cancel", cancelAuction(a.State, a.authenticate, a.OnCommand, a.publish, a.GetCurrentTime)).Methods("POST")
+	a.Router.HandleFunc("/auctions/{id}/bids/retract", retractBid(a.State, a.authenticate, a.OnCommand, a.publish, a.GetCurrentTime)).Methods("POST")
+	a.Router.HandleFunc("/auctions/{id}/commands", getAuctionCommands(a.authenticate, a.scanCommands)).Methods("GET")
+	a.Router.HandleFunc("/users/{userId}/commands", getUserCommands(a.authenticate, a.scanCommands)).Methods("GET")
+}
+
+// authenticate extracts the user from a request using the configured authenticator
+func (a *App) authenticate(r *http.Request) (domain.User, error) {
+	return a.Authenticator(r)
+}
+
+// scanCommands streams the command log configured in ScanCommands
+func (a *App) scanCommands(fn func(int64, domain.Command) error) error {
+	if a.ScanCommands == nil {
+		return errCommandLogUnavailable
+	}
+	return a.ScanCommands(fn)
 }
 
 // Run starts the web server
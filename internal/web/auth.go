@@ -0,0 +1,43 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"auction-site-go/internal/domain"
+)
+
+// Authenticator extracts the authenticated user from an HTTP request
+type Authenticator func(r *http.Request) (domain.User, error)
+
+// TrustedProxyAuthenticator reads the JWT payload forwarded by a front proxy
+// that has already verified the token. Only use it behind such a proxy.
+func TrustedProxyAuthenticator(r *http.Request) (domain.User, error) {
+	authHeader := r.Header.Get("x-jwt-payload")
+	if authHeader == "" {
+		return domain.User{}, errors.New("missing authentication header")
+	}
+
+	// In the test, trim any whitespace
+	authHeader = strings.TrimSpace(authHeader)
+
+	return DecodeJwtUser(authHeader)
+}
+
+// NewBearerAuthenticator verifies full compact JWTs from the Authorization: Bearer header
+func NewBearerAuthenticator(verifier *JwtVerifier) Authenticator {
+	return func(r *http.Request) (domain.User, error) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			return domain.User{}, errors.New("missing authentication header")
+		}
+
+		parts := strings.Fields(authHeader)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return domain.User{}, errors.New("invalid authentication header format")
+		}
+
+		return verifier.Verify(parts[1])
+	}
+}
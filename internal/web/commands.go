@@ -0,0 +1,125 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"auction-site-go/internal/domain"
+)
+
+// errCommandLogUnavailable is returned when the server has no command log to query
+var errCommandLogUnavailable = errors.New("command log is not available")
+
+// getAuctionCommands lists the commands for an auction with their outcomes; support users only
+func getAuctionCommands(authenticate Authenticator, scanCommands func(fn func(int64, domain.Command) error) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid auction ID")
+			return
+		}
+		auctionId := domain.AuctionId(id)
+
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !user.IsSupport() {
+			respondDomainError(w, domain.NewNotAuthorizedError(user.ID, auctionId))
+			return
+		}
+
+		respondCommandRecords(w, scanCommands, func(cmd domain.Command) bool {
+			return cmd.GetAuctionId() == auctionId
+		})
+	}
+}
+
+// getUserCommands lists the commands issued by a user with their outcomes; support users only
+func getUserCommands(authenticate Authenticator, scanCommands func(fn func(int64, domain.Command) error) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := domain.UserId(mux.Vars(r)["userId"])
+
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if !user.IsSupport() {
+			respondDomainError(w, domain.NewSupportRequiredError(user.ID))
+			return
+		}
+
+		respondCommandRecords(w, scanCommands, func(cmd domain.Command) bool {
+			issuer, ok := domain.CommandIssuer(cmd)
+			return ok && issuer == userId
+		})
+	}
+}
+
+// respondCommandRecords responds with the commands matching keep, in log order
+func respondCommandRecords(w http.ResponseWriter, scanCommands func(fn func(int64, domain.Command) error) error, keep func(domain.Command) bool) {
+	records, err := commandRecords(scanCommands, keep)
+	if errors.Is(err, errCommandLogUnavailable) {
+		respondError(w, http.StatusNotImplemented, "Command log is not available")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read command log: %v", err)
+		respondError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	respondJSON(w, http.StatusOK, records)
+}
+
+// commandRecords pairs the commands matching keep with their outcomes.
+// Commands for an auction are handled one at a time, so an outcome belongs to
+// the last command for the same auction before it. Commands recorded before
+// outcomes were introduced have none.
+func commandRecords(scanCommands func(fn func(int64, domain.Command) error) error, keep func(domain.Command) bool) ([]CommandRecordResponse, error) {
+	records := []CommandRecordResponse{}
+	// pending holds the index of the last kept command for each auction that has no outcome yet
+	pending := make(map[domain.AuctionId]int)
+
+	err := scanCommands(func(seq int64, cmd domain.Command) error {
+		if _, ok := cmd.(domain.IdempotentResponse); ok {
+			return nil
+		}
+		outcome, ok := cmd.(domain.CommandOutcome)
+		if !ok {
+			delete(pending, cmd.GetAuctionId())
+			if keep(cmd) {
+				records = append(records, CommandRecordResponse{Seq: seq, Command: cmd})
+				pending[cmd.GetAuctionId()] = len(records) - 1
+			}
+			return nil
+		}
+
+		i, ok := pending[outcome.AuctionId]
+		if !ok || domain.CommandType(records[i].Command) != outcome.Command {
+			return nil
+		}
+		delete(pending, outcome.AuctionId)
+		records[i].Outcome = newOutcomeResponse(outcome)
+		return nil
+	})
+	return records, err
+}
+
+// newOutcomeResponse renders an outcome, with the error of a rejected command as its client received it
+func newOutcomeResponse(outcome domain.CommandOutcome) *OutcomeResponse {
+	if outcome.Accepted() {
+		return &OutcomeResponse{Accepted: true, EventSeq: outcome.EventSeq}
+	}
+
+	response := &OutcomeResponse{Error: map[string]interface{}{"type": outcome.Error.Type}}
+	if renderer, ok := domainErrorRenderers[outcome.Error.Type]; ok {
+		response.Error = renderer.payload(outcome.Error.Data)
+	}
+	return response
+}
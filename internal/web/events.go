@@ -0,0 +1,352 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"auction-site-go/internal/domain"
+)
+
+// subscriberBufferSize is the number of events buffered per stream subscriber;
+// subscribers that fall further behind are disconnected and may resume with Last-Event-ID
+const subscriberBufferSize = 256
+
+// sequencedEvent is an event with its 1-based position in the event log
+type sequencedEvent struct {
+	Seq   int64
+	Event domain.Event
+	// After is the state of the auction of a BidAcceptedEvent once the bid was accepted
+	After domain.State
+}
+
+// EventBroker numbers observed events by their position in the event log and
+// fans them out to stream subscribers
+type EventBroker struct {
+	mu          sync.Mutex
+	seq         int64
+	scanEvents  func(fn func(seq int64, event domain.Event) error) error
+	subscribers map[chan sequencedEvent]struct{}
+	// stateAfter returns the state an event leads to; it is called while the event is observed
+	stateAfter func(event domain.Event) domain.State
+}
+
+// NewEventBroker creates a new broker; seq is the number of events already in the log
+// and scanEvents streams the persisted log so that streams can resume from it
+func NewEventBroker(seq int64, scanEvents func(fn func(seq int64, event domain.Event) error) error) *EventBroker {
+	return &EventBroker{
+		seq:         seq,
+		scanEvents:  scanEvents,
+		subscribers: make(map[chan sequencedEvent]struct{}),
+	}
+}
+
+// SetHistory sets the number of events already in the log and how to stream them.
+// It must be called before any event is observed.
+func (b *EventBroker) SetHistory(seq int64, scanEvents func(fn func(seq int64, event domain.Event) error) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq = seq
+	b.scanEvents = scanEvents
+}
+
+// Sequence returns the number of events in the log
+func (b *EventBroker) Sequence() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Observe wraps an event observer so that every event it persists is published
+// to subscribers with its position in the log
+func (b *EventBroker) Observe(onEvent func(domain.Event) error) func(domain.Event) error {
+	observe := b.ObserveSeq(onEvent)
+	return func(event domain.Event) error {
+		_, err := observe(event)
+		return err
+	}
+}
+
+// ObserveSeq is like Observe, but the wrapped observer also returns the position
+// of the event in the log
+func (b *EventBroker) ObserveSeq(onEvent func(domain.Event) error) func(domain.Event) (int64, error) {
+	return func(event domain.Event) (int64, error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if err := onEvent(event); err != nil {
+			return 0, err
+		}
+
+		b.publish(event)
+		return b.seq, nil
+	}
+}
+
+// ObserveAll is like Observe for an observer that persists several events at once.
+// None of the events are published unless all of them were persisted.
+func (b *EventBroker) ObserveAll(onEvents func([]domain.Event) error) func([]domain.Event) error {
+	return func(events []domain.Event) error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if err := onEvents(events); err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			b.publish(event)
+		}
+		return nil
+	}
+}
+
+// publish numbers a persisted event and sends it to every subscriber.
+// The caller must hold b.mu.
+func (b *EventBroker) publish(event domain.Event) {
+	b.seq++
+	published := sequencedEvent{Seq: b.seq, Event: event}
+	if _, ok := event.(domain.BidAcceptedEvent); ok && b.stateAfter != nil {
+		published.After = b.stateAfter(event)
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- published:
+		default:
+			// Disconnect subscribers that cannot keep up
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers a new subscriber and returns its channel together with
+// the sequence number of the last event published before it subscribed
+func (b *EventBroker) subscribe() (chan sequencedEvent, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan sequencedEvent, subscriberBufferSize)
+	b.subscribers[ch] = struct{}{}
+	return ch, b.seq
+}
+
+// unsubscribe removes a subscriber
+func (b *EventBroker) unsubscribe(ch chan sequencedEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// history returns the persisted events with sequence numbers in (after, upTo].
+// Sequence numbers of events removed by compaction are skipped. The events are
+// folded into a repository on the way, so that accepted bids come with the state
+// they led to, as when they were published.
+func (b *EventBroker) history(after, upTo int64) ([]sequencedEvent, error) {
+	if after >= upTo || b.scanEvents == nil {
+		return nil, nil
+	}
+
+	var result []sequencedEvent
+	repo := make(domain.Repository)
+	err := b.scanEvents(func(seq int64, event domain.Event) error {
+		if seq > upTo {
+			return errHistoryComplete
+		}
+		domain.ApplyEvent(repo, event)
+		if seq > after {
+			missed := sequencedEvent{Seq: seq, Event: event}
+			if _, ok := event.(domain.BidAcceptedEvent); ok {
+				missed.After = repo[event.GetAuctionId()].State
+			}
+			result = append(result, missed)
+		}
+		return nil
+	})
+	if err != nil && err != errHistoryComplete {
+		return nil, err
+	}
+	return result, nil
+}
+
+// errHistoryComplete stops scanning the event log once the requested history has been read
+var errHistoryComplete = errors.New("history complete")
+
+// streamEvents streams AuctionAdded, BidAccepted, AuctionCancelled and BidRetracted events
+// as Server-Sent Events, for a single auction when the route has an {id} and for all
+// auctions otherwise
+func streamEvents(state *AppState, broker *EventBroker, getCurrentTime func() time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var forAuction *domain.AuctionId
+		if idStr, ok := mux.Vars(r)["id"]; ok {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid auction ID")
+				return
+			}
+			auctionId := domain.AuctionId(id)
+			if _, _, ok := state.GetAuction(auctionId); !ok {
+				respondDomainError(w, domain.NewAuctionNotFoundError(auctionId))
+				return
+			}
+			forAuction = &auctionId
+		}
+
+		var lastEventId int64
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id < 0 {
+				respondError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+				return
+			}
+			lastEventId = id
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondError(w, http.StatusInternalServerError, "Streaming unsupported")
+			return
+		}
+
+		// Subscribe before reading the log so that no event falls between the two
+		ch, subscribedAt := broker.subscribe()
+		defer broker.unsubscribe(ch)
+
+		missed, err := broker.history(lastEventId, subscribedAt)
+		if err != nil {
+			log.Printf("Failed to read event log: %v", err)
+			respondError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		send := func(e sequencedEvent) bool {
+			if e.Seq <= lastEventId {
+				return true
+			}
+			lastEventId = e.Seq
+
+			name, data, ok := streamPayload(state, e, forAuction, getCurrentTime())
+			if !ok {
+				return true
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, name, data); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		for _, e := range missed {
+			if !send(e) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case e, ok := <-ch:
+				if !ok || !send(e) {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// streamPayload returns the SSE event name and JSON data for an event, and false
+// when the event should not be streamed to this subscriber. Hidden proxy maximums
+// are never streamed, reserve prices are only streamed if the seller shows them,
+// and sealed bid amounts are only streamed once the auction is disclosing.
+// Accepted bids are streamed at the amounts GET /auctions/:id shows once they were
+// accepted: the bid at the amount it is shown at, which proxy bidding and the clock of
+// Dutch auctions make differ from the amount bid, and the leading bid as highestBid.
+func streamPayload(state *AppState, published sequencedEvent, forAuction *domain.AuctionId, now time.Time) (string, []byte, bool) {
+	event := published.Event
+	var name string
+	var auctionId domain.AuctionId
+	switch e := event.(type) {
+	case domain.AuctionAddedEvent:
+		name, auctionId = "AuctionAdded", e.Auction.ID
+		e.Auction.Type = e.Auction.PublicType()
+		event = e
+	case domain.BidAcceptedEvent:
+		name, auctionId = "BidAccepted", e.Bid.ForAuction
+	case domain.AuctionCancelledEvent:
+		name, auctionId = "AuctionCancelled", e.AuctionId
+	case domain.BidRetractedEvent:
+		name, auctionId = "BidRetracted", e.AuctionId
+	default:
+		return "", nil, false
+	}
+
+	if forAuction != nil && *forAuction != auctionId {
+		return "", nil, false
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal event: %v", err)
+		return "", nil, false
+	}
+
+	if _, ok := event.(domain.BidAcceptedEvent); !ok {
+		return name, data, true
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Printf("Failed to redact event: %v", err)
+		return "", nil, false
+	}
+	bid, _ := payload["bid"].(map[string]interface{})
+	delete(bid, "maxAmount")
+
+	sealed := true
+	if _, auctionState, ok := state.GetAuction(auctionId); ok {
+		sealed = domain.BidsSealed(auctionState.Increment(now))
+	}
+	if sealed {
+		delete(bid, "amount")
+	} else if published.After != nil {
+		accepted := published.Event.(domain.BidAcceptedEvent).Bid
+		for _, shown := range published.After.GetBids() {
+			if shown.Bidder.ID == accepted.Bidder.ID {
+				bid["amount"] = shown.Amount
+				break
+			}
+		}
+		if highest, ok := domain.GetHighestVisibleBid(published.After); ok {
+			payload["highestBid"] = AuctionBidResponse{
+				Amount:   highest.Amount,
+				Bidder:   highest.Bidder,
+				Quantity: highest.Quantity,
+			}
+		}
+	}
+
+	data, err = json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal event: %v", err)
+		return "", nil, false
+	}
+	return name, data, true
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,22 +13,19 @@ import (
 	"auction-site-go/internal/domain"
 )
 
-// getAuctions returns all auctions
-func getAuctions(state *AppState) http.HandlerFunc {
+// getAuctions returns a page of auctions matching the query parameters
+func getAuctions(state *AppState, getCurrentTime func() time.Time) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseAuctionQuery(r.URL.Query())
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid query parameter: "+err.Error())
+			return
+		}
+
 		repo := state.GetRepository()
-		auctions := domain.GetAuctions(repo)
-
-		// Convert to AuctionListItem
-		auctionItems := make([]AuctionListItem, len(auctions))
-		for i, auction := range auctions {
-			auctionItems[i] = AuctionListItem{
-				ID:       auction.ID,
-				StartsAt: auction.StartsAt,
-				Title:    auction.Title,
-				Expiry:   auction.Expiry,
-				Currency: auction.Currency,
-			}
+		auctionItems, next := query.apply(repo, getCurrentTime())
+		if next != nil {
+			w.Header().Set("Link", nextPageLink(r, *next))
 		}
 
 		respondJSON(w, http.StatusOK, auctionItems)
@@ -48,31 +44,30 @@ func getAuction(state *AppState, getCurrentTime func() time.Time) http.HandlerFu
 			return
 		}
 
-		// Get auction from repository
-		repo := state.GetRepository()
-		entry, ok := repo[domain.AuctionId(id)]
+		// Get auction from state
+		auction, currentState, ok := state.GetAuction(domain.AuctionId(id))
 		if !ok {
 			respondDomainError(w, domain.NewAuctionNotFoundError(domain.AuctionId(id)))
 			return
 		}
 
-		auction := entry.Auction
 		// Advance state to the current time so a winner surfaces once the auction has ended.
-		auctionState := entry.State.Increment(getCurrentTime())
+		auctionState := currentState.Increment(getCurrentTime())
 
 		// Get bids
 		bids := auctionState.GetBids()
 		bidResponses := make([]AuctionBidResponse, len(bids))
 		for i, bid := range bids {
 			bidResponses[i] = AuctionBidResponse{
-				Amount: bid.Amount,
-				Bidder: bid.Bidder,
+				Amount:   bid.Amount,
+				Bidder:   bid.Bidder,
+				Quantity: bid.Quantity,
 			}
 		}
 
 		// Get winner information
 		var winner *domain.UserId
-		var winnerPrice *int64
+		var winnerPrice *domain.Amount
 		if amount, userId, found := auctionState.TryGetAmountAndWinner(); found {
 			winner = &userId
 			winnerPrice = &amount
@@ -84,10 +79,31 @@ func getAuction(state *AppState, getCurrentTime func() time.Time) http.HandlerFu
 			StartsAt:    auction.StartsAt,
 			Title:       auction.Title,
 			Expiry:      auction.Expiry,
+			NextExpiry:  auction.Expiry,
 			Currency:    auction.Currency,
 			Bids:        bidResponses,
 			Winner:      winner,
 			WinnerPrice: winnerPrice,
+			ReserveMet:  domain.ReserveMet(auctionState),
+			Allocations: []AllocationResponse{},
+		}
+		for _, allocation := range domain.GetAllocations(auctionState) {
+			response.Allocations = append(response.Allocations, AllocationResponse{
+				Bidder:   allocation.Bidder,
+				Quantity: allocation.Quantity,
+				Price:    allocation.Price,
+			})
+		}
+		if nextExpiry, ok := domain.NextExpiry(auctionState); ok {
+			response.NextExpiry = nextExpiry
+		}
+		if reserve, ok := domain.ReservePrice(auctionState); ok && auction.ShowReserve {
+			response.ReservePrice = &reserve
+		}
+		if ongoing, ok := auctionState.(*domain.OngoingState); ok {
+			if price, available := ongoing.BuyNowPrice(); available {
+				response.BuyNowPrice = &price
+			}
 		}
 
 		respondJSON(w, http.StatusOK, response)
@@ -95,7 +111,7 @@ func getAuction(state *AppState, getCurrentTime func() time.Time) http.HandlerFu
 }
 
 // createAuction creates a new auction
-func createAuction(state *AppState, onCommand func(domain.Command) error, onEvent func(domain.Event) error, getCurrentTime func() time.Time) http.HandlerFunc {
+func createAuction(state *AppState, authenticate Authenticator, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error), getCurrentTime func() time.Time) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse request body
 		var req AddAuctionRequest
@@ -105,7 +121,7 @@ func createAuction(state *AppState, onCommand func(domain.Command) error, onEven
 		}
 
 		// Extract user from JWT
-		user, err := extractUserFromRequest(r)
+		user, err := authenticate(r)
 		if err != nil {
 			respondError(w, http.StatusUnauthorized, "Unauthorized")
 			return
@@ -113,7 +129,7 @@ func createAuction(state *AppState, onCommand func(domain.Command) error, onEven
 
 		// Create auction
 		var auctionType domain.AuctionType
-		if req.Type.Type != 0 {
+		if req.Type.Options != "" {
 			auctionType = req.Type
 		} else {
 			// Default to English auction
@@ -122,13 +138,14 @@ func createAuction(state *AppState, onCommand func(domain.Command) error, onEven
 		}
 
 		auction := domain.Auction{
-			ID:       req.ID,
-			StartsAt: req.StartsAt,
-			Title:    req.Title,
-			Expiry:   req.EndsAt,
-			Seller:   user,
-			Type:     auctionType,
-			Currency: req.Currency,
+			ID:          req.ID,
+			StartsAt:    req.StartsAt,
+			Title:       req.Title,
+			Expiry:      req.EndsAt,
+			Seller:      user,
+			Type:        auctionType,
+			Currency:    req.Currency,
+			ShowReserve: req.ShowReserve,
 		}
 
 		now := getCurrentTime()
@@ -145,27 +162,10 @@ func createAuction(state *AppState, onCommand func(domain.Command) error, onEven
 			Auction: auction,
 		}
 
-		if err := onCommand(cmd); err != nil {
-			log.Printf("Failed to observe command: %v", err)
-			respondError(w, http.StatusInternalServerError, "Internal server error")
-			return
-		}
-
-		// Handle command
-		repo := state.GetRepository()
-		event, newRepo, err := domain.Handle(cmd, repo)
+		// Handle command atomically with respect to other commands for the same auction
+		event, err := state.HandleCommand(cmd, onCommand, onEvent)
 		if err != nil {
-			respondDomainError(w, err)
-			return
-		}
-
-		// Update repository
-		state.UpdateRepository(newRepo)
-
-		// Call event handler
-		if err := onEvent(event); err != nil {
-			log.Printf("Failed to observe event: %v", err)
-			respondError(w, http.StatusInternalServerError, "Internal server error")
+			respondCommandError(w, err)
 			return
 		}
 
@@ -175,7 +175,7 @@ func createAuction(state *AppState, onCommand func(domain.Command) error, onEven
 }
 
 // placeBid places a bid on an auction
-func placeBid(state *AppState, onCommand func(domain.Command) error, onEvent func(domain.Event) error, getCurrentTime func() time.Time) http.HandlerFunc {
+func placeBid(state *AppState, authenticate Authenticator, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error), getCurrentTime func() time.Time) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse auction ID from path
 		vars := mux.Vars(r)
@@ -194,7 +194,7 @@ func placeBid(state *AppState, onCommand func(domain.Command) error, onEvent fun
 		}
 
 		// Extract user from JWT
-		user, err := extractUserFromRequest(r)
+		user, err := authenticate(r)
 		if err != nil {
 			respondError(w, http.StatusUnauthorized, "Unauthorized")
 			return
@@ -206,6 +206,8 @@ func placeBid(state *AppState, onCommand func(domain.Command) error, onEvent fun
 			Bidder:     user,
 			At:         getCurrentTime(),
 			Amount:     req.Amount,
+			MaxAmount:  req.MaxAmount,
+			Quantity:   req.Quantity,
 		}
 
 		// Create command
@@ -214,46 +216,145 @@ func placeBid(state *AppState, onCommand func(domain.Command) error, onEvent fun
 			Bid:  bid,
 		}
 
-		if err := onCommand(cmd); err != nil {
-			log.Printf("Failed to observe command: %v", err)
-			respondError(w, http.StatusInternalServerError, "Internal server error")
+		// Handle command atomically with respect to other commands for the same auction
+		event, err := state.HandleCommand(cmd, onCommand, onEvent)
+		if err != nil {
+			respondCommandError(w, err)
 			return
 		}
 
-		// Handle command
-		repo := state.GetRepository()
-		event, newRepo, err := domain.Handle(cmd, repo)
+		// Return the event
+		respondJSON(w, http.StatusOK, event)
+	}
+}
+
+// buyNow buys the item of an auction at its buy-now price, ending the auction
+func buyNow(state *AppState, authenticate Authenticator, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error), getCurrentTime func() time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse auction ID from path
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid auction ID")
+			return
+		}
+
+		// Extract user from JWT
+		user, err := authenticate(r)
 		if err != nil {
-			respondDomainError(w, err)
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
-		// Update repository
-		state.UpdateRepository(newRepo)
+		cmd := domain.BuyNowCommand{
+			Time:      getCurrentTime(),
+			AuctionId: domain.AuctionId(id),
+			Buyer:     user,
+		}
 
-		// Call event handler
-		if err := onEvent(event); err != nil {
-			log.Printf("Failed to observe event: %v", err)
-			respondError(w, http.StatusInternalServerError, "Internal server error")
+		// Handle command atomically with respect to other commands for the same auction
+		event, err := state.HandleCommand(cmd, onCommand, onEvent)
+		if err != nil {
+			respondCommandError(w, err)
 			return
 		}
 
-		// Return the event
 		respondJSON(w, http.StatusOK, event)
 	}
 }
 
-// extractUserFromRequest extracts a user from an HTTP request
-func extractUserFromRequest(r *http.Request) (domain.User, error) {
-	authHeader := r.Header.Get("x-jwt-payload")
-	if authHeader == "" {
-		return domain.User{}, errors.New("missing authentication header")
+// cancelAuction cancels an auction on behalf of a support user or, before the first bid, the seller
+func cancelAuction(state *AppState, authenticate Authenticator, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error), getCurrentTime func() time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse auction ID from path
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid auction ID")
+			return
+		}
+
+		// Parse request body
+		var req CancelAuctionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		// Extract user from JWT
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		// Create command
+		cmd := domain.CancelAuctionCommand{
+			Time:        getCurrentTime(),
+			AuctionId:   domain.AuctionId(id),
+			CancelledBy: user,
+			Reason:      req.Reason,
+		}
+
+		// Handle command atomically with respect to other commands for the same auction
+		event, err := state.HandleCommand(cmd, onCommand, onEvent)
+		if err != nil {
+			respondCommandError(w, err)
+			return
+		}
+
+		// Return the event
+		respondJSON(w, http.StatusOK, event)
 	}
+}
+
+// retractBid retracts the bids of a bidder on behalf of a support user
+func retractBid(state *AppState, authenticate Authenticator, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error), getCurrentTime func() time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse auction ID from path
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid auction ID")
+			return
+		}
+
+		// Parse request body
+		var req RetractBidRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		// Extract user from JWT
+		user, err := authenticate(r)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
 
-	// In the test, trim any whitespace
-	authHeader = strings.TrimSpace(authHeader)
+		// Create command
+		cmd := domain.RetractBidCommand{
+			Time:        getCurrentTime(),
+			AuctionId:   domain.AuctionId(id),
+			Bidder:      req.Bidder,
+			RetractedBy: user,
+			Reason:      req.Reason,
+		}
+
+		// Handle command atomically with respect to other commands for the same auction
+		event, err := state.HandleCommand(cmd, onCommand, onEvent)
+		if err != nil {
+			respondCommandError(w, err)
+			return
+		}
 
-	return DecodeJwtUser(authHeader)
+		// Return the event
+		respondJSON(w, http.StatusOK, event)
+	}
 }
 
 // respondJSON responds with a JSON payload
@@ -293,6 +394,22 @@ func withAuctionId(typeName string, status int) domainErrorRenderer {
 	}
 }
 
+// withFields returns a renderer that copies the fields of map Data into the payload.
+func withFields(typeName string, status int) domainErrorRenderer {
+	return domainErrorRenderer{
+		status: status,
+		payload: func(data interface{}) map[string]interface{} {
+			resp := map[string]interface{}{"type": typeName}
+			if d, ok := data.(map[string]interface{}); ok {
+				for k, v := range d {
+					resp[k] = v
+				}
+			}
+			return resp
+		},
+	}
+}
+
 var domainErrorRenderers = map[domain.ErrorType]domainErrorRenderer{
 	domain.ErrorAuctionNotFound:      withAuctionId("AuctionNotFound", http.StatusNotFound),
 	domain.ErrorAuctionAlreadyExists: withAuctionId("AuctionAlreadyExists", http.StatusBadRequest),
@@ -304,24 +421,60 @@ var domainErrorRenderers = map[domain.ErrorType]domainErrorRenderer{
 			return map[string]interface{}{"type": "AlreadyPlacedBid"}
 		},
 	},
-	domain.ErrorSellerCannotPlaceBids: {
+	domain.ErrorSellerCannotPlaceBids: withFields("SellerCannotPlaceBids", http.StatusBadRequest),
+	domain.ErrorMustPlaceBidOverHighest: {
 		status: http.StatusBadRequest,
 		payload: func(data interface{}) map[string]interface{} {
-			resp := map[string]interface{}{"type": "SellerCannotPlaceBids"}
-			if d, ok := data.(map[string]interface{}); ok {
-				for k, v := range d {
-					resp[k] = v
-				}
-			}
-			return resp
+			return map[string]interface{}{"type": "MustPlaceBidOverHighestBid", "amount": data}
 		},
 	},
-	domain.ErrorMustPlaceBidOverHighest: {
+	domain.ErrorAuctionCancelled: withAuctionId("AuctionCancelled", http.StatusBadRequest),
+	domain.ErrorBidNotFound: {
+		status: http.StatusNotFound,
+		payload: func(data interface{}) map[string]interface{} {
+			return map[string]interface{}{"type": "BidNotFound", "userId": data}
+		},
+	},
+	domain.ErrorNotAuthorized:    withFields("NotAuthorized", http.StatusForbidden),
+	domain.ErrorCurrencyMismatch: withFields("CurrencyMismatch", http.StatusBadRequest),
+	domain.ErrorUnknownCurrency: {
 		status: http.StatusBadRequest,
 		payload: func(data interface{}) map[string]interface{} {
-			return map[string]interface{}{"type": "MustPlaceBidOverHighestBid", "amount": data}
+			return map[string]interface{}{"type": "UnknownCurrency", "currency": data}
 		},
 	},
+	domain.ErrorAmountOverflow: withFields("AmountOverflow", http.StatusBadRequest),
+	domain.ErrorBidAmountNotPositive: {
+		status: http.StatusBadRequest,
+		payload: func(data interface{}) map[string]interface{} {
+			return map[string]interface{}{"type": "BidAmountMustBePositive", "amount": data}
+		},
+	},
+	domain.ErrorNegativeAmount:         withFields("AmountMustNotBeNegative", http.StatusBadRequest),
+	domain.ErrorAmountNotPositive:      withFields("AmountMustBePositive", http.StatusBadRequest),
+	domain.ErrorFloorAboveStartPrice:   withFields("FloorPriceMustNotExceedStartPrice", http.StatusBadRequest),
+	domain.ErrorBuyNowBelowReserve:     withFields("BuyNowPriceMustExceedReserve", http.StatusBadRequest),
+	domain.ErrorIncrementsNotAscending: withFields("IncrementTableMustAscend", http.StatusBadRequest),
+	domain.ErrorInvalidQuantity:        withFields("InvalidQuantity", http.StatusBadRequest),
+	domain.ErrorBuyNowNotAvailable:     withAuctionId("BuyNowNotAvailable", http.StatusBadRequest),
+	domain.ErrorReasonRequired: {
+		status: http.StatusBadRequest,
+		payload: func(_ interface{}) map[string]interface{} {
+			return map[string]interface{}{"type": "ReasonRequired"}
+		},
+	},
+}
+
+// respondCommandError responds with a 500 when a command or event could not be
+// observed, and renders the error as a domain error otherwise.
+func respondCommandError(w http.ResponseWriter, err error) {
+	var observerErr observerError
+	if errors.As(err, &observerErr) {
+		log.Printf("Failed to observe command or event: %v", observerErr.err)
+		respondError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	respondDomainError(w, err)
 }
 
 // respondDomainError translates a domain error into a typed HTTP error
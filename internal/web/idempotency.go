@@ -0,0 +1,308 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"auction-site-go/internal/domain"
+)
+
+// IdempotencyKeyHeader is the header clients use to make a request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyKeyTTL is how long responses are kept for retries by default
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+// errIdempotencyKeyReused is returned when a key is used again for a different request
+var errIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+
+// idempotencyKey is a key as used by a user; keys of different users do not collide
+type idempotencyKey struct {
+	userId domain.UserId
+	key    string
+}
+
+// idempotencyEntry is the first request made with a key
+type idempotencyEntry struct {
+	key  idempotencyKey
+	hash string
+	at   time.Time
+	// done is closed once the request has been answered
+	done chan struct{}
+	// response is the recorded response, or nil while the request is being handled
+	response *domain.IdempotentResponse
+}
+
+// IdempotencyKeys remembers the responses to requests made with an idempotency key
+// until they expire, so that retries get the same response
+type IdempotencyKeys struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[idempotencyKey]*idempotencyEntry
+	// order holds the entries in the order they were created, oldest first
+	order []*idempotencyEntry
+}
+
+// NewIdempotencyKeys creates an empty set of keys whose responses expire after ttl.
+// A ttl of 0 disables idempotency keys.
+func NewIdempotencyKeys(ttl time.Duration) *IdempotencyKeys {
+	return &IdempotencyKeys{
+		ttl:     ttl,
+		entries: make(map[idempotencyKey]*idempotencyEntry),
+	}
+}
+
+// SetTTL changes how long responses are kept for retries
+func (k *IdempotencyKeys) SetTTL(ttl time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.ttl = ttl
+}
+
+// Load restores the responses recorded in the command log that have not expired by now
+func (k *IdempotencyKeys) Load(scanCommands func(fn func(seq int64, cmd domain.Command) error) error, now time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return scanCommands(func(_ int64, cmd domain.Command) error {
+		response, ok := cmd.(domain.IdempotentResponse)
+		if !ok || !now.Before(response.Time.Add(k.ttl)) {
+			return nil
+		}
+
+		entry := &idempotencyEntry{
+			key:      idempotencyKey{userId: response.UserId, key: response.Key},
+			hash:     response.RequestHash,
+			at:       response.Time,
+			done:     make(chan struct{}),
+			response: &response,
+		}
+		close(entry.done)
+		k.entries[entry.key] = entry
+		k.order = append(k.order, entry)
+		return nil
+	})
+}
+
+// enabled returns true if requests with a key are remembered
+func (k *IdempotencyKeys) enabled() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.ttl > 0
+}
+
+// begin starts a request with a key. It returns the recorded response if the request
+// was already answered, or a new entry that the caller must finish otherwise. A request
+// made while the first request with the same key is still being handled waits for it.
+func (k *IdempotencyKeys) begin(ctx context.Context, key idempotencyKey, hash string, now time.Time) (*idempotencyEntry, *domain.IdempotentResponse, error) {
+	for {
+		k.mu.Lock()
+		k.expire(now)
+
+		entry, ok := k.entries[key]
+		if !ok {
+			entry = &idempotencyEntry{key: key, hash: hash, at: now, done: make(chan struct{})}
+			k.entries[key] = entry
+			k.order = append(k.order, entry)
+			k.mu.Unlock()
+			return entry, nil, nil
+		}
+		k.mu.Unlock()
+
+		if entry.hash != hash {
+			return nil, nil, errIdempotencyKeyReused
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		k.mu.Lock()
+		response := entry.response
+		k.mu.Unlock()
+		if response != nil {
+			return nil, response, nil
+		}
+		// The first request was not answered in a way worth repeating; try again
+	}
+}
+
+// finish records the response to the request of entry, or forgets the key if response
+// is nil so that the request can be retried
+func (k *IdempotencyKeys) finish(entry *idempotencyEntry, response *domain.IdempotentResponse) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if response != nil {
+		entry.response = response
+	} else if k.entries[entry.key] == entry {
+		delete(k.entries, entry.key)
+	}
+	close(entry.done)
+}
+
+// expire forgets the answered requests older than the ttl. k.mu must be held.
+func (k *IdempotencyKeys) expire(now time.Time) {
+	for len(k.order) > 0 {
+		entry := k.order[0]
+		if entry.response == nil && k.entries[entry.key] == entry {
+			// Still being handled
+			return
+		}
+		if now.Before(entry.at.Add(k.ttl)) {
+			return
+		}
+		if k.entries[entry.key] == entry {
+			delete(k.entries, entry.key)
+		}
+		k.order = k.order[1:]
+	}
+}
+
+// idempotent makes a write endpoint honour the Idempotency-Key header. The first request
+// with a key is handled by next and its response is recorded with onCommand; a retry with
+// the same key and body gets the recorded response without being handled again, and a
+// request that reuses the key with a different body is rejected. Server errors are not
+// recorded, so that requests which failed for reasons of their own can be retried.
+func idempotent(keys *IdempotencyKeys, authenticate Authenticator, onCommand func(domain.Command) error, getCurrentTime func() time.Time, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !keys.enabled() {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		// Let the endpoint reject unauthenticated requests
+		user, err := authenticate(r)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := requestHash(r, body)
+		entry, recorded, err := keys.begin(r.Context(), idempotencyKey{userId: user.ID, key: key}, hash, getCurrentTime())
+		if errors.Is(err, errIdempotencyKeyReused) {
+			respondError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			return
+		}
+		if err != nil {
+			// The client went away while waiting for the first request with the key
+			return
+		}
+		if recorded != nil {
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeRecorded(w, recorded.Status, recorded.Body)
+			return
+		}
+
+		recorder := &responseRecorder{status: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.status >= http.StatusInternalServerError {
+			keys.finish(entry, nil)
+			writeRecorded(w, recorder.status, recorder.body.Bytes())
+			return
+		}
+
+		response := domain.IdempotentResponse{
+			Time:        entry.at,
+			AuctionId:   requestAuctionId(r, body),
+			UserId:      user.ID,
+			Key:         key,
+			RequestHash: hash,
+			Status:      recorder.status,
+			Body:        json.RawMessage(recorder.body.Bytes()),
+		}
+		if err := onCommand(response); err != nil {
+			log.Printf("Failed to record response for idempotency key %q of user %s: %v", key, user.ID, err)
+		}
+		keys.finish(entry, &response)
+		writeRecorded(w, recorder.status, recorder.body.Bytes())
+	}
+}
+
+// requestHash identifies a request by its method, path and body. JSON bodies are
+// compared without insignificant whitespace.
+func requestHash(r *http.Request, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		body = compact.Bytes()
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// requestAuctionId returns the ID of the auction a request is for, from its path or its body
+func requestAuctionId(r *http.Request, body []byte) domain.AuctionId {
+	if id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err == nil {
+		return domain.AuctionId(id)
+	}
+
+	var req struct {
+		ID domain.AuctionId `json:"id"`
+	}
+	json.Unmarshal(body, &req)
+	return req.ID
+}
+
+// writeRecorded writes a JSON response recorded earlier
+func writeRecorded(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// responseRecorder captures the JSON response of a handler
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
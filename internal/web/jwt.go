@@ -1,10 +1,23 @@
 package web
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"math/big"
+	"os"
 	"strings"
+	"time"
 
 	"auction-site-go/internal/domain"
 )
@@ -45,7 +58,11 @@ func DecodeJwtUser(jwtPayload string) (domain.User, error) {
 		return domain.User{}, err
 	}
 
-	// Convert to domain User
+	return jwtUserToDomain(jwtUser)
+}
+
+// jwtUserToDomain converts JWT user claims to a domain User
+func jwtUserToDomain(jwtUser JwtUser) (domain.User, error) {
 	switch jwtUser.UType {
 	case "0":
 		return domain.NewBuyerOrSeller(domain.UserId(jwtUser.Subject), jwtUser.Name), nil
@@ -71,3 +88,329 @@ func ExtractJwtUser(authHeader string) (domain.User, error) {
 	// Decode JWT payload
 	return DecodeJwtUser(parts[1])
 }
+
+// JwtKey is a key that can verify JWT signatures
+type JwtKey struct {
+	// ID matches the "kid" header of tokens signed with this key; empty matches any token
+	ID string
+	// Algorithm is one of HS256, RS256 or ES256
+	Algorithm string
+	// Key is a []byte secret for HS256, *rsa.PublicKey for RS256 or *ecdsa.PublicKey for ES256
+	Key interface{}
+}
+
+// NewHS256Key creates a key for verifying HS256 tokens with a shared secret
+func NewHS256Key(secret []byte) JwtKey {
+	return JwtKey{Algorithm: "HS256", Key: secret}
+}
+
+// JwtVerifier verifies compact JWTs and maps their claims to domain users
+type JwtVerifier struct {
+	Keys []JwtKey
+	// Issuer is the required "iss" claim; empty skips the check
+	Issuer string
+	// Audience is the required "aud" claim; empty skips the check
+	Audience string
+	// Leeway is the allowed clock skew when checking "exp" and "nbf"
+	Leeway         time.Duration
+	GetCurrentTime func() time.Time
+}
+
+// jwtHeader represents the JOSE header of a compact JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// jwtClaims represents the registered and user claims of a JWT
+type jwtClaims struct {
+	JwtUser
+	Issuer    string      `json:"iss,omitempty"`
+	Audience  jwtAudience `json:"aud,omitempty"`
+	ExpiresAt *int64      `json:"exp,omitempty"`
+	NotBefore *int64      `json:"nbf,omitempty"`
+}
+
+// jwtAudience accepts both the single string and the array form of the "aud" claim
+type jwtAudience []string
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (a *jwtAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = jwtAudience{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("invalid audience claim")
+	}
+	*a = many
+	return nil
+}
+
+// Verify verifies a compact JWT and returns the user it identifies
+func (v *JwtVerifier) Verify(token string) (domain.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return domain.User{}, errors.New("malformed token")
+	}
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return domain.User{}, errors.New("malformed token header")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return domain.User{}, errors.New("malformed token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return domain.User{}, errors.New("malformed token signature")
+	}
+
+	if !v.verifySignature(header, []byte(parts[0]+"."+parts[1]), signature) {
+		return domain.User{}, errors.New("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return domain.User{}, errors.New("malformed token payload")
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return domain.User{}, errors.New("malformed token payload")
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return domain.User{}, err
+	}
+
+	return jwtUserToDomain(claims.JwtUser)
+}
+
+// verifySignature checks the signature against every key matching the token's algorithm and key ID
+func (v *JwtVerifier) verifySignature(header jwtHeader, signingInput, signature []byte) bool {
+	digest := sha256.Sum256(signingInput)
+
+	for _, key := range v.Keys {
+		if key.Algorithm != header.Algorithm {
+			continue
+		}
+		if key.ID != "" && header.KeyID != "" && key.ID != header.KeyID {
+			continue
+		}
+
+		switch k := key.Key.(type) {
+		case []byte:
+			if header.Algorithm != "HS256" {
+				continue
+			}
+			mac := hmac.New(sha256.New, k)
+			mac.Write(signingInput)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if header.Algorithm != "RS256" {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if header.Algorithm != "ES256" || k.Curve != elliptic.P256() || len(signature) != 64 {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(k, digest[:], r, s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// validateClaims checks the time, issuer and audience claims; "exp" is required
+func (v *JwtVerifier) validateClaims(claims jwtClaims) error {
+	now := time.Now()
+	if v.GetCurrentTime != nil {
+		now = v.GetCurrentTime()
+	}
+
+	// A token without an expiry would be valid forever
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(v.Leeway)) {
+		return errors.New("token has expired")
+	}
+	if claims.NotBefore != nil && now.Add(v.Leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return errors.New("invalid token issuer")
+	}
+	if v.Audience != "" {
+		found := false
+		for _, aud := range claims.Audience {
+			if aud == v.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("invalid token audience")
+		}
+	}
+
+	return nil
+}
+
+// LoadJwtKeys loads public keys from a PEM file or a JWKS (JSON Web Key Set) file
+func LoadJwtKeys(path string) ([]JwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseJwks(trimmed)
+	}
+	return parsePemKeys(data)
+}
+
+// parsePemKeys parses every public key or certificate block in PEM data
+func parsePemKeys(data []byte) ([]JwtKey, error) {
+	var keys []JwtKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var pub interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				pub = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s block: %v", block.Type, err)
+		}
+
+		key, err := publicJwtKey("", pub)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found in PEM data")
+	}
+	return keys, nil
+}
+
+// jwk represents a single JSON Web Key
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// parseJwks parses the RSA and P-256 signing keys of a JSON Web Key Set
+func parseJwks(data []byte) ([]JwtKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %v", err)
+	}
+
+	var keys []JwtKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var pub interface{}
+		switch k.KeyType {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA modulus for key %s", k.KeyID)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("invalid RSA exponent for key %s", k.KeyID)
+			}
+			pub = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			if k.Curve != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid EC coordinates for key %s", k.KeyID)
+			}
+			pub = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		default:
+			continue
+		}
+
+		key, err := publicJwtKey(k.KeyID, pub)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no supported keys found in JWKS")
+	}
+	return keys, nil
+}
+
+// publicJwtKey wraps a parsed public key with the algorithm it verifies
+func publicJwtKey(id string, pub interface{}) (JwtKey, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return JwtKey{ID: id, Algorithm: "RS256", Key: k}, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return JwtKey{}, errors.New("only P-256 EC keys are supported")
+		}
+		return JwtKey{ID: id, Algorithm: "ES256", Key: k}, nil
+	default:
+		return JwtKey{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
@@ -0,0 +1,255 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+const (
+	// defaultPageSize is the number of auctions returned when no limit is given
+	defaultPageSize = 100
+	// maxPageSize is the largest accepted limit
+	maxPageSize = 1000
+)
+
+// auctionQuery holds the filters, sort order and page of a GET /auctions request
+type auctionQuery struct {
+	Status       domain.AuctionStatus
+	Currency     domain.Currency
+	Type         string
+	Seller       domain.UserId
+	StartsAfter  *time.Time
+	StartsBefore *time.Time
+	EndsAfter    *time.Time
+	EndsBefore   *time.Time
+	Title        string
+	Sort         string
+	Descending   bool
+	Limit        int
+	Cursor       *auctionCursor
+}
+
+// auctionCursor points just past the last auction of a page
+type auctionCursor struct {
+	Sort string           `json:"s"`
+	Key  int64            `json:"k"`
+	ID   domain.AuctionId `json:"i"`
+}
+
+// encode returns the opaque representation of the cursor
+func (c auctionCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeAuctionCursor parses an opaque cursor
+func decodeAuctionCursor(s string) (*auctionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor auctionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// parseAuctionQuery parses the query parameters of a GET /auctions request
+func parseAuctionQuery(values url.Values) (auctionQuery, error) {
+	query := auctionQuery{
+		Currency: domain.Currency(values.Get("currency")),
+		Type:     values.Get("type"),
+		Seller:   domain.UserId(values.Get("seller")),
+		Title:    strings.ToLower(values.Get("title")),
+		Sort:     "id",
+		Limit:    defaultPageSize,
+	}
+
+	switch status := domain.AuctionStatus(values.Get("status")); status {
+	case "", domain.AuctionAwaiting, domain.AuctionOngoing, domain.AuctionEnded, domain.AuctionCancelled:
+		query.Status = status
+	default:
+		return query, errors.New("status")
+	}
+
+	for name, target := range map[string]**time.Time{
+		"startsAfter":  &query.StartsAfter,
+		"startsBefore": &query.StartsBefore,
+		"endsAfter":    &query.EndsAfter,
+		"endsBefore":   &query.EndsBefore,
+	} {
+		if v := values.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return query, errors.New(name)
+			}
+			*target = &t
+		}
+	}
+
+	if v := values.Get("sort"); v != "" {
+		if strings.HasPrefix(v, "-") {
+			query.Descending = true
+			v = v[1:]
+		}
+		switch v {
+		case "id", "expiry", "start":
+			query.Sort = v
+		default:
+			return query, errors.New("sort")
+		}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return query, errors.New("limit")
+		}
+		query.Limit = limit
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		cursor, err := decodeAuctionCursor(v)
+		if err != nil || cursor.Sort != query.sortName() {
+			return query, errors.New("cursor")
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+// sortName returns the sort order including its direction
+func (q auctionQuery) sortName() string {
+	if q.Descending {
+		return "-" + q.Sort
+	}
+	return q.Sort
+}
+
+// sortKey returns the primary sort key of an auction
+func (q auctionQuery) sortKey(auction domain.Auction) int64 {
+	switch q.Sort {
+	case "expiry":
+		return auction.Expiry.UnixNano()
+	case "start":
+		return auction.StartsAt.UnixNano()
+	default:
+		return int64(auction.ID)
+	}
+}
+
+// less orders auctions by the sort key and then by ID so that the order is stable
+func (q auctionQuery) less(keyA int64, idA domain.AuctionId, keyB int64, idB domain.AuctionId) bool {
+	if keyA != keyB {
+		return (keyA < keyB) != q.Descending
+	}
+	return (idA < idB) != q.Descending
+}
+
+// matches returns true if the auction passes all filters
+func (q auctionQuery) matches(auction domain.Auction, status domain.AuctionStatus) bool {
+	if q.Status != "" && status != q.Status {
+		return false
+	}
+	if q.Currency != "" && auction.Currency != q.Currency {
+		return false
+	}
+	if q.Type != "" && auction.Type.Name() != q.Type {
+		return false
+	}
+	if q.Seller != "" && auction.Seller.ID != q.Seller {
+		return false
+	}
+	if q.StartsAfter != nil && auction.StartsAt.Before(*q.StartsAfter) {
+		return false
+	}
+	if q.StartsBefore != nil && auction.StartsAt.After(*q.StartsBefore) {
+		return false
+	}
+	if q.EndsAfter != nil && auction.Expiry.Before(*q.EndsAfter) {
+		return false
+	}
+	if q.EndsBefore != nil && auction.Expiry.After(*q.EndsBefore) {
+		return false
+	}
+	if q.Title != "" && !strings.Contains(strings.ToLower(auction.Title), q.Title) {
+		return false
+	}
+	return true
+}
+
+// apply filters, sorts and pages the repository, returning the page and the
+// cursor of the next page if there is one
+func (q auctionQuery) apply(repo domain.Repository, now time.Time) ([]AuctionListItem, *auctionCursor) {
+	type keyedItem struct {
+		key  int64
+		item AuctionListItem
+	}
+
+	matching := make([]keyedItem, 0)
+	for _, entry := range repo {
+		auction := entry.Auction
+		status := domain.GetStatus(auction, entry.State, now)
+		if !q.matches(auction, status) {
+			continue
+		}
+		key := q.sortKey(auction)
+		if q.Cursor != nil && !q.less(q.Cursor.Key, q.Cursor.ID, key, auction.ID) {
+			continue
+		}
+		matching = append(matching, keyedItem{key: key, item: newAuctionListItem(auction, entry.State.Increment(now), status)})
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		return q.less(matching[i].key, matching[i].item.ID, matching[j].key, matching[j].item.ID)
+	})
+
+	var next *auctionCursor
+	if len(matching) > q.Limit {
+		matching = matching[:q.Limit]
+		last := matching[len(matching)-1]
+		next = &auctionCursor{Sort: q.sortName(), Key: last.key, ID: last.item.ID}
+	}
+
+	items := make([]AuctionListItem, len(matching))
+	for i, m := range matching {
+		items[i] = m.item
+	}
+	return items, next
+}
+
+// newAuctionListItem creates a list item with the status and visible highest bid of an auction
+func newAuctionListItem(auction domain.Auction, state domain.State, status domain.AuctionStatus) AuctionListItem {
+	item := AuctionListItem{
+		ID:       auction.ID,
+		StartsAt: auction.StartsAt,
+		Title:    auction.Title,
+		Expiry:   auction.Expiry,
+		Currency: auction.Currency,
+		Status:   status,
+	}
+	if bid, ok := domain.GetHighestVisibleBid(state); ok {
+		item.HighestBid = &bid.Amount
+	}
+	return item
+}
+
+// nextPageLink returns a Link header value pointing to the page after cursor
+func nextPageLink(r *http.Request, cursor auctionCursor) string {
+	values := r.URL.Query()
+	values.Set("cursor", cursor.encode())
+	next := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+	return fmt.Sprintf("<%s>; rel=\"next\"", next.String())
+}
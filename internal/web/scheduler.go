@@ -0,0 +1,219 @@
+package web
+
+import (
+	"container/heap"
+	"log"
+	"sync"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// Scheduler watches auction expiries and emits closing events once auctions have ended.
+// The auctions that have not been closed are queued by the time they end, which follows
+// their state as bids extend them, so that a tick only looks at the auctions that are due.
+type Scheduler struct {
+	State          *AppState
+	OnEvents       func([]domain.Event) error
+	GetCurrentTime func() time.Time
+
+	// ticking serializes ticks, so that an auction is closed only once
+	ticking sync.Mutex
+	// mu guards the queue and the closed auctions; it is never held while updating State
+	mu     sync.Mutex
+	queue  *expiryQueue
+	closed map[domain.AuctionId]bool
+}
+
+// NewScheduler creates a new scheduler; closed holds the auctions that have already been closed
+// and onEvents persists the closing events of an auction at once
+func NewScheduler(state *AppState, closed map[domain.AuctionId]bool, onEvents func([]domain.Event) error, getCurrentTime func() time.Time) *Scheduler {
+	if closed == nil {
+		closed = make(map[domain.AuctionId]bool)
+	}
+	s := &Scheduler{
+		State:          state,
+		OnEvents:       onEvents,
+		GetCurrentTime: getCurrentTime,
+		queue:          newExpiryQueue(),
+		closed:         closed,
+	}
+
+	// Follow the auctions as they are added and bid on, then queue the ones already there
+	state.observeUpdates(s.schedule)
+	for id, entry := range state.GetRepository() {
+		s.schedule(id, entry.Auction, entry.State)
+	}
+	return s
+}
+
+// schedule queues the auction to be closed at the time it ends given its state,
+// unless it has been closed or cancelled
+func (s *Scheduler) schedule(id domain.AuctionId, auction domain.Auction, state domain.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, cancelled := state.(*domain.CancelledState); cancelled {
+		// A cancelled auction is closed by its cancellation
+		s.closed[id] = true
+	}
+	if s.closed[id] {
+		s.queue.remove(id)
+		return
+	}
+	s.queue.set(id, domain.EndsAt(auction, state))
+}
+
+// Tick closes every auction that has ended by the current time and returns the emitted events
+func (s *Scheduler) Tick() []domain.Event {
+	s.ticking.Lock()
+	defer s.ticking.Unlock()
+
+	now := s.GetCurrentTime()
+	s.mu.Lock()
+	due := s.queue.popDue(now)
+	s.mu.Unlock()
+
+	var emitted []domain.Event
+	for _, next := range due {
+		// An auction that has not ended after all was extended and queued again when its state was stored
+		events, err := s.State.CloseAuction(next.id, now, s.closing(next.id))
+		if err != nil {
+			// Retry on the next tick
+			log.Printf("Failed to close auction %d: %v", next.id, err)
+			s.retry(next)
+			continue
+		}
+		emitted = append(emitted, events...)
+	}
+
+	return emitted
+}
+
+// closing returns the function persisting the closing events of an auction, which records
+// the auction as closed once they are persisted, before its ended state is stored
+func (s *Scheduler) closing(id domain.AuctionId) func([]domain.Event) error {
+	return func(events []domain.Event) error {
+		if err := s.OnEvents(events); err != nil {
+			return err
+		}
+		s.markClosed(id)
+		return nil
+	}
+}
+
+// Closed returns the auctions that have been closed. Called while taking a snapshot of
+// State, it reflects the closing events persisted by then.
+func (s *Scheduler) Closed() map[domain.AuctionId]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closed := make(map[domain.AuctionId]bool, len(s.closed))
+	for id := range s.closed {
+		closed[id] = true
+	}
+	return closed
+}
+
+// retry queues an auction that failed to close again, unless a newer state queued it meanwhile
+func (s *Scheduler) retry(next scheduledClose) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, queued := s.queue.byAuction[next.id]; !queued && !s.closed[next.id] {
+		s.queue.set(next.id, next.at)
+	}
+}
+
+// markClosed records that the auction has been closed, so that it is never queued again
+func (s *Scheduler) markClosed(id domain.AuctionId) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed[id] = true
+	s.queue.remove(id)
+}
+
+// Run closes ended auctions every interval until stop is closed
+func (s *Scheduler) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Tick()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// scheduledClose is an auction queued to be closed at the time it ends
+type scheduledClose struct {
+	id    domain.AuctionId
+	at    time.Time
+	index int
+}
+
+// expiryQueue is a min-heap of auctions by the time they end, holding each auction at most once
+type expiryQueue struct {
+	items     []*scheduledClose
+	byAuction map[domain.AuctionId]*scheduledClose
+}
+
+func newExpiryQueue() *expiryQueue {
+	return &expiryQueue{byAuction: make(map[domain.AuctionId]*scheduledClose)}
+}
+
+func (q *expiryQueue) Len() int { return len(q.items) }
+
+func (q *expiryQueue) Less(i, j int) bool { return q.items[i].at.Before(q.items[j].at) }
+
+func (q *expiryQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+func (q *expiryQueue) Push(x interface{}) {
+	item := x.(*scheduledClose)
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+	q.byAuction[item.id] = item
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items[last] = nil
+	q.items = q.items[:last]
+	delete(q.byAuction, item.id)
+	return item
+}
+
+// set queues the auction to be closed at the given time, replacing the time it was queued at
+func (q *expiryQueue) set(id domain.AuctionId, at time.Time) {
+	if item, ok := q.byAuction[id]; ok {
+		item.at = at
+		heap.Fix(q, item.index)
+		return
+	}
+	heap.Push(q, &scheduledClose{id: id, at: at})
+}
+
+// remove takes the auction off the queue
+func (q *expiryQueue) remove(id domain.AuctionId) {
+	if item, ok := q.byAuction[id]; ok {
+		heap.Remove(q, item.index)
+	}
+}
+
+// popDue takes the auctions that end by now off the queue, earliest first
+func (q *expiryQueue) popDue(now time.Time) []scheduledClose {
+	var due []scheduledClose
+	for len(q.items) > 0 && !q.items[0].at.After(now) {
+		due = append(due, *heap.Pop(q).(*scheduledClose))
+	}
+	return due
+}
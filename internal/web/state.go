@@ -0,0 +1,260 @@
+package web
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// AppState holds the application state.
+// All changes to an auction are serialized through that auction's lock so that
+// commands for the same auction are applied atomically and in order, while
+// different auctions proceed in parallel.
+type AppState struct {
+	auctions *sync.Map // map[domain.AuctionId]struct{Auction domain.Auction, State domain.State}
+	// locks holds the locks of the auctions being updated; a lock is removed once no
+	// update holds or waits for it, so that locks do not outlive their use
+	locks   map[domain.AuctionId]*auctionLock
+	locksMu *sync.Mutex
+	// updates is held for reading during every update and for writing while
+	// taking a snapshot, so that snapshots never see a half-applied update
+	updates *sync.RWMutex
+	// onUpdate is called with every auction stored by an update, while holding its lock
+	onUpdate func(id domain.AuctionId, auction domain.Auction, state domain.State)
+}
+
+// NewAppState creates a new application state
+func NewAppState(repo domain.Repository) *AppState {
+	auctions := &sync.Map{}
+
+	// Convert repository to sync.Map
+	for id, entry := range repo {
+		auctions.Store(id, entry)
+	}
+
+	return &AppState{
+		auctions: auctions,
+		locks:    make(map[domain.AuctionId]*auctionLock),
+		locksMu:  &sync.Mutex{},
+		updates:  &sync.RWMutex{},
+	}
+}
+
+// GetRepository returns a snapshot of the current repository
+func (s *AppState) GetRepository() domain.Repository {
+	repo := make(domain.Repository)
+
+	s.auctions.Range(func(key, value interface{}) bool {
+		id := key.(domain.AuctionId)
+		entry := value.(struct {
+			Auction domain.Auction
+			State   domain.State
+		})
+		repo[id] = entry
+		return true
+	})
+
+	return repo
+}
+
+// GetAuction returns the auction with the given ID and its current state
+func (s *AppState) GetAuction(id domain.AuctionId) (domain.Auction, domain.State, bool) {
+	value, ok := s.auctions.Load(id)
+	if !ok {
+		return domain.Auction{}, nil, false
+	}
+	entry := value.(struct {
+		Auction domain.Auction
+		State   domain.State
+	})
+	return entry.Auction, entry.State, true
+}
+
+// Update applies fn to the auction with the given ID while holding that auction's lock.
+// fn receives a repository holding only that auction (or nothing if it does not exist)
+// and returns the repository to store. Nothing is stored when fn returns an error.
+func (s *AppState) Update(id domain.AuctionId, fn func(repo domain.Repository) (domain.Repository, error)) error {
+	s.updates.RLock()
+	defer s.updates.RUnlock()
+
+	unlock := s.lock(id)
+	defer unlock()
+
+	repo := make(domain.Repository)
+	if value, ok := s.auctions.Load(id); ok {
+		repo[id] = value.(struct {
+			Auction domain.Auction
+			State   domain.State
+		})
+	}
+
+	newRepo, err := fn(repo)
+	if err != nil {
+		return err
+	}
+
+	if entry, ok := newRepo[id]; ok {
+		s.auctions.Store(id, entry)
+		if s.onUpdate != nil {
+			s.onUpdate(id, entry.Auction, entry.State)
+		}
+	}
+	return nil
+}
+
+// observeUpdates sets the function called with every auction stored by an update
+func (s *AppState) observeUpdates(fn func(id domain.AuctionId, auction domain.Auction, state domain.State)) {
+	s.updates.Lock()
+	defer s.updates.Unlock()
+
+	s.onUpdate = fn
+}
+
+// Snapshot calls fn with a copy of the repository while no update is in progress.
+// Every event observed before fn is called is reflected in the repository.
+func (s *AppState) Snapshot(fn func(repo domain.Repository)) {
+	s.updates.Lock()
+	defer s.updates.Unlock()
+
+	fn(s.GetRepository())
+}
+
+// HandleCommand observes and handles a command against the current state of its auction.
+// The command is observed before it is handled and the resulting event is observed
+// before the new state is stored, all while holding the auction's lock, so that the
+// command and event logs reflect the order in which commands were applied.
+// onEvent returns the sequence number of the event in the event log, which is recorded
+// in the command's outcome; the outcome is observed with onCommand after the command.
+func (s *AppState) HandleCommand(cmd domain.Command, onCommand func(domain.Command) error, onEvent func(domain.Event) (int64, error)) (domain.Event, error) {
+	var event domain.Event
+	err := s.Update(cmd.GetAuctionId(), func(repo domain.Repository) (domain.Repository, error) {
+		if err := onCommand(cmd); err != nil {
+			return nil, observerError{err}
+		}
+
+		next, newRepo, err := domain.Handle(cmd, repo)
+		if err != nil {
+			observeOutcome(onCommand, domain.NewCommandOutcome(cmd, 0, err))
+			return nil, err
+		}
+
+		seq, err := onEvent(next)
+		if err != nil {
+			return nil, observerError{err}
+		}
+		observeOutcome(onCommand, domain.NewCommandOutcome(cmd, seq, nil))
+
+		event = next
+		return newRepo, nil
+	})
+	return event, err
+}
+
+// observeOutcome observes the outcome of a command. Outcomes only serve to explain
+// what happened, so a failure to observe one is logged rather than undoing the command.
+func observeOutcome(onCommand func(domain.Command) error, outcome domain.CommandOutcome) {
+	if err := onCommand(outcome); err != nil {
+		log.Printf("Failed to record outcome of %s command for auction %d: %v", outcome.Command, outcome.AuctionId, err)
+	}
+}
+
+// stateAfter returns the state of the auction of an event once the event is applied to the
+// stored state. Events are observed while holding the auction's lock and before the state
+// they lead to is stored, so it is the state the event leads to.
+func (s *AppState) stateAfter(event domain.Event) domain.State {
+	id := event.GetAuctionId()
+	repo := make(domain.Repository)
+	if value, ok := s.auctions.Load(id); ok {
+		repo[id] = value.(struct {
+			Auction domain.Auction
+			State   domain.State
+		})
+	}
+	return domain.ApplyEvent(repo, event)[id].State
+}
+
+// CloseAuction closes the auction with the given ID if it has ended by now.
+// Cancelled auctions never close, so they give no events.
+// The closing events are stamped with the time the auction ended rather than now, and
+// observed together, so that they are persisted all or not at all, before the ended
+// state is stored.
+func (s *AppState) CloseAuction(id domain.AuctionId, now time.Time, onEvents func([]domain.Event) error) ([]domain.Event, error) {
+	var events []domain.Event
+	err := s.Update(id, func(repo domain.Repository) (domain.Repository, error) {
+		entry, ok := repo[id]
+		if !ok {
+			return nil, domain.NewAuctionNotFoundError(id)
+		}
+		if _, cancelled := entry.State.(*domain.CancelledState); cancelled {
+			return repo, nil
+		}
+
+		nextState := entry.State.Increment(now)
+		if !nextState.HasEnded() {
+			return repo, nil
+		}
+
+		closing := domain.CloseAuction(domain.EndsAt(entry.Auction, nextState), entry.Auction, nextState)
+		if err := onEvents(closing); err != nil {
+			return nil, observerError{err}
+		}
+
+		events = closing
+		repo[id] = struct {
+			Auction domain.Auction
+			State   domain.State
+		}{
+			Auction: entry.Auction,
+			State:   nextState,
+		}
+		return repo, nil
+	})
+	return events, err
+}
+
+// auctionLock guards an auction; users counts the updates holding or waiting for it
+type auctionLock struct {
+	sync.Mutex
+	users int
+}
+
+// lock acquires the lock guarding the auction with the given ID and returns the function
+// releasing it. The last user to release the lock removes it.
+func (s *AppState) lock(id domain.AuctionId) func() {
+	s.locksMu.Lock()
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &auctionLock{}
+		s.locks[id] = lock
+	}
+	lock.users++
+	s.locksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		s.locksMu.Lock()
+		defer s.locksMu.Unlock()
+		lock.users--
+		if lock.users == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
+
+// observerError wraps a failure to observe a command or event,
+// as opposed to a domain error raised while handling the command
+type observerError struct {
+	err error
+}
+
+func (e observerError) Error() string {
+	return e.err.Error()
+}
+
+func (e observerError) Unwrap() error {
+	return e.err
+}
@@ -2,63 +2,34 @@ package web
 
 import (
 	"encoding/json"
-	"sync"
 	"time"
 
 	"auction-site-go/internal/domain"
 )
 
-// AppState holds the application state
-type AppState struct {
-	auctions *sync.Map // map[domain.AuctionId]struct{Auction domain.Auction, State domain.State}
-}
-
-// NewAppState creates a new application state
-func NewAppState(repo domain.Repository) *AppState {
-	auctions := &sync.Map{}
-
-	// Convert repository to sync.Map
-	for id, entry := range repo {
-		auctions.Store(id, entry)
-	}
-
-	return &AppState{
-		auctions: auctions,
-	}
-}
-
-// GetRepository returns the current repository
-func (s *AppState) GetRepository() domain.Repository {
-	repo := make(domain.Repository)
-
-	s.auctions.Range(func(key, value interface{}) bool {
-		id := key.(domain.AuctionId)
-		entry := value.(struct {
-			Auction domain.Auction
-			State   domain.State
-		})
-		repo[id] = entry
-		return true
-	})
-
-	return repo
-}
-
-// UpdateRepository updates the repository with new values
-func (s *AppState) UpdateRepository(repo domain.Repository) {
-	for id, entry := range repo {
-		s.auctions.Store(id, entry)
-	}
-}
-
 // ApiError represents an API error response
 type ApiError struct {
 	Message string `json:"message"`
 }
 
-// BidRequest represents a request to place a bid
+// BidRequest represents a request to place a bid. Amounts are either strings like
+// "SEK100" or plain numbers in the auction's currency.
 type BidRequest struct {
-	Amount int64 `json:"amount"`
+	Amount    domain.Amount `json:"amount"`
+	MaxAmount domain.Amount `json:"maxAmount"`
+	// Quantity is the number of units bid for in a multi-unit auction
+	Quantity int `json:"quantity"`
+}
+
+// CancelAuctionRequest represents a request to cancel an auction
+type CancelAuctionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RetractBidRequest represents a request to retract the bids of a bidder
+type RetractBidRequest struct {
+	Bidder domain.UserId `json:"bidder"`
+	Reason string        `json:"reason"`
 }
 
 // AddAuctionRequest represents a request to add an auction
@@ -69,6 +40,8 @@ type AddAuctionRequest struct {
 	EndsAt   time.Time          `json:"endsAt"`
 	Currency domain.Currency    `json:"currency"`
 	Type     domain.AuctionType `json:"typ,omitempty"`
+	// ShowReserve lets bidders see the reserve price
+	ShowReserve bool `json:"showReserve"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler
@@ -91,8 +64,17 @@ func (r *AddAuctionRequest) UnmarshalJSON(data []byte) error {
 
 // AuctionBidResponse represents a bid in an auction response
 type AuctionBidResponse struct {
-	Amount int64       `json:"amount"`
-	Bidder domain.User `json:"bidder"`
+	Amount   domain.Amount `json:"amount"`
+	Bidder   domain.User   `json:"bidder"`
+	Quantity int           `json:"quantity,omitempty"`
+}
+
+// AllocationResponse represents what a winner of an auction won
+type AllocationResponse struct {
+	Bidder   domain.UserId `json:"bidder"`
+	Quantity int           `json:"quantity"`
+	// Price is the price per unit
+	Price domain.Amount `json:"price"`
 }
 
 // AuctionResponse represents an auction with bids and winner information
@@ -104,14 +86,42 @@ type AuctionResponse struct {
 	Currency    domain.Currency      `json:"currency"`
 	Bids        []AuctionBidResponse `json:"bids"`
 	Winner      *domain.UserId       `json:"winner"`
-	WinnerPrice *int64               `json:"winnerPrice"`
+	WinnerPrice *domain.Amount       `json:"winnerPrice"`
+	// BuyNowPrice is the price at which the item can be bought right now, if it can
+	BuyNowPrice *domain.Amount `json:"buyNowPrice,omitempty"`
+	// ReserveMet tells whether the highest bid meets the reserve price, if there is one
+	ReserveMet bool `json:"reserveMet"`
+	// ReservePrice is only shown when the seller allows it
+	ReservePrice *domain.Amount `json:"reservePrice,omitempty"`
+	// NextExpiry is when the auction ends given the bids so far, which is later than
+	// Expiry once bids close to the end have extended it
+	NextExpiry time.Time `json:"nextExpiry"`
+	// Allocations lists the winners with what they won, highest bid first
+	Allocations []AllocationResponse `json:"allocations"`
 }
 
 // AuctionListItem represents an auction in a list
 type AuctionListItem struct {
-	ID       domain.AuctionId `json:"id"`
-	StartsAt time.Time        `json:"startsAt"`
-	Title    string           `json:"title"`
-	Expiry   time.Time        `json:"expiry"`
-	Currency domain.Currency  `json:"currency"`
+	ID         domain.AuctionId     `json:"id"`
+	StartsAt   time.Time            `json:"startsAt"`
+	Title      string               `json:"title"`
+	Expiry     time.Time            `json:"expiry"`
+	Currency   domain.Currency      `json:"currency"`
+	Status     domain.AuctionStatus `json:"status"`
+	HighestBid *domain.Amount       `json:"highestBid,omitempty"`
+}
+
+// CommandRecordResponse represents a command from the command log and its outcome
+type CommandRecordResponse struct {
+	Seq     int64            `json:"seq"`
+	Command domain.Command   `json:"command"`
+	Outcome *OutcomeResponse `json:"outcome"`
+}
+
+// OutcomeResponse represents whether a command was accepted and, if it was rejected,
+// the error the client received
+type OutcomeResponse struct {
+	Accepted bool                   `json:"accepted"`
+	EventSeq int64                  `json:"event,omitempty"`
+	Error    map[string]interface{} `json:"error,omitempty"`
 }
@@ -19,10 +19,15 @@ var (
 	buyer1          = domain.NewBuyerOrSeller("Buyer_1", "Buyer 1")
 	buyer2          = domain.NewBuyerOrSeller("Buyer_2", "Buyer 2")
 	buyer3          = domain.NewBuyerOrSeller("Buyer_3", "Buyer 3")
-	bidAmount1      = int64(10)
-	bidAmount2      = int64(12)
+	bidAmount1      = sek(10)
+	bidAmount2      = sek(12)
 )
 
+// sek returns an amount in the currency of the sample auction
+func sek(value int64) domain.Amount {
+	return domain.NewAmount(domain.SEK, value)
+}
+
 // Helper function to parse time
 func mustParseTime(timeStr string) time.Time {
 	t, err := time.Parse(time.RFC3339, timeStr)
@@ -69,7 +74,7 @@ func createBidLessThan2() domain.Bid {
 		ForAuction: sampleAuctionId,
 		Bidder:     buyer3,
 		At:         sampleStartsAt.Add(3 * time.Second),
-		Amount:     11,
+		Amount:     sek(11),
 	}
 }
 
@@ -383,8 +388,8 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 	t.Run("ReservePriceWorks", func(t *testing.T) {
 		// Create an auction with a reserve price
 		reserveOptions := domain.TimedAscendingOptions{
-			ReservePrice: 15,
-			MinRaise:     0,
+			ReservePrice: sek(15),
+			MinRaise:     sek(0),
 			TimeFrame:    0,
 		}
 
@@ -409,7 +414,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			ForAuction: sampleAuctionId,
 			Bidder:     buyer3,
 			At:         sampleStartsAt.Add(3 * time.Second),
-			Amount:     20,
+			Amount:     sek(20),
 		}
 
 		// Start with a fresh state
@@ -423,7 +428,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			t.Errorf("Expected to find winner when highest bid is above reserve price")
 		}
 
-		if amount != 20 {
+		if amount != sek(20) {
 			t.Errorf("Expected winning amount to be 20, got %v", amount)
 		}
 
@@ -436,8 +441,8 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 	t.Run("MinimumRaiseWorks", func(t *testing.T) {
 		// Create an auction with a minimum raise requirement
 		minRaiseOptions := domain.TimedAscendingOptions{
-			ReservePrice: 0,
-			MinRaise:     5,
+			ReservePrice: sek(0),
+			MinRaise:     sek(5),
 			TimeFrame:    0,
 		}
 
@@ -455,7 +460,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			ForAuction: sampleAuctionId,
 			Bidder:     buyer2,
 			At:         sampleStartsAt.Add(2 * time.Second),
-			Amount:     14, // Only 4 more than bid1
+			Amount:     sek(14), // Only 4 more than bid1
 		}
 
 		_, err := stateWith1Bid.AddBid(smallRaiseBid)
@@ -472,7 +477,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			ForAuction: sampleAuctionId,
 			Bidder:     buyer2,
 			At:         sampleStartsAt.Add(2 * time.Second),
-			Amount:     15, // 5 more than bid1
+			Amount:     sek(15), // 5 more than bid1
 		}
 
 		stateWith2Bids, err := stateWith1Bid.AddBid(goodRaiseBid)
@@ -485,7 +490,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			t.Errorf("Expected 2 bids, got %d", len(bids))
 		}
 
-		if bids[0].Amount != 15 {
+		if bids[0].Amount != sek(15) {
 			t.Errorf("Expected highest bid to be 15, got %v", bids[0].Amount)
 		}
 	})
@@ -494,8 +499,8 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 	t.Run("TimeFrameWorks", func(t *testing.T) {
 		// Create an auction with a time frame
 		timeFrameOptions := domain.TimedAscendingOptions{
-			ReservePrice: 0,
-			MinRaise:     0,
+			ReservePrice: sek(0),
+			MinRaise:     sek(0),
 			TimeFrame:    10 * time.Minute,
 		}
 
@@ -511,7 +516,7 @@ func TestTimedAscendingAuctionState(t *testing.T) {
 			ForAuction: sampleAuctionId,
 			Bidder:     buyer1,
 			At:         bidTime,
-			Amount:     10,
+			Amount:     sek(10),
 		}
 
 		stateWithLateBid, _ := activeState.AddBid(lateBid)
@@ -610,7 +615,7 @@ func TestCommandHandling(t *testing.T) {
 			ForAuction: auction.ID,
 			Bidder:     buyer1,
 			At:         sampleStartsAt.Add(time.Second),
-			Amount:     10,
+			Amount:     sek(10),
 		}
 
 		cmd := domain.PlaceBidCommand{
@@ -646,3 +651,257 @@ func TestCommandHandling(t *testing.T) {
 		}
 	})
 }
+
+// Test timed descending (Dutch) auction
+func TestTimedDescendingAuctionState(t *testing.T) {
+	options := domain.TimedDescendingOptions{
+		StartPrice:   sek(100),
+		Decrement:    sek(10),
+		TickInterval: time.Hour,
+		FloorPrice:   sek(20),
+	}
+	dutchAuction := sampleAuctionOfType(domain.NewTimedDescendingType(options))
+	emptyDutchAuctionState := dutchAuction.CreateEmptyState()
+
+	t.Run("CannotBidBeforeStart", func(t *testing.T) {
+		earlyBid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer1,
+			At:         sampleStartsAt.Add(-time.Second),
+			Amount:     sek(100),
+		}
+
+		_, err := emptyDutchAuctionState.AddBid(earlyBid)
+		if domainErr, ok := err.(domain.DomainError); !ok || domainErr.Type != domain.ErrorAuctionHasNotStarted {
+			t.Errorf("Expected AuctionHasNotStarted error, got %v", err)
+		}
+	})
+
+	t.Run("CannotBidBelowClockPrice", func(t *testing.T) {
+		lowBid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer1,
+			At:         sampleStartsAt.Add(2*time.Hour + time.Minute),
+			Amount:     sek(70),
+		}
+
+		_, err := emptyDutchAuctionState.AddBid(lowBid)
+		if domainErr, ok := err.(domain.DomainError); !ok || domainErr.Type != domain.ErrorMustPlaceBidOverHighest {
+			t.Errorf("Expected MustPlaceBidOverHighestBid error, got %v", err)
+		} else if domainErr.Data != sek(80) {
+			t.Errorf("Expected current price 80 in error, got %v", domainErr.Data)
+		}
+	})
+
+	t.Run("FirstAcceptedBidWinsAtClockPrice", func(t *testing.T) {
+		acceptBid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer1,
+			At:         sampleStartsAt.Add(3*time.Hour + time.Minute),
+			Amount:     sek(90),
+		}
+
+		endedState, err := emptyDutchAuctionState.AddBid(acceptBid)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !endedState.HasEnded() {
+			t.Errorf("Expected auction to have ended after the first accepted bid")
+		}
+
+		amount, winner, found := endedState.TryGetAmountAndWinner()
+		if !found {
+			t.Fatalf("Expected to find winner and price")
+		}
+		if amount != sek(70) {
+			t.Errorf("Expected winning amount to be the clock price 70, got %v", amount)
+		}
+		if winner != buyer1.ID {
+			t.Errorf("Expected winner to be %s, got %s", buyer1.ID, winner)
+		}
+
+		// The winning bid is shown at the clock price, not at the amount bid
+		if bids := endedState.GetBids(); len(bids) != 1 || bids[0].Amount != sek(70) || bids[0].Bidder != buyer1 {
+			t.Errorf("Expected the winning bid of %s at 70, got %v", buyer1.ID, bids)
+		}
+
+		// A later bid is rejected
+		_, err = endedState.AddBid(createBid2())
+		if domainErr, ok := err.(domain.DomainError); !ok || domainErr.Type != domain.ErrorAuctionHasEnded {
+			t.Errorf("Expected AuctionHasEnded error, got %v", err)
+		}
+	})
+
+	t.Run("PriceStopsAtFloor", func(t *testing.T) {
+		floorBid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer2,
+			At:         sampleStartsAt.Add(48 * time.Hour),
+			Amount:     sek(20),
+		}
+
+		endedState, err := emptyDutchAuctionState.AddBid(floorBid)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		amount, _, _ := endedState.TryGetAmountAndWinner()
+		if amount != sek(20) {
+			t.Errorf("Expected winning amount to be the floor price 20, got %v", amount)
+		}
+	})
+
+	t.Run("ReplayYieldsSameWinner", func(t *testing.T) {
+		bid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer1,
+			At:         sampleStartsAt.Add(5 * time.Hour),
+			Amount:     sek(50),
+		}
+		repo := domain.EventsToAuctionStates([]domain.Event{
+			domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: dutchAuction},
+			domain.BidAcceptedEvent{Time: bid.At, Bid: bid},
+		})
+
+		amount, winner, found := repo[sampleAuctionId].State.TryGetAmountAndWinner()
+		if !found || amount != sek(50) || winner != buyer1.ID {
+			t.Errorf("Expected %s to win at 50, got %s at %v (found=%v)", buyer1.ID, winner, amount, found)
+		}
+	})
+
+	// Run common increment tests
+	testStateIncrement(t, emptyDutchAuctionState)
+}
+
+// Test proxy (maximum) bidding in timed ascending auctions
+func TestTimedAscendingProxyBidding(t *testing.T) {
+	options := domain.TimedAscendingOptions{
+		ReservePrice: sek(0),
+		MinRaise:     sek(1),
+		TimeFrame:    0,
+	}
+	auction := sampleAuctionOfType(domain.NewTimedAscendingType(options))
+	activeState := auction.CreateEmptyState().Increment(sampleStartsAt.Add(time.Second))
+
+	proxyBid := domain.NewProxyBid(sampleAuctionId, buyer1, sampleStartsAt.Add(time.Second), sek(10), sek(50))
+
+	t.Run("HiddenMaximumIsNotVisible", func(t *testing.T) {
+		state, err := activeState.AddBid(proxyBid)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bids := state.GetBids()
+		if len(bids) != 1 || bids[0].Amount != sek(10) || bids[0].MaxAmount != (domain.Amount{}) {
+			t.Errorf("Expected a single visible bid of 10 without maximum, got %+v", bids)
+		}
+	})
+
+	t.Run("ProxyDefendsAgainstLowerBid", func(t *testing.T) {
+		state, _ := activeState.AddBid(proxyBid)
+		state, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(20)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bids := state.GetBids()
+		if bids[0].Bidder.ID != buyer1.ID || bids[0].Amount != sek(21) {
+			t.Errorf("Expected %s to lead at 21, got %s at %v", buyer1.ID, bids[0].Bidder.ID, bids[0].Amount)
+		}
+
+		ended := state.Increment(sampleEndsAt.Add(time.Second))
+		amount, winner, found := ended.TryGetAmountAndWinner()
+		if !found || amount != sek(21) || winner != buyer1.ID {
+			t.Errorf("Expected %s to win at 21, got %s at %v", buyer1.ID, winner, amount)
+		}
+	})
+
+	t.Run("CompetingProxiesResolveInMinRaiseSteps", func(t *testing.T) {
+		state, _ := activeState.AddBid(proxyBid)
+		state, err := state.AddBid(domain.NewProxyBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(11), sek(80)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bids := state.GetBids()
+		if bids[0].Bidder.ID != buyer2.ID || bids[0].Amount != sek(51) {
+			t.Errorf("Expected %s to lead at 51, got %s at %v", buyer2.ID, bids[0].Bidder.ID, bids[0].Amount)
+		}
+		if bids[1].Bidder.ID != buyer1.ID || bids[1].Amount != sek(50) {
+			t.Errorf("Expected %s's proxy to be exhausted at 50, got %s at %v", buyer1.ID, bids[1].Bidder.ID, bids[1].Amount)
+		}
+	})
+
+	t.Run("EarlierProxyWinsTies", func(t *testing.T) {
+		state, _ := activeState.AddBid(proxyBid)
+		state, err := state.AddBid(domain.NewProxyBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(11), sek(50)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bids := state.GetBids()
+		if bids[0].Bidder.ID != buyer1.ID || bids[0].Amount != sek(50) {
+			t.Errorf("Expected %s to lead at 50, got %s at %v", buyer1.ID, bids[0].Bidder.ID, bids[0].Amount)
+		}
+	})
+
+	t.Run("LeaderRaisingTheirMaximumKeepsThePrice", func(t *testing.T) {
+		state, _ := activeState.AddBid(proxyBid)
+		state, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer1, sampleStartsAt.Add(2*time.Second), sek(60)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		bids := state.GetBids()
+		if len(bids) != 1 || bids[0].Bidder.ID != buyer1.ID || bids[0].Amount != sek(10) {
+			t.Errorf("Expected %s to still lead at 10, got %+v", buyer1.ID, bids)
+		}
+
+		// The raised maximum defends against a bid over the original one
+		state, err = state.AddBid(domain.NewBid(sampleAuctionId, buyer2, sampleStartsAt.Add(3*time.Second), sek(55)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if bids := state.GetBids(); bids[0].Bidder.ID != buyer1.ID || bids[0].Amount != sek(56) {
+			t.Errorf("Expected %s to lead at 56, got %s at %v", buyer1.ID, bids[0].Bidder.ID, bids[0].Amount)
+		}
+	})
+
+	t.Run("ReplayYieldsSameOutcome", func(t *testing.T) {
+		bid2 := domain.NewProxyBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(11), sek(30))
+		bid3 := domain.NewBid(sampleAuctionId, buyer3, sampleStartsAt.Add(3*time.Second), sek(45))
+
+		repo := domain.Repository{}
+		var events []domain.Event
+		commands := []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: proxyBid.At, Bid: proxyBid},
+			domain.PlaceBidCommand{Time: bid2.At, Bid: bid2},
+			domain.PlaceBidCommand{Time: bid3.At, Bid: bid3},
+		}
+		for _, cmd := range commands {
+			event, next, err := domain.Handle(cmd, repo)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			events = append(events, event)
+			repo = next
+		}
+
+		replayed := domain.EventsToAuctionStates(events)
+		expected := repo[sampleAuctionId].State.GetBids()
+		actual := replayed[sampleAuctionId].State.GetBids()
+		if len(expected) != len(actual) {
+			t.Fatalf("Expected %d bids after replay, got %d", len(expected), len(actual))
+		}
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Errorf("Expected bid %d to be %+v after replay, got %+v", i, expected[i], actual[i])
+			}
+		}
+		if actual[0].Bidder.ID != buyer1.ID || actual[0].Amount != sek(46) {
+			t.Errorf("Expected %s to lead at 46, got %s at %v", buyer1.ID, actual[0].Bidder.ID, actual[0].Amount)
+		}
+	})
+}
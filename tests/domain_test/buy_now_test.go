@@ -0,0 +1,208 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func buyNowAuction(reserve, buyNow int64, untilReserveMet bool) domain.Auction {
+	return sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{
+		ReservePrice:          sek(reserve),
+		MinRaise:              sek(1),
+		BuyNowPrice:           sek(buyNow),
+		BuyNowUntilReserveMet: untilReserveMet,
+	}))
+}
+
+func TestBuyNowOptions(t *testing.T) {
+	t.Run("OptionsWithoutBuyNowKeepTheirFormat", func(t *testing.T) {
+		options, err := domain.ParseTimedAscendingOptions("English|SEK15|SEK1|0")
+		if err != nil {
+			t.Fatalf("Failed to parse options: %v", err)
+		}
+		if options.HasBuyNow() || options.String() != "English|SEK15.00|SEK1.00|0" {
+			t.Errorf("Expected options without a buy-now price, got %s", options)
+		}
+	})
+
+	t.Run("ParsesAndFormatsBuyNow", func(t *testing.T) {
+		for s, untilReserveMet := range map[string]bool{
+			"English|SEK15.00|SEK1.00|0|SEK100.00":   false,
+			"English|SEK15.00|SEK1.00|0|SEK100.00|1": true,
+		} {
+			options, err := domain.ParseTimedAscendingOptions(s)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", s, err)
+			}
+			if options.BuyNowPrice != sek(10000) || options.BuyNowUntilReserveMet != untilReserveMet {
+				t.Errorf("Expected a buy-now price of SEK100.00 from %s, got %+v", s, options)
+			}
+			if options.String() != s {
+				t.Errorf("Expected options to be formatted as %s, got %s", s, options)
+			}
+		}
+
+		if _, err := domain.ParseTimedAscendingOptions("English|0|0|0|SEK100|maybe"); err == nil {
+			t.Errorf("Expected an invalid withdrawal flag to be rejected")
+		}
+	})
+
+	t.Run("BuyNowMustExceedReserve", func(t *testing.T) {
+		_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: buyNowAuction(100, 100, false)}, domain.Repository{})
+		expectDomainError(t, err, domain.ErrorBuyNowBelowReserve)
+
+		_, _, err = domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: buyNowAuction(0, -100, false)}, domain.Repository{})
+		expectDomainError(t, err, domain.ErrorNegativeAmount)
+	})
+}
+
+func TestBuyNow(t *testing.T) {
+	bidAt := sampleStartsAt.Add(time.Hour)
+	ongoing := func(t *testing.T, auction domain.Auction) domain.Repository {
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+		})
+		return repo
+	}
+
+	expectBought := func(t *testing.T, state domain.State, buyer domain.UserId, price domain.Amount) {
+		t.Helper()
+		if !state.HasEnded() {
+			t.Fatalf("Expected the auction to have ended")
+		}
+		if amount, winner, found := state.TryGetAmountAndWinner(); !found || winner != buyer || amount != price {
+			t.Errorf("Expected %s to win at %v, got %s at %v", buyer, price, winner, amount)
+		}
+	}
+
+	t.Run("BidAtBuyNowPriceEndsAuction", func(t *testing.T) {
+		repo := ongoing(t, buyNowAuction(0, 100, false))
+
+		bid := domain.NewBid(sampleAuctionId, buyer2, bidAt, sek(150))
+		_, next, err := domain.Handle(domain.PlaceBidCommand{Time: bidAt, Bid: bid}, repo)
+		if err != nil {
+			t.Fatalf("Expected the bid to be accepted, got %v", err)
+		}
+		// A bid over the buy-now price pays the buy-now price
+		expectBought(t, next[sampleAuctionId].State, buyer2.ID, sek(100))
+
+		_, _, err = domain.Handle(domain.PlaceBidCommand{Time: bidAt.Add(time.Second), Bid: createBid1()}, next)
+		expectDomainError(t, err, domain.ErrorAuctionHasEnded)
+	})
+
+	t.Run("BuyNowCommand", func(t *testing.T) {
+		repo := ongoing(t, buyNowAuction(0, 100, false))
+
+		buyNow := domain.BuyNowCommand{Time: bidAt, AuctionId: sampleAuctionId, Buyer: buyer2}
+		event, next, err := domain.Handle(buyNow, repo)
+		if err != nil {
+			t.Fatalf("Expected the purchase to be accepted, got %v", err)
+		}
+		expectBought(t, next[sampleAuctionId].State, buyer2.ID, sek(100))
+
+		// The purchase is recorded as a bid, and replays to the same state
+		replayed := domain.EventsToAuctionStates([]domain.Event{
+			domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: buyNowAuction(0, 100, false)},
+			domain.BidAcceptedEvent{Time: createBid1().At, Bid: createBid1()},
+			event,
+		})
+		expectBought(t, replayed[sampleAuctionId].State, buyer2.ID, sek(100))
+
+		closing := domain.CloseAuction(bidAt, next[sampleAuctionId].Auction, next[sampleAuctionId].State)
+		if won, ok := closing[1].(domain.AuctionWonEvent); !ok || won.Winner != buyer2.ID || won.Price != sek(100) {
+			t.Errorf("Expected the auction to be won by %s, got %v", buyer2.ID, closing)
+		}
+	})
+
+	t.Run("BuyNowCommandIsRecorded", func(t *testing.T) {
+		buyNow := domain.BuyNowCommand{Time: bidAt, AuctionId: sampleAuctionId, Buyer: buyer2}
+		data, err := json.Marshal(buyNow)
+		if err != nil {
+			t.Fatalf("Failed to marshal command: %v", err)
+		}
+		cmd, err := domain.UnmarshalCommand(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", data, err)
+		}
+		if cmd != buyNow {
+			t.Errorf("Expected %+v, got %+v", buyNow, cmd)
+		}
+		if issuer, ok := domain.CommandIssuer(cmd); !ok || issuer != buyer2.ID || domain.CommandType(cmd) != "BuyNow" {
+			t.Errorf("Expected a BuyNow command issued by %s, got %s by %s", buyer2.ID, domain.CommandType(cmd), issuer)
+		}
+	})
+
+	t.Run("NotAvailable", func(t *testing.T) {
+		buyNow := domain.BuyNowCommand{Time: bidAt, AuctionId: sampleAuctionId, Buyer: buyer2}
+
+		_, _, err := domain.Handle(buyNow, ongoing(t, buyNowAuction(0, 0, false)))
+		expectDomainError(t, err, domain.ErrorBuyNowNotAvailable)
+
+		repo := ongoing(t, buyNowAuction(0, 100, false))
+		seller := buyNow
+		seller.Buyer = sampleSeller
+		_, _, err = domain.Handle(seller, repo)
+		expectDomainError(t, err, domain.ErrorSellerCannotPlaceBids)
+
+		notStarted, _ := handleAll(t, []domain.Command{domain.AddAuctionCommand{Time: sampleStartsAt, Auction: buyNowAuction(0, 100, false)}})
+		early := buyNow
+		early.Time = sampleStartsAt.Add(-time.Hour)
+		_, _, err = domain.Handle(early, notStarted)
+		expectDomainError(t, err, domain.ErrorAuctionHasNotStarted)
+
+		late := buyNow
+		late.Time = sampleEndsAt
+		_, _, err = domain.Handle(late, repo)
+		expectDomainError(t, err, domain.ErrorAuctionHasEnded)
+	})
+
+	t.Run("WithdrawnOnceReserveIsMet", func(t *testing.T) {
+		repo := ongoing(t, buyNowAuction(50, 100, true))
+		state := repo[sampleAuctionId].State.(*domain.OngoingState)
+		if price, ok := state.BuyNowPrice(); !ok || price != sek(100) {
+			t.Fatalf("Expected the item to be for sale at SEK1.00 below the reserve, got %v", price)
+		}
+
+		over := domain.NewBid(sampleAuctionId, buyer2, bidAt, sek(60))
+		_, repo, err := domain.Handle(domain.PlaceBidCommand{Time: bidAt, Bid: over}, repo)
+		if err != nil {
+			t.Fatalf("Expected the bid to be accepted, got %v", err)
+		}
+		if _, ok := repo[sampleAuctionId].State.(*domain.OngoingState).BuyNowPrice(); ok {
+			t.Errorf("Expected the buy-now price to be withdrawn")
+		}
+
+		_, _, err = domain.Handle(domain.BuyNowCommand{Time: bidAt, AuctionId: sampleAuctionId, Buyer: buyer3}, repo)
+		expectDomainError(t, err, domain.ErrorBuyNowNotAvailable)
+
+		// A high bid is now a regular bid
+		high := domain.NewBid(sampleAuctionId, buyer3, bidAt, sek(200))
+		_, repo, err = domain.Handle(domain.PlaceBidCommand{Time: bidAt, Bid: high}, repo)
+		if err != nil || repo[sampleAuctionId].State.HasEnded() {
+			t.Errorf("Expected the auction to go on, got %v", err)
+		}
+	})
+
+	t.Run("WithdrawnOnceProxyBiddingReachesIt", func(t *testing.T) {
+		// Both proxies start below the buy-now price, but bid each other up past it
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: buyNowAuction(0, 100, false)},
+			domain.PlaceBidCommand{Time: bidAt, Bid: domain.NewProxyBid(sampleAuctionId, buyer1, bidAt, sek(10), sek(400))},
+			domain.PlaceBidCommand{Time: bidAt, Bid: domain.NewProxyBid(sampleAuctionId, buyer2, bidAt, sek(20), sek(300))},
+		})
+		state := repo[sampleAuctionId].State.(*domain.OngoingState)
+		if highest := state.GetBids()[0]; highest.Amount != sek(301) {
+			t.Fatalf("Expected the highest bid to be SEK3.01, got %v", highest.Amount)
+		}
+		if _, ok := state.BuyNowPrice(); ok {
+			t.Errorf("Expected the buy-now price to be withdrawn")
+		}
+
+		_, _, err := domain.Handle(domain.BuyNowCommand{Time: bidAt, AuctionId: sampleAuctionId, Buyer: buyer3}, repo)
+		expectDomainError(t, err, domain.ErrorBuyNowNotAvailable)
+	})
+}
@@ -0,0 +1,272 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+var sampleSupport = domain.NewSupport("Sample_Support")
+
+// handleAll handles the commands in order, failing the test on the first error
+func handleAll(t *testing.T, commands []domain.Command) (domain.Repository, []domain.Event) {
+	t.Helper()
+
+	repo := domain.Repository{}
+	var events []domain.Event
+	for _, cmd := range commands {
+		event, next, err := domain.Handle(cmd, repo)
+		if err != nil {
+			t.Fatalf("Failed to handle %T: %v", cmd, err)
+		}
+		repo = next
+		events = append(events, event)
+	}
+	return repo, events
+}
+
+func expectDomainError(t *testing.T, err error, expected domain.ErrorType) {
+	t.Helper()
+
+	domainErr, ok := err.(domain.DomainError)
+	if !ok || domainErr.Type != expected {
+		t.Errorf("Expected %s error, got %v", expected, err)
+	}
+}
+
+func TestCancelAuction(t *testing.T) {
+	auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+	addAuction := domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}
+	placeBid := domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()}
+	cancelAt := sampleStartsAt.Add(time.Hour)
+
+	cancel := func(by domain.User, reason string) domain.CancelAuctionCommand {
+		return domain.CancelAuctionCommand{Time: cancelAt, AuctionId: sampleAuctionId, CancelledBy: by, Reason: reason}
+	}
+
+	t.Run("Support can cancel an auction with bids", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction, placeBid})
+
+		event, next, err := domain.Handle(cancel(sampleSupport, "Counterfeit item"), repo)
+		if err != nil {
+			t.Fatalf("Failed to cancel auction: %v", err)
+		}
+		if _, ok := event.(domain.AuctionCancelledEvent); !ok {
+			t.Fatalf("Expected AuctionCancelledEvent, got %T", event)
+		}
+
+		state := next[sampleAuctionId].State
+		if !state.HasEnded() {
+			t.Error("Expected cancelled auction to have ended")
+		}
+		if _, _, found := state.TryGetAmountAndWinner(); found {
+			t.Error("Expected cancelled auction to have no winner")
+		}
+		if domain.GetStatus(auction, state, cancelAt) != domain.AuctionCancelled {
+			t.Errorf("Expected status cancelled, got %s", domain.GetStatus(auction, state, cancelAt))
+		}
+
+		bid := createBid2()
+		bid.At = cancelAt.Add(time.Second)
+		_, err = state.AddBid(bid)
+		expectDomainError(t, err, domain.ErrorAuctionCancelled)
+
+		_, _, err = domain.Handle(cancel(sampleSupport, "Again"), next)
+		expectDomainError(t, err, domain.ErrorAuctionCancelled)
+	})
+
+	t.Run("Seller can cancel before the first bid", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction})
+
+		if _, _, err := domain.Handle(cancel(sampleSeller, "Changed my mind"), repo); err != nil {
+			t.Errorf("Expected seller to be able to cancel, got %v", err)
+		}
+	})
+
+	t.Run("Seller cannot cancel after the first bid", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction, placeBid})
+
+		_, _, err := domain.Handle(cancel(sampleSeller, "Changed my mind"), repo)
+		expectDomainError(t, err, domain.ErrorNotAuthorized)
+	})
+
+	t.Run("Buyers cannot cancel", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction})
+
+		_, _, err := domain.Handle(cancel(buyer1, "Too expensive"), repo)
+		expectDomainError(t, err, domain.ErrorNotAuthorized)
+	})
+
+	t.Run("Requires a reason", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction})
+
+		_, _, err := domain.Handle(cancel(sampleSupport, ""), repo)
+		expectDomainError(t, err, domain.ErrorReasonRequired)
+	})
+
+	t.Run("Cannot cancel an ended auction", func(t *testing.T) {
+		repo, _ := handleAll(t, []domain.Command{addAuction})
+
+		cmd := cancel(sampleSupport, "Too late")
+		cmd.Time = sampleEndsAt
+		_, _, err := domain.Handle(cmd, repo)
+		expectDomainError(t, err, domain.ErrorAuctionHasEnded)
+	})
+}
+
+func TestRetractBid(t *testing.T) {
+	retractAt := sampleStartsAt.Add(time.Hour)
+
+	retract := func(bidder domain.UserId) domain.RetractBidCommand {
+		return domain.RetractBidCommand{
+			Time:        retractAt,
+			AuctionId:   sampleAuctionId,
+			Bidder:      bidder,
+			RetractedBy: sampleSupport,
+			Reason:      "Bid placed by mistake",
+		}
+	}
+
+	t.Run("Next-highest bid leads after the leader is retracted", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{MinRaise: sek(1)}))
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+			domain.PlaceBidCommand{Time: createBid2().At, Bid: createBid2()},
+		})
+
+		_, next, err := domain.Handle(retract(buyer2.ID), repo)
+		if err != nil {
+			t.Fatalf("Failed to retract bid: %v", err)
+		}
+
+		bids := next[sampleAuctionId].State.GetBids()
+		if len(bids) != 1 || bids[0].Bidder.ID != buyer1.ID || bids[0].Amount != bidAmount1 {
+			t.Errorf("Expected buyer 1 to lead at %v, got %v", bidAmount1, bids)
+		}
+
+		// The new leader is outbid like any other leader
+		bid := domain.NewBid(sampleAuctionId, buyer3, retractAt.Add(time.Second), sek(bidAmount1.Value+1))
+		state, err := next[sampleAuctionId].State.AddBid(bid)
+		if err != nil {
+			t.Fatalf("Failed to place bid after retraction: %v", err)
+		}
+		if leader := state.GetBids()[0]; leader.Bidder.ID != buyer3.ID {
+			t.Errorf("Expected buyer 3 to lead, got %s", leader.Bidder.ID)
+		}
+
+		ended := state.Increment(sampleEndsAt)
+		if amount, winner, _ := ended.TryGetAmountAndWinner(); winner != buyer3.ID || amount != sek(bidAmount1.Value+1) {
+			t.Errorf("Expected buyer 3 to win at %d, got %s at %v", bidAmount1.Value+1, winner, amount)
+		}
+	})
+
+	t.Run("Removes the bid from a sealed bid auction", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewSingleSealedBidType(domain.Vickrey))
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+			domain.PlaceBidCommand{Time: createBid2().At, Bid: createBid2()},
+		})
+
+		_, next, err := domain.Handle(retract(buyer1.ID), repo)
+		if err != nil {
+			t.Fatalf("Failed to retract bid: %v", err)
+		}
+
+		// The retracted bidder may bid again
+		bid := createBid1()
+		bid.At = retractAt.Add(time.Second)
+		state, err := next[sampleAuctionId].State.AddBid(bid)
+		if err != nil {
+			t.Fatalf("Expected retracted bidder to be able to bid again, got %v", err)
+		}
+
+		_, next, err = domain.Handle(retract(buyer2.ID), repo)
+		if err != nil {
+			t.Fatalf("Failed to retract bid: %v", err)
+		}
+		ended := next[sampleAuctionId].State.Increment(sampleEndsAt)
+		if amount, winner, _ := ended.TryGetAmountAndWinner(); winner != buyer1.ID || amount != bidAmount1 {
+			t.Errorf("Expected buyer 1 to win at %v, got %s at %v", bidAmount1, winner, amount)
+		}
+		if len(state.GetBids()) != 2 {
+			t.Errorf("Expected 2 bids, got %d", len(state.GetBids()))
+		}
+	})
+
+	t.Run("Only support can retract bids", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+		})
+
+		for _, user := range []domain.User{sampleSeller, buyer1} {
+			cmd := retract(buyer1.ID)
+			cmd.RetractedBy = user
+			_, _, err := domain.Handle(cmd, repo)
+			expectDomainError(t, err, domain.ErrorNotAuthorized)
+		}
+	})
+
+	t.Run("Fails when the bidder has no bids", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+		})
+
+		_, _, err := domain.Handle(retract(buyer2.ID), repo)
+		expectDomainError(t, err, domain.ErrorBidNotFound)
+	})
+
+	t.Run("Fails once the auction has ended", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+		repo, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+			domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+		})
+
+		cmd := retract(buyer1.ID)
+		cmd.Time = sampleEndsAt
+		_, _, err := domain.Handle(cmd, repo)
+		expectDomainError(t, err, domain.ErrorAuctionHasEnded)
+	})
+}
+
+func TestCancellationReplay(t *testing.T) {
+	english := sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{MinRaise: sek(1)}))
+	blind := sampleAuctionOfType(domain.NewSingleSealedBidType(domain.Blind))
+	blind.ID = 2
+
+	bidOn := func(id domain.AuctionId, bid domain.Bid) domain.Command {
+		bid.ForAuction = id
+		return domain.PlaceBidCommand{Time: bid.At, Bid: bid}
+	}
+	retractAt := sampleStartsAt.Add(time.Hour)
+
+	repo, events := handleAll(t, []domain.Command{
+		domain.AddAuctionCommand{Time: sampleStartsAt, Auction: english},
+		domain.AddAuctionCommand{Time: sampleStartsAt, Auction: blind},
+		bidOn(english.ID, createBid1()),
+		bidOn(english.ID, domain.NewProxyBid(english.ID, buyer2, createBid2().At, bidAmount2, sek(30))),
+		bidOn(blind.ID, createBid1()),
+		bidOn(blind.ID, createBid2()),
+		domain.RetractBidCommand{Time: retractAt, AuctionId: english.ID, Bidder: buyer2.ID, RetractedBy: sampleSupport, Reason: "Shill bidding"},
+		domain.RetractBidCommand{Time: retractAt, AuctionId: blind.ID, Bidder: buyer1.ID, RetractedBy: sampleSupport, Reason: "Shill bidding"},
+		domain.CancelAuctionCommand{Time: retractAt, AuctionId: blind.ID, CancelledBy: sampleSupport, Reason: "Counterfeit item"},
+	})
+
+	replayed := domain.EventsToAuctionStates(events)
+	if !reflect.DeepEqual(replayed, repo) {
+		t.Errorf("Replayed repository differs from handled repository:\n%#v\n%#v", replayed, repo)
+	}
+
+	restored := restoreThroughJSON(t, replayed)
+	if !reflect.DeepEqual(restored, repo) {
+		t.Errorf("Restored repository differs from handled repository")
+	}
+}
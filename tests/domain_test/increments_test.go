@@ -0,0 +1,113 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// tieredOptions raise by 1 below 100, by 5 below 1000 and by 50 above
+func tieredOptions() domain.TimedAscendingOptions {
+	return domain.TimedAscendingOptions{
+		MinRaise: sek(1),
+		Increments: []domain.IncrementTier{
+			{From: sek(100), Increment: sek(5)},
+			{From: sek(1000), Increment: sek(50)},
+		},
+	}
+}
+
+func TestIncrementTable(t *testing.T) {
+	t.Run("ParsesAndFormatsIncrements", func(t *testing.T) {
+		s := "English|SEK0.00|SEK1.00,SEK100.00:SEK5.00,SEK1000.00:SEK50.00|0"
+		options, err := domain.ParseTimedAscendingOptions(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", s, err)
+		}
+		if len(options.Increments) != 2 || options.Increments[1] != (domain.IncrementTier{From: sek(100000), Increment: sek(5000)}) {
+			t.Errorf("Expected two increment tiers, got %+v", options.Increments)
+		}
+		if options.String() != s {
+			t.Errorf("Expected options to be formatted as %s, got %s", s, options)
+		}
+
+		for _, invalid := range []string{"English|0|1,100|0", "English|0|1,100:5:6|0", "English|0|1,SEK:5|0"} {
+			if _, err := domain.ParseTimedAscendingOptions(invalid); err == nil {
+				t.Errorf("Expected %s to be rejected", invalid)
+			}
+		}
+	})
+
+	t.Run("IncrementAt", func(t *testing.T) {
+		options := tieredOptions()
+		for amount, increment := range map[int64]int64{0: 1, 99: 1, 100: 5, 999: 5, 1000: 50, 100000: 50} {
+			if got := options.IncrementAt(sek(amount)); got != sek(increment) {
+				t.Errorf("Expected an increment of %v over %v, got %v", sek(increment), sek(amount), got)
+			}
+		}
+	})
+
+	t.Run("TiersMustAscend", func(t *testing.T) {
+		for _, increments := range [][]domain.IncrementTier{
+			{{From: sek(0), Increment: sek(5)}},
+			{{From: sek(1000), Increment: sek(50)}, {From: sek(100), Increment: sek(5)}},
+			{{From: sek(100), Increment: sek(5)}, {From: sek(100), Increment: sek(50)}},
+		} {
+			options := domain.TimedAscendingOptions{MinRaise: sek(1), Increments: increments}
+			auction := sampleAuctionOfType(domain.NewTimedAscendingType(options))
+			_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{})
+			expectDomainError(t, err, domain.ErrorIncrementsNotAscending)
+		}
+
+		options := domain.TimedAscendingOptions{Increments: []domain.IncrementTier{{From: sek(100), Increment: sek(-5)}}}
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(options))
+		_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{})
+		expectDomainError(t, err, domain.ErrorNegativeAmount)
+	})
+}
+
+func TestIncrementTableBidding(t *testing.T) {
+	bidAt := sampleStartsAt.Add(time.Hour)
+	withHighestBid := func(t *testing.T, amount int64) domain.State {
+		t.Helper()
+		state := sampleAuctionOfType(domain.NewTimedAscendingType(tieredOptions())).CreateEmptyState()
+		state, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer1, bidAt, sek(amount)))
+		if err != nil {
+			t.Fatalf("Expected the first bid to be accepted, got %v", err)
+		}
+		return state
+	}
+
+	t.Run("ReportsNextAcceptableAmount", func(t *testing.T) {
+		for highest, next := range map[int64]int64{50: 51, 100: 105, 2000: 2050} {
+			state := withHighestBid(t, highest)
+			_, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer2, bidAt, sek(next-1)))
+			domainErr, ok := err.(domain.DomainError)
+			if !ok || domainErr.Type != domain.ErrorMustPlaceBidOverHighest || domainErr.Data != sek(next) {
+				t.Errorf("Expected a bid over %v to have to reach %v, got %v", sek(highest), sek(next), err)
+			}
+
+			if _, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer2, bidAt, sek(next))); err != nil {
+				t.Errorf("Expected a bid of %v over %v to be accepted, got %v", sek(next), sek(highest), err)
+			}
+		}
+	})
+
+	t.Run("ProxyBidsRaiseByTier", func(t *testing.T) {
+		state := withHighestBid(t, 100)
+		state, err := state.AddBid(domain.NewProxyBid(sampleAuctionId, buyer2, bidAt, sek(105), sek(2000)))
+		if err != nil {
+			t.Fatalf("Expected the proxy bid to be accepted, got %v", err)
+		}
+
+		// The proxy defends against a bid of 1200 by the increment of the tier it reaches
+		state, err = state.AddBid(domain.NewBid(sampleAuctionId, buyer3, bidAt, sek(1200)))
+		if err != nil {
+			t.Fatalf("Expected the bid to be accepted, got %v", err)
+		}
+		if leading := state.GetBids()[0]; leading.Bidder.ID != buyer2.ID || leading.Amount != sek(1250) {
+			t.Errorf("Expected %s to lead at %v, got %s at %v", buyer2.ID, sek(1250), leading.Bidder.ID, leading.Amount)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package domain_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{
+		"EUR12.50", "EUR12.5", "JPY1200", "SEK0.10", "KWD1.005", "VAC9223372036854775807",
+		"EUR92233720368547758.07", "EUR92233720368547758.08", "VAC9223372036854775808",
+		"", "EUR", "EUR.5", "EUR1.", "EUR1.234", "eur1", "XYZ10", "-EUR1", "EUR-0.50", "EUR--1",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		amount, err := domain.ParseAmount(s)
+		if err != nil {
+			return
+		}
+		if negative := strings.Contains(s, "-"); (negative && amount.Value > 0) || (!negative && amount.Value < 0) {
+			t.Fatalf("%q parsed to %+v", s, *amount)
+		}
+		if !strings.HasPrefix(s, string(amount.Currency)) {
+			t.Fatalf("%q parsed to an amount in %s", s, amount.Currency)
+		}
+
+		// Formatting an amount and parsing it again gives the same amount
+		formatted := amount.String()
+		again, err := domain.ParseAmount(formatted)
+		if err != nil {
+			t.Fatalf("%q parsed to %+v, which is formatted as %q that does not parse: %v", s, *amount, formatted, err)
+		}
+		if *again != *amount {
+			t.Fatalf("%q parsed to %+v, but its format %q parses to %+v", s, *amount, formatted, *again)
+		}
+	})
+}
+
+func FuzzEnglishBidAcceptance(f *testing.F) {
+	f.Add(int64(0), int64(0), int64(10), int64(12), int64(0), int64(11))
+	f.Add(int64(100), int64(5), int64(10), int64(14), int64(50), int64(0))
+	f.Add(int64(0), int64(1), int64(math.MaxInt64), int64(math.MaxInt64), int64(0), int64(1))
+	f.Add(int64(0), int64(math.MaxInt64), int64(1), int64(2), int64(math.MaxInt64), int64(3))
+	f.Add(int64(-1), int64(0), int64(-5), int64(0), int64(-1), int64(math.MinInt64))
+
+	f.Fuzz(func(t *testing.T, reserve, minRaise, amount1, max1, amount2, max2 int64) {
+		options := domain.TimedAscendingOptions{ReservePrice: sek(reserve), MinRaise: sek(minRaise)}
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(options))
+
+		_, repo, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{})
+		if reserve < 0 || minRaise < 0 {
+			expectDomainError(t, err, domain.ErrorNegativeAmount)
+			return
+		}
+		if err != nil {
+			t.Fatalf("Failed to add auction with %+v: %v", options, err)
+		}
+
+		bids := []domain.Bid{
+			domain.NewProxyBid(sampleAuctionId, buyer1, sampleStartsAt.Add(time.Second), sek(amount1), sek(max1)),
+			domain.NewProxyBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(amount2), sek(max2)),
+		}
+
+		highest := domain.Amount{}
+		for _, bid := range bids {
+			_, next, err := domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+			if !bid.Amount.IsPositive() || bid.MaxAmount.IsNegative() {
+				expectDomainError(t, err, domain.ErrorBidAmountNotPositive)
+				continue
+			}
+			if err != nil {
+				if domainErr, ok := err.(domain.DomainError); !ok || (domainErr.Type != domain.ErrorMustPlaceBidOverHighest && domainErr.Type != domain.ErrorAmountOverflow) {
+					t.Fatalf("Bid %v up to %v was rejected with %v", bid.Amount, bid.MaxAmount, err)
+				}
+				continue
+			}
+			repo = next
+
+			// The visible price never drops, and never exceeds what a bidder was willing to pay
+			visible := repo[sampleAuctionId].State.GetBids()
+			leading := visible[0]
+			if leading.Amount.LessThan(highest) {
+				t.Fatalf("The highest bid dropped from %v to %v", highest, leading.Amount)
+			}
+			for _, b := range visible {
+				if !b.Amount.IsPositive() {
+					t.Fatalf("A bid of %v is visible", b.Amount)
+				}
+			}
+			if leading.Bidder.ID == bid.Bidder.ID && leading.Amount.GreaterThan(bid.Ceiling()) {
+				t.Fatalf("%s leads with %v over their ceiling of %v", bid.Bidder.ID, leading.Amount, bid.Ceiling())
+			}
+			highest = leading.Amount
+		}
+
+		// The auction ends with a winner only if the reserve was met
+		ended := repo[sampleAuctionId].State.Increment(sampleEndsAt)
+		if price, _, found := ended.TryGetAmountAndWinner(); found && price.LessThan(sek(reserve)) {
+			t.Fatalf("The auction was won at %v below the reserve of %v", price, sek(reserve))
+		}
+	})
+}
@@ -0,0 +1,350 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func TestAmountSerialization(t *testing.T) {
+	t.Run("AcceptsNumbersAndStrings", func(t *testing.T) {
+		inputs := map[string]domain.Amount{
+			`"SEK100"`: sek(10000),
+			`100`:      {Value: 100},
+			`"100"`:    {Value: 100},
+		}
+		for input, expected := range inputs {
+			var amount domain.Amount
+			if err := json.Unmarshal([]byte(input), &amount); err != nil {
+				t.Fatalf("Failed to unmarshal %s: %v", input, err)
+			}
+			if amount != expected {
+				t.Errorf("Expected %s to be %+v, got %+v", input, expected, amount)
+			}
+		}
+	})
+
+	t.Run("RejectsInvalidAmounts", func(t *testing.T) {
+		for _, input := range []string{`"SEK"`, `"sek100"`, `10.5`, `{}`} {
+			var amount domain.Amount
+			if err := json.Unmarshal([]byte(input), &amount); err == nil {
+				t.Errorf("Expected %s to be rejected, got %+v", input, amount)
+			}
+		}
+	})
+
+	t.Run("WritesAmountsWithoutCurrencyAsNumbers", func(t *testing.T) {
+		data, _ := json.Marshal([]domain.Amount{sek(10000), {Value: 100}})
+		if string(data) != `["SEK100.00",100]` {
+			t.Errorf("Expected [\"SEK100.00\",100], got %s", data)
+		}
+	})
+
+	t.Run("ReplaysBidsRecordedWithoutCurrency", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+		data, err := json.Marshal(domain.BidAcceptedEvent{Time: createBid1().At, Bid: createBid1()})
+		if err != nil {
+			t.Fatalf("Failed to marshal event: %v", err)
+		}
+		legacy := strings.Replace(string(data), `"SEK0.10"`, `10`, 1)
+		event, err := domain.UnmarshalEvent([]byte(legacy))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", legacy, err)
+		}
+
+		repo := domain.EventsToAuctionStates([]domain.Event{
+			domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: auction},
+			event,
+		})
+		bids := repo[sampleAuctionId].State.GetBids()
+		if len(bids) != 1 || bids[0].Amount != sek(1000) {
+			t.Errorf("Expected a bid of SEK10.00, got %v", bids)
+		}
+	})
+}
+
+func TestTimedAscendingOptionAmounts(t *testing.T) {
+	t.Run("ParsesPlainValuesAndAmounts", func(t *testing.T) {
+		legacy, err := domain.ParseTimedAscendingOptions("English|15|1|0")
+		if err != nil {
+			t.Fatalf("Failed to parse options: %v", err)
+		}
+		if legacy.ReservePrice != (domain.Amount{Value: 15}) || legacy.MinRaise != (domain.Amount{Value: 1}) {
+			t.Errorf("Expected amounts without a currency, got %+v", legacy)
+		}
+		if legacy.String() != "English|15|1|0" {
+			t.Errorf("Expected options to be formatted as parsed, got %s", legacy.String())
+		}
+
+		options, err := domain.ParseTimedAscendingOptions("English|SEK15|SEK1.50|0")
+		if err != nil {
+			t.Fatalf("Failed to parse options: %v", err)
+		}
+		if options.ReservePrice != sek(1500) || options.MinRaise != sek(150) {
+			t.Errorf("Expected amounts in SEK, got %+v", options)
+		}
+		if options.String() != "English|SEK15.00|SEK1.50|0" {
+			t.Errorf("Expected options to be formatted as parsed, got %s", options.String())
+		}
+	})
+
+	t.Run("PlainValuesAreInTheAuctionCurrency", func(t *testing.T) {
+		auctionType, _ := domain.ParseAuctionType("English|15|0|0")
+		state := sampleAuctionOfType(auctionType).CreateEmptyState().Increment(sampleStartsAt.Add(time.Second))
+		state, _ = state.AddBid(createBid1())
+		state, _ = state.AddBid(domain.NewBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(1600)))
+
+		// The plain reserve of 15 is SEK15.00, which only the second bid reaches
+		if amount, winner, found := state.Increment(sampleEndsAt).TryGetAmountAndWinner(); !found || amount != sek(1600) || winner != buyer2.ID {
+			t.Errorf("Expected %s to win at SEK16.00, got %s at %v", buyer2.ID, winner, amount)
+		}
+	})
+}
+
+func TestMinorUnits(t *testing.T) {
+	t.Run("ParsesAndFormatsDecimals", func(t *testing.T) {
+		amounts := map[string]domain.Amount{
+			"EUR12.50": domain.NewAmount(domain.EUR, 1250),
+			"JPY1200":  domain.NewAmount(domain.JPY, 1200),
+			"KWD1.005": domain.NewAmount("KWD", 1005),
+			"VAC10":    domain.NewAmount(domain.VAC, 10),
+		}
+		for s, expected := range amounts {
+			amount, err := domain.ParseAmount(s)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", s, err)
+			}
+			if *amount != expected {
+				t.Errorf("Expected %s to be %+v, got %+v", s, expected, *amount)
+			}
+			if amount.String() != s {
+				t.Errorf("Expected %+v to be formatted as %s, got %s", expected, s, amount.String())
+			}
+		}
+	})
+
+	t.Run("AcceptsFewerDecimals", func(t *testing.T) {
+		for _, s := range []string{"EUR12.5", "EUR12.50"} {
+			amount, err := domain.ParseAmount(s)
+			if err != nil || *amount != domain.NewAmount(domain.EUR, 1250) {
+				t.Errorf("Expected %s to be EUR12.50, got %v, %v", s, amount, err)
+			}
+		}
+		if amount, _ := domain.ParseAmount("EUR12"); amount.String() != "EUR12.00" {
+			t.Errorf("Expected EUR12 to be formatted as EUR12.00, got %s", amount)
+		}
+	})
+
+	t.Run("RejectsTooManyDecimals", func(t *testing.T) {
+		for _, s := range []string{"EUR12.505", "JPY12.5", "VAC1.0", "EUR12."} {
+			if amount, err := domain.ParseAmount(s); err == nil {
+				t.Errorf("Expected %s to be rejected, got %v", s, amount)
+			}
+		}
+	})
+
+	t.Run("RejectsAmountsOutOfRange", func(t *testing.T) {
+		if amount, err := domain.ParseAmount("EUR92233720368547758.08"); err == nil {
+			t.Errorf("Expected the amount to be rejected, got %v", amount)
+		}
+		if amount, err := domain.ParseAmount("EUR92233720368547758.07"); err != nil || amount.Value != math.MaxInt64 {
+			t.Errorf("Expected the largest amount to be accepted, got %v, %v", amount, err)
+		}
+	})
+
+	t.Run("RejectsUnknownCurrencies", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+		auction.Currency = "XYZ"
+		_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{})
+		expectDomainError(t, err, domain.ErrorUnknownCurrency)
+
+		for _, currency := range []domain.Currency{domain.VAC, domain.EUR, domain.USD, domain.NOK, domain.JPY} {
+			auction.Currency = currency
+			if _, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{}); err != nil {
+				t.Errorf("Expected an auction in %s to be added, got %v", currency, err)
+			}
+		}
+	})
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+	repo, _ := handleAll(t, []domain.Command{domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}})
+
+	t.Run("RejectsBidsInAnotherCurrency", func(t *testing.T) {
+		bid := createBid1()
+		bid.Amount = domain.NewAmount(domain.DKK, 10)
+		_, _, err := domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+		expectDomainError(t, err, domain.ErrorCurrencyMismatch)
+
+		bid = createBid1()
+		bid.MaxAmount = domain.NewAmount(domain.DKK, 50)
+		_, _, err = domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+		expectDomainError(t, err, domain.ErrorCurrencyMismatch)
+	})
+
+	t.Run("BidsWithoutCurrencyAreInTheAuctionCurrency", func(t *testing.T) {
+		bid := createBid1()
+		bid.Amount = domain.Amount{Value: 10}
+		event, _, err := domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+		if err != nil {
+			t.Fatalf("Expected the bid to be accepted, got %v", err)
+		}
+		if accepted := event.(domain.BidAcceptedEvent); accepted.Bid.Amount != sek(1000) {
+			t.Errorf("Expected the bid to be recorded as SEK10.00, got %v", accepted.Bid.Amount)
+		}
+	})
+
+	t.Run("RejectsOptionsInAnotherCurrency", func(t *testing.T) {
+		other := auction
+		other.ID = 2
+		other.Type, _ = domain.ParseAuctionType("English|DKK15|0|0")
+		_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: other}, repo)
+		expectDomainError(t, err, domain.ErrorCurrencyMismatch)
+	})
+}
+
+// TestReplaysLegacyLogs replays events written before amounts had minor units: plain
+// values from before amounts carried a currency, and strings like "SEK150" that were
+// whole kronor. Both keep meaning whole kronor.
+func TestReplaysLegacyLogs(t *testing.T) {
+	log := []string{
+		`{"$type":"AuctionAdded","at":"2016-01-01T08:28:00Z","auction":{"id":1,"startsAt":"2016-01-01T08:28:00Z","title":"auction","expiry":"2016-02-01T08:28:00Z","user":"BuyerOrSeller|Sample_Seller|Seller","type":"English|100|10|0","currency":"SEK"}}`,
+		`{"$type":"BidAccepted","at":"2016-01-01T08:29:00Z","bid":{"auction":1,"user":"BuyerOrSeller|Buyer_1|Buyer 1","at":"2016-01-01T08:29:00Z","amount":120}}`,
+		`{"$type":"BidAccepted","at":"2016-01-01T08:30:00Z","bid":{"auction":1,"user":"BuyerOrSeller|Buyer_2|Buyer 2","at":"2016-01-01T08:30:00Z","amount":"SEK150"}}`,
+		`{"$type":"AuctionAdded","at":"2016-01-01T08:28:00Z","auction":{"id":2,"startsAt":"2016-01-01T08:28:00Z","title":"auction","expiry":"2016-02-01T08:28:00Z","user":"BuyerOrSeller|Sample_Seller|Seller","type":"Dutch|100|10|3600|20","currency":"SEK"}}`,
+		`{"$type":"BidAccepted","at":"2016-01-01T10:29:00Z","bid":{"auction":2,"user":"BuyerOrSeller|Buyer_1|Buyer 1","at":"2016-01-01T10:29:00Z","amount":"SEK90"}}`,
+	}
+	var events []domain.Event
+	for _, line := range log {
+		event, err := domain.UnmarshalEvent([]byte(line))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	repo := domain.EventsToAuctionStates(events)
+
+	english := repo[1].State
+	if reserve, _ := domain.ReservePrice(english); reserve != sek(10000) {
+		t.Errorf("Expected the plain reserve of 100 to be SEK100.00, got %v", reserve)
+	}
+	bids := english.GetBids()
+	if len(bids) != 2 || bids[0].Amount != sek(15000) || bids[1].Amount != sek(12000) {
+		t.Errorf("Expected bids of SEK150.00 and SEK120.00, got %v", bids)
+	}
+	if amount, winner, found := english.Increment(sampleEndsAt).TryGetAmountAndWinner(); !found || winner != buyer2.ID || amount != sek(15000) {
+		t.Errorf("Expected %s to win at SEK150.00, got %s at %v", buyer2.ID, winner, amount)
+	}
+
+	// Two hours into the auction the clock of the Dutch auction is at SEK80.00
+	if amount, winner, found := repo[2].State.TryGetAmountAndWinner(); !found || winner != buyer1.ID || amount != sek(8000) {
+		t.Errorf("Expected %s to win at SEK80.00, got %s at %v", buyer1.ID, winner, amount)
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	t.Run("AddAndSubDetectOverflow", func(t *testing.T) {
+		_, err := sek(math.MaxInt64).Add(sek(1))
+		expectDomainError(t, err, domain.ErrorAmountOverflow)
+		_, err = sek(math.MinInt64).Sub(sek(1))
+		expectDomainError(t, err, domain.ErrorAmountOverflow)
+		_, err = sek(0).Sub(sek(math.MinInt64))
+		expectDomainError(t, err, domain.ErrorAmountOverflow)
+
+		if sum, err := sek(math.MaxInt64 - 1).Add(sek(1)); err != nil || sum != sek(math.MaxInt64) {
+			t.Errorf("Expected the largest amount, got %v, %v", sum, err)
+		}
+		if diff, err := sek(10).Sub(sek(12)); err != nil || diff != sek(-2) {
+			t.Errorf("Expected SEK-0.02, got %v, %v", diff, err)
+		}
+	})
+
+	t.Run("CompareRejectsOtherCurrencies", func(t *testing.T) {
+		_, err := sek(10).Compare(domain.NewAmount(domain.DKK, 10))
+		expectDomainError(t, err, domain.ErrorCurrencyMismatch)
+
+		if c, err := sek(10).Compare(domain.Amount{Value: 12}); err != nil || c != -1 {
+			t.Errorf("Expected SEK0.10 to be less than 12, got %d, %v", c, err)
+		}
+	})
+
+	t.Run("BidsNearTheLimitDoNotWrapAround", func(t *testing.T) {
+		options := domain.TimedAscendingOptions{MinRaise: sek(10)}
+		state := sampleAuctionOfType(domain.NewTimedAscendingType(options)).CreateEmptyState().Increment(sampleStartsAt.Add(time.Second))
+		state, _ = state.AddBid(domain.NewBid(sampleAuctionId, buyer1, sampleStartsAt.Add(time.Second), sek(math.MaxInt64-5)))
+
+		// The next acceptable bid would be too large to represent
+		_, err := state.AddBid(domain.NewBid(sampleAuctionId, buyer2, sampleStartsAt.Add(2*time.Second), sek(math.MaxInt64)))
+		expectDomainError(t, err, domain.ErrorAmountOverflow)
+	})
+}
+
+func TestAmountRules(t *testing.T) {
+	auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+	repo, _ := handleAll(t, []domain.Command{domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}})
+
+	t.Run("BidsMustBePositive", func(t *testing.T) {
+		for _, amount := range []int64{0, -10} {
+			bid := createBid1()
+			bid.Amount = sek(amount)
+			_, _, err := domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+			expectDomainError(t, err, domain.ErrorBidAmountNotPositive)
+		}
+
+		bid := createBid1()
+		bid.MaxAmount = sek(-10)
+		_, _, err := domain.Handle(domain.PlaceBidCommand{Time: bid.At, Bid: bid}, repo)
+		expectDomainError(t, err, domain.ErrorBidAmountNotPositive)
+	})
+
+	t.Run("OptionsMustNotBeNegative", func(t *testing.T) {
+		for _, options := range []string{"English|SEK-1|0|0", "English|-1|0|0", "English|0|-1|0"} {
+			other := auction
+			other.ID = 2
+			other.Type, _ = domain.ParseAuctionType(options)
+			_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: other}, repo)
+			expectDomainError(t, err, domain.ErrorNegativeAmount)
+		}
+	})
+
+	t.Run("DutchClockMustRunDown", func(t *testing.T) {
+		other := auction
+		other.ID = 2
+		for options, expected := range map[string]domain.ErrorType{
+			"Dutch|0|10|60|0":     domain.ErrorAmountNotPositive,
+			"Dutch|100|0|60|0":    domain.ErrorAmountNotPositive,
+			"Dutch|100|-10|60|0":  domain.ErrorAmountNotPositive,
+			"Dutch|100|10|60|-5":  domain.ErrorNegativeAmount,
+			"Dutch|100|10|60|200": domain.ErrorFloorAboveStartPrice,
+		} {
+			other.Type, _ = domain.ParseAuctionType(options)
+			_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: other}, repo)
+			expectDomainError(t, err, expected)
+		}
+
+		other.Type, _ = domain.ParseAuctionType("Dutch|100|10|60|100")
+		if _, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: other}, repo); err != nil {
+			t.Errorf("Expected a clock starting at its floor to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("DutchClockStaysBetweenStartAndFloor", func(t *testing.T) {
+		for _, options := range []domain.TimedDescendingOptions{
+			{StartPrice: sek(math.MaxInt64), Decrement: sek(1), TickInterval: time.Nanosecond, FloorPrice: sek(0)},
+			{StartPrice: sek(math.MaxInt64), Decrement: sek(math.MaxInt64), TickInterval: time.Nanosecond, FloorPrice: sek(1)},
+			{StartPrice: sek(math.MaxInt64), Decrement: sek(1), TickInterval: time.Nanosecond, FloorPrice: sek(math.MinInt64)},
+		} {
+			for _, elapsed := range []time.Duration{time.Nanosecond, time.Hour, math.MaxInt64} {
+				price := options.PriceAt(sampleStartsAt, sampleStartsAt.Add(elapsed))
+				if price.GreaterThan(options.StartPrice) || price.LessThan(options.FloorPrice) {
+					t.Errorf("Expected the price of %+v after %v to stay between start and floor, got %v", options, elapsed, price)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func multiUnitAuction(units int, pricing domain.MultiUnitPricing, reserve int64) domain.Auction {
+	return sampleAuctionOfType(domain.NewMultiUnitType(domain.MultiUnitOptions{
+		Units:        units,
+		Pricing:      pricing,
+		ReservePrice: sek(reserve),
+	}))
+}
+
+// unitBid is a bid for a quantity of units at a price per unit
+func unitBid(bidder domain.User, seconds int, price int64, quantity int) domain.Bid {
+	bid := domain.NewBid(sampleAuctionId, bidder, sampleStartsAt.Add(time.Duration(seconds)*time.Second), sek(price))
+	bid.Quantity = quantity
+	return bid
+}
+
+func TestMultiUnitOptions(t *testing.T) {
+	for _, s := range []string{"MultiUnit|50|LowestAccepted", "MultiUnit|50|HighestRejected", "MultiUnit|1|PayAsBid|SEK100.00"} {
+		options, err := domain.ParseMultiUnitOptions(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", s, err)
+		}
+		if options.String() != s {
+			t.Errorf("Expected options to be formatted as %s, got %s", s, options)
+		}
+	}
+
+	for _, s := range []string{"MultiUnit|0|PayAsBid", "MultiUnit|ten|PayAsBid", "MultiUnit|10|Vickrey", "MultiUnit|10", "MultiUnit|10|PayAsBid|SEK1|SEK2"} {
+		if _, err := domain.ParseMultiUnitOptions(s); err == nil {
+			t.Errorf("Expected %s to be rejected", s)
+		}
+	}
+}
+
+func TestMultiUnitAllocations(t *testing.T) {
+	endedWith := func(t *testing.T, auction domain.Auction, bids ...domain.Bid) domain.State {
+		t.Helper()
+		state := auction.CreateEmptyState()
+		for _, bid := range bids {
+			next, err := state.AddBid(bid)
+			if err != nil {
+				t.Fatalf("Expected bid %v for %d units to be accepted, got %v", bid.Amount, bid.Quantity, err)
+			}
+			state = next
+		}
+		return state.Increment(sampleEndsAt)
+	}
+	expectAllocations := func(t *testing.T, state domain.State, expected ...domain.Allocation) {
+		t.Helper()
+		if allocations := domain.GetAllocations(state); !reflect.DeepEqual(allocations, expected) {
+			t.Errorf("Expected allocations %+v, got %+v", expected, allocations)
+		}
+	}
+
+	// 5 units are bid for at 12, 11 and 10: the bid at 11 wins 2 of the 3 units it was for
+	bids := []domain.Bid{
+		unitBid(buyer1, 1, 10, 2),
+		unitBid(buyer2, 2, 12, 3),
+		unitBid(buyer3, 3, 11, 3),
+	}
+
+	t.Run("SealedUntilExpiry", func(t *testing.T) {
+		state := multiUnitAuction(5, domain.PayAsBid, 0).CreateEmptyState()
+		state, _ = state.AddBid(bids[0])
+		if !domain.BidsSealed(state) || len(domain.GetAllocations(state)) != 0 {
+			t.Errorf("Expected the bids to be sealed without allocations before the expiry")
+		}
+		if _, ok := domain.GetHighestVisibleBid(state); ok {
+			t.Errorf("Expected no visible bid before the expiry")
+		}
+	})
+
+	t.Run("PayAsBid", func(t *testing.T) {
+		expectAllocations(t, endedWith(t, multiUnitAuction(5, domain.PayAsBid, 0), bids...),
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(12)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 2, Price: sek(11)},
+		)
+	})
+
+	t.Run("LowestAccepted", func(t *testing.T) {
+		expectAllocations(t, endedWith(t, multiUnitAuction(5, domain.LowestAccepted, 0), bids...),
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(11)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 2, Price: sek(11)},
+		)
+	})
+
+	t.Run("HighestRejected", func(t *testing.T) {
+		expectAllocations(t, endedWith(t, multiUnitAuction(5, domain.HighestRejected, 0), bids...),
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(10)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 2, Price: sek(10)},
+		)
+	})
+
+	t.Run("HighestRejectedWithoutRejectedBids", func(t *testing.T) {
+		// Without competition the winners pay the lowest winning bid, or the reserve price
+		expectAllocations(t, endedWith(t, multiUnitAuction(10, domain.HighestRejected, 0), bids...),
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(10)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 3, Price: sek(10)},
+			domain.Allocation{Bidder: buyer1.ID, Quantity: 2, Price: sek(10)},
+		)
+		expectAllocations(t, endedWith(t, multiUnitAuction(10, domain.HighestRejected, 5), bids...),
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(5)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 3, Price: sek(5)},
+			domain.Allocation{Bidder: buyer1.ID, Quantity: 2, Price: sek(5)},
+		)
+	})
+
+	t.Run("ReservePrice", func(t *testing.T) {
+		state := endedWith(t, multiUnitAuction(10, domain.HighestRejected, 11), bids...)
+		expectAllocations(t, state,
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 3, Price: sek(11)},
+			domain.Allocation{Bidder: buyer3.ID, Quantity: 3, Price: sek(11)},
+		)
+		if !domain.ReserveMet(state) {
+			t.Errorf("Expected the reserve to be met")
+		}
+
+		unsold := endedWith(t, multiUnitAuction(10, domain.PayAsBid, 20), bids...)
+		expectAllocations(t, unsold)
+		if _, _, found := unsold.TryGetAmountAndWinner(); found {
+			t.Errorf("Expected no winner below the reserve price")
+		}
+	})
+
+	t.Run("EarlierBidsWinTies", func(t *testing.T) {
+		expectAllocations(t, endedWith(t, multiUnitAuction(3, domain.PayAsBid, 0), unitBid(buyer1, 1, 10, 2), unitBid(buyer2, 2, 10, 2)),
+			domain.Allocation{Bidder: buyer1.ID, Quantity: 2, Price: sek(10)},
+			domain.Allocation{Bidder: buyer2.ID, Quantity: 1, Price: sek(10)},
+		)
+	})
+
+	t.Run("SingleItemAuctionsHaveOneAllocation", func(t *testing.T) {
+		state := endedWith(t, sampleAuctionOfType(domain.NewSingleSealedBidType(domain.Blind)), createBid1(), createBid2())
+		expectAllocations(t, state, domain.Allocation{Bidder: buyer2.ID, Quantity: 1, Price: bidAmount2})
+	})
+}
+
+func TestMultiUnitCommands(t *testing.T) {
+	auction := multiUnitAuction(5, domain.LowestAccepted, 0)
+	repo, _ := handleAll(t, []domain.Command{
+		domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+		domain.PlaceBidCommand{Time: sampleStartsAt.Add(time.Second), Bid: unitBid(buyer1, 1, 10, 2)},
+		domain.PlaceBidCommand{Time: sampleStartsAt.Add(2 * time.Second), Bid: unitBid(buyer2, 2, 12, 4)},
+	})
+
+	t.Run("QuantityMustBeWithinUnits", func(t *testing.T) {
+		for _, quantity := range []int{-1, 6} {
+			_, _, err := domain.Handle(domain.PlaceBidCommand{Time: sampleStartsAt.Add(time.Hour), Bid: unitBid(buyer3, 3600, 11, quantity)}, repo)
+			expectDomainError(t, err, domain.ErrorInvalidQuantity)
+		}
+
+		english, _ := handleAll(t, []domain.Command{
+			domain.AddAuctionCommand{Time: sampleStartsAt, Auction: sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))},
+		})
+		_, _, err := domain.Handle(domain.PlaceBidCommand{Time: sampleStartsAt.Add(time.Hour), Bid: unitBid(buyer3, 3600, 11, 2)}, english)
+		expectDomainError(t, err, domain.ErrorInvalidQuantity)
+	})
+
+	t.Run("OneBidPerBidder", func(t *testing.T) {
+		_, _, err := domain.Handle(domain.PlaceBidCommand{Time: sampleStartsAt.Add(time.Hour), Bid: unitBid(buyer1, 3600, 11, 1)}, repo)
+		expectDomainError(t, err, domain.ErrorAlreadyPlacedBid)
+	})
+
+	t.Run("ClosingAnnouncesEveryWinner", func(t *testing.T) {
+		state := repo[sampleAuctionId].State.Increment(sampleEndsAt)
+		closing := domain.CloseAuction(sampleEndsAt, auction, state)
+		expected := []domain.Event{
+			domain.AuctionEndedEvent{Time: sampleEndsAt, AuctionId: sampleAuctionId},
+			domain.AuctionWonEvent{Time: sampleEndsAt, AuctionId: sampleAuctionId, Winner: buyer2.ID, Price: sek(10), Quantity: 4},
+			domain.AuctionWonEvent{Time: sampleEndsAt, AuctionId: sampleAuctionId, Winner: buyer1.ID, Price: sek(10), Quantity: 1},
+		}
+		if !reflect.DeepEqual(closing, expected) {
+			t.Errorf("Expected closing events %+v, got %+v", expected, closing)
+		}
+
+		data, err := json.Marshal(closing[1])
+		if err != nil {
+			t.Fatalf("Failed to marshal event: %v", err)
+		}
+		event, err := domain.UnmarshalEvent(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", data, err)
+		}
+		if event != closing[1] {
+			t.Errorf("Expected %+v, got %+v", closing[1], event)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// fixedPriceState is an in-house auction format where the first bid at the fixed price wins
+type fixedPriceState struct {
+	price  int64
+	expiry time.Time
+	winner *domain.Bid
+}
+
+func (s *fixedPriceState) Increment(now time.Time) domain.State { return s }
+
+func (s *fixedPriceState) AddBid(bid domain.Bid) (domain.State, error) {
+	if s.winner != nil || !bid.At.Before(s.expiry) {
+		return s, domain.NewAuctionHasEndedError(bid.ForAuction)
+	}
+	if bid.Amount.Value < s.price {
+		return s, domain.NewMustPlaceBidOverHighestError(domain.NewAmount(bid.Amount.Currency, s.price))
+	}
+	return &fixedPriceState{price: s.price, expiry: s.expiry, winner: &bid}, nil
+}
+
+func (s *fixedPriceState) GetBids() []domain.Bid {
+	if s.winner == nil {
+		return []domain.Bid{}
+	}
+	return []domain.Bid{*s.winner}
+}
+
+func (s *fixedPriceState) TryGetAmountAndWinner() (domain.Amount, domain.UserId, bool) {
+	if s.winner == nil {
+		return domain.Amount{}, "", false
+	}
+	return domain.NewAmount(s.winner.Amount.Currency, s.price), s.winner.Bidder.ID, true
+}
+
+func (s *fixedPriceState) HasEnded() bool { return s.winner != nil }
+
+func (s *fixedPriceState) RetractBids(bidder domain.UserId, at time.Time) (domain.State, error) {
+	if s.winner == nil || s.winner.Bidder.ID != bidder {
+		return s, domain.NewBidNotFoundError(bidder)
+	}
+	return s, domain.NewAuctionHasEndedError(s.winner.ForAuction)
+}
+
+var fixedPrice = domain.NewAuctionTypeEnum("FixedPrice")
+
+func init() {
+	domain.MustRegisterAuctionType(domain.AuctionTypeDefinition{
+		Name: "Fixed",
+		Type: fixedPrice,
+		ParseOptions: func(s string) (interface{}, error) {
+			parts := strings.Split(s, "|")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid fixed price options: %s", s)
+			}
+			return strconv.ParseInt(parts[1], 10, 64)
+		},
+		FormatOptions: func(options interface{}) string {
+			return fmt.Sprintf("Fixed|%d", options.(int64))
+		},
+		DefaultOptions: func() interface{} {
+			return int64(0)
+		},
+		CreateEmptyState: func(auction domain.Auction, options interface{}) domain.State {
+			return &fixedPriceState{price: options.(int64), expiry: auction.Expiry}
+		},
+		Validate: func(options interface{}, currency domain.Currency) error {
+			if price := domain.NewAmount(currency, options.(int64)); !price.IsPositive() {
+				return domain.NewNegativeAmountError("price", price)
+			}
+			return nil
+		},
+	})
+}
+
+// Test registering an auction type from outside the domain package
+func TestAuctionTypeRegistry(t *testing.T) {
+	t.Run("ParsesRegisteredType", func(t *testing.T) {
+		var auctionType domain.AuctionType
+		if err := json.Unmarshal([]byte(`"Fixed|0042"`), &auctionType); err != nil {
+			t.Fatalf("Failed to unmarshal auction type: %v", err)
+		}
+		if auctionType.Type != fixedPrice || auctionType.Options != "Fixed|42" {
+			t.Errorf("Expected FixedPrice with options Fixed|42, got %v with options %s", auctionType.Type, auctionType.Options)
+		}
+		if auctionType.Type.String() != "FixedPrice" {
+			t.Errorf("Expected enum to be named FixedPrice, got %s", auctionType.Type.String())
+		}
+	})
+
+	t.Run("CreatesRegisteredState", func(t *testing.T) {
+		auctionType, _ := domain.ParseAuctionType("Fixed|42")
+		state := sampleAuctionOfType(auctionType).CreateEmptyState()
+
+		_, err := state.AddBid(createBid1())
+		if err == nil {
+			t.Errorf("Expected bid below the fixed price to be rejected")
+		}
+
+		bid := createBid1()
+		bid.Amount = sek(42)
+		ended, err := state.AddBid(bid)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if amount, winner, found := ended.TryGetAmountAndWinner(); !found || amount != sek(42) || winner != buyer1.ID {
+			t.Errorf("Expected %s to win at 42, got %s at %v", buyer1.ID, winner, amount)
+		}
+	})
+
+	t.Run("ValidatesWithRegisteredHook", func(t *testing.T) {
+		auctionType, _ := domain.ParseAuctionType("Fixed|0")
+		err := sampleAuctionOfType(auctionType).ValidateOptions()
+		if domainErr, ok := err.(domain.DomainError); !ok || domainErr.Type != domain.ErrorNegativeAmount {
+			t.Errorf("Expected a fixed price of 0 to be rejected, got %v", err)
+		}
+
+		auctionType, _ = domain.ParseAuctionType("Fixed|42")
+		if err := sampleAuctionOfType(auctionType).ValidateOptions(); err != nil {
+			t.Errorf("Expected a fixed price of 42 to be valid, got %v", err)
+		}
+	})
+
+	t.Run("ValidationReturnsParseError", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.AuctionType{Type: fixedPrice, Options: "Fixed|lots"})
+		if err := auction.ValidateOptions(); err == nil {
+			t.Errorf("Expected options that cannot be parsed to be rejected")
+		}
+	})
+
+	t.Run("BuiltInTypesAreRegistered", func(t *testing.T) {
+		for _, name := range []string{"English", "Blind", "Vickrey", "Dutch"} {
+			if _, ok := domain.LookupAuctionType(name); !ok {
+				t.Errorf("Expected %s to be registered", name)
+			}
+		}
+	})
+
+	t.Run("RejectsDuplicateRegistration", func(t *testing.T) {
+		def, _ := domain.LookupAuctionType("English")
+		if err := domain.RegisterAuctionType(def); err == nil {
+			t.Errorf("Expected duplicate registration to fail")
+		}
+	})
+
+	t.Run("RejectsUnknownType", func(t *testing.T) {
+		var auctionType domain.AuctionType
+		if err := json.Unmarshal([]byte(`"Swedish|1"`), &auctionType); err == nil {
+			t.Errorf("Expected unknown auction type to be rejected")
+		}
+	})
+}
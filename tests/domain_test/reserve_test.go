@@ -0,0 +1,164 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func TestSealedBidReserve(t *testing.T) {
+	endedWith := func(t *testing.T, kind domain.SealedBidOptions, reserve int64, bids ...domain.Bid) domain.State {
+		t.Helper()
+		state := sampleAuctionOfType(domain.NewSingleSealedBidTypeWithReserve(kind, sek(reserve))).CreateEmptyState()
+		for _, bid := range bids {
+			next, err := state.AddBid(bid)
+			if err != nil {
+				t.Fatalf("Expected bid %v to be accepted, got %v", bid.Amount, err)
+			}
+			state = next
+		}
+		return state.Increment(sampleEndsAt)
+	}
+	expectWinner := func(t *testing.T, state domain.State, winner domain.UserId, price domain.Amount) {
+		t.Helper()
+		amount, bidder, found := state.TryGetAmountAndWinner()
+		if !found || bidder != winner || amount != price {
+			t.Errorf("Expected %s to win at %v, got %s at %v (found: %v)", winner, price, bidder, amount, found)
+		}
+	}
+
+	t.Run("ParsesAndFormatsReserve", func(t *testing.T) {
+		for s, expected := range map[string]domain.SealedBidAuctionOptions{
+			"Blind":             {Kind: domain.Blind},
+			"Vickrey|SEK100.00": {Kind: domain.Vickrey, ReservePrice: sek(10000)},
+		} {
+			options, err := domain.ParseSealedBidOptions(s)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", s, err)
+			}
+			if *options != expected || options.String() != s {
+				t.Errorf("Expected %s to parse to %+v, got %+v", s, expected, *options)
+			}
+		}
+		for _, s := range []string{"Dutch|SEK1", "Blind|SEK1|SEK2", "Vickrey|lots"} {
+			if _, err := domain.ParseSealedBidOptions(s); err == nil {
+				t.Errorf("Expected %s to be rejected", s)
+			}
+		}
+	})
+
+	t.Run("BlindBelowReserveIsUnsold", func(t *testing.T) {
+		state := endedWith(t, domain.Blind, 20, createBid1(), createBid2())
+		if _, _, found := state.TryGetAmountAndWinner(); found {
+			t.Errorf("Expected no winner below the reserve price")
+		}
+		if domain.ReserveMet(state) {
+			t.Errorf("Expected the reserve not to be met")
+		}
+	})
+
+	t.Run("BlindAtReserveIsSold", func(t *testing.T) {
+		expectWinner(t, endedWith(t, domain.Blind, 12, createBid1(), createBid2()), buyer2.ID, bidAmount2)
+	})
+
+	t.Run("VickreyPaysSecondHighestBidOverReserve", func(t *testing.T) {
+		expectWinner(t, endedWith(t, domain.Vickrey, 5, createBid1(), createBid2()), buyer2.ID, bidAmount1)
+	})
+
+	t.Run("VickreyPaysReserveOverSecondHighestBid", func(t *testing.T) {
+		expectWinner(t, endedWith(t, domain.Vickrey, 11, createBid1(), createBid2()), buyer2.ID, sek(11))
+	})
+
+	t.Run("SingleVickreyBidPaysReserve", func(t *testing.T) {
+		expectWinner(t, endedWith(t, domain.Vickrey, 5, createBid1()), buyer1.ID, sek(5))
+	})
+
+	t.Run("ReserveIsNotMetBeforeDisclosure", func(t *testing.T) {
+		state := sampleAuctionOfType(domain.NewSingleSealedBidTypeWithReserve(domain.Blind, sek(5))).CreateEmptyState()
+		state, _ = state.AddBid(createBid1())
+		if domain.ReserveMet(state) {
+			t.Errorf("Expected sealed bids not to meet the reserve before they are disclosed")
+		}
+		if reserve, ok := domain.ReservePrice(state); !ok || reserve != sek(5) {
+			t.Errorf("Expected a reserve price of %v, got %v", sek(5), reserve)
+		}
+	})
+
+	t.Run("ReserveMustNotBeNegative", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewSingleSealedBidTypeWithReserve(domain.Vickrey, sek(-1)))
+		_, _, err := domain.Handle(domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction}, domain.Repository{})
+		expectDomainError(t, err, domain.ErrorNegativeAmount)
+	})
+}
+
+func TestEnglishReserveMet(t *testing.T) {
+	auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{
+		ReservePrice: sek(11),
+		MinRaise:     sek(1),
+	}))
+	repo, _ := handleAll(t, []domain.Command{
+		domain.AddAuctionCommand{Time: sampleStartsAt, Auction: auction},
+		domain.PlaceBidCommand{Time: createBid1().At, Bid: createBid1()},
+	})
+	if domain.ReserveMet(repo[sampleAuctionId].State) {
+		t.Errorf("Expected a bid of %v not to meet the reserve of %v", bidAmount1, sek(11))
+	}
+
+	_, repo, err := domain.Handle(domain.PlaceBidCommand{Time: createBid2().At, Bid: createBid2()}, repo)
+	if err != nil {
+		t.Fatalf("Expected the bid to be accepted, got %v", err)
+	}
+	if !domain.ReserveMet(repo[sampleAuctionId].State) {
+		t.Errorf("Expected a bid of %v to meet the reserve of %v", bidAmount2, sek(11))
+	}
+
+	// The flag stays the same once the auction has ended
+	ended := repo[sampleAuctionId].State.Increment(sampleEndsAt.Add(time.Second))
+	if !domain.ReserveMet(ended) {
+		t.Errorf("Expected the reserve to be met once the auction has ended")
+	}
+}
+
+func TestPublicType(t *testing.T) {
+	for _, auctionType := range []domain.AuctionType{
+		domain.NewTimedAscendingType(domain.TimedAscendingOptions{ReservePrice: sek(500), MinRaise: sek(100)}),
+		domain.NewSingleSealedBidTypeWithReserve(domain.Vickrey, sek(500)),
+	} {
+		auction := sampleAuctionOfType(auctionType)
+		hidden := auction.PublicType()
+		hiddenAuction := auction
+		hiddenAuction.Type = hidden
+		if reserve, _ := domain.ReservePrice(hiddenAuction.CreateEmptyState()); reserve.Value != 0 || hidden.Type != auctionType.Type {
+			t.Errorf("Expected %s to be shown without its reserve price, got %s", auctionType.Options, hidden.Options)
+		}
+
+		auction.ShowReserve = true
+		if shown := auction.PublicType(); shown != auctionType {
+			t.Errorf("Expected %s to be shown as it is, got %s", auctionType.Options, shown.Options)
+		}
+	}
+}
+
+// Test that a leading bid at exactly the reserve price meets it in every auction type
+func TestBidAtReserveMeetsIt(t *testing.T) {
+	for name, auctionType := range map[string]domain.AuctionType{
+		"English":   domain.NewTimedAscendingType(domain.TimedAscendingOptions{ReservePrice: bidAmount1, MinRaise: sek(1)}),
+		"Vickrey":   domain.NewSingleSealedBidTypeWithReserve(domain.Vickrey, bidAmount1),
+		"MultiUnit": domain.NewMultiUnitType(domain.MultiUnitOptions{Units: 1, Pricing: domain.PayAsBid, ReservePrice: bidAmount1}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			state, err := sampleAuctionOfType(auctionType).CreateEmptyState().AddBid(createBid1())
+			if err != nil {
+				t.Fatalf("Expected the bid to be accepted, got %v", err)
+			}
+			ended := state.Increment(sampleEndsAt.Add(time.Second))
+			if !domain.ReserveMet(ended) {
+				t.Errorf("Expected a bid of %v to meet the reserve of %v", bidAmount1, bidAmount1)
+			}
+			if amount, winner, found := ended.TryGetAmountAndWinner(); !found || winner != buyer1.ID || amount != bidAmount1 {
+				t.Errorf("Expected %s to win at %v, got %s at %v (found: %v)", buyer1.ID, bidAmount1, winner, amount, found)
+			}
+		})
+	}
+}
@@ -36,7 +36,7 @@ func TestCommandAndEventSerialization(t *testing.T) {
 		ForAuction: auctionId,
 		Bidder:     buyer,
 		At:         now.Add(time.Hour),
-		Amount:     10,
+		Amount:     sek(10),
 	}
 
 	// Test AddAuctionCommand serialization
@@ -214,4 +214,72 @@ func TestCommandAndEventSerialization(t *testing.T) {
 			}
 		}
 	})
+
+	// Test closing events round-trip through UnmarshalEvent
+	t.Run("ClosingEventsSerialization", func(t *testing.T) {
+		events := []domain.Event{
+			domain.AuctionEndedEvent{Time: now, AuctionId: auctionId},
+			domain.AuctionWonEvent{Time: now, AuctionId: auctionId, Winner: buyer.ID, Price: sek(10)},
+			domain.AuctionUnsoldEvent{Time: now, AuctionId: auctionId, ReserveNotMet: true},
+		}
+
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("Failed to marshal %T: %v", event, err)
+			}
+
+			parsedEvent, err := domain.UnmarshalEvent(data)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal %T: %v", event, err)
+			}
+
+			if parsedEvent != event {
+				t.Errorf("Expected %+v after round-trip, got %+v", event, parsedEvent)
+			}
+		}
+	})
+	t.Run("CancellationSerialization", func(t *testing.T) {
+		support := domain.NewSupport("support1")
+
+		commands := []domain.Command{
+			domain.CancelAuctionCommand{Time: now, AuctionId: auctionId, CancelledBy: support, Reason: "Counterfeit"},
+			domain.RetractBidCommand{Time: now, AuctionId: auctionId, Bidder: buyer.ID, RetractedBy: support, Reason: "Mistake"},
+		}
+		for _, cmd := range commands {
+			data, err := json.Marshal(cmd)
+			if err != nil {
+				t.Fatalf("Failed to marshal %T: %v", cmd, err)
+			}
+
+			parsedCmd, err := domain.UnmarshalCommand(data)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal %T: %v", cmd, err)
+			}
+
+			if parsedCmd != cmd {
+				t.Errorf("Expected %+v after round-trip, got %+v", cmd, parsedCmd)
+			}
+		}
+
+		events := []domain.Event{
+			domain.AuctionCancelledEvent{Time: now, AuctionId: auctionId, CancelledBy: support, Reason: "Counterfeit"},
+			domain.BidRetractedEvent{Time: now, AuctionId: auctionId, Bidder: buyer.ID, RetractedBy: support, Reason: "Mistake"},
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				t.Fatalf("Failed to marshal %T: %v", event, err)
+			}
+
+			parsedEvent, err := domain.UnmarshalEvent(data)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal %T: %v", event, err)
+			}
+
+			if parsedEvent != event {
+				t.Errorf("Expected %+v after round-trip, got %+v", event, parsedEvent)
+			}
+		}
+	})
 }
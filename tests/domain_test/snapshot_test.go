@@ -0,0 +1,153 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+// snapshotEvents returns events covering every built-in state kind
+func snapshotEvents() []domain.Event {
+	english := sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{
+		ReservePrice: sek(5),
+		MinRaise:     sek(1),
+		TimeFrame:    0,
+	}))
+
+	blind := sampleAuctionOfType(domain.NewSingleSealedBidType(domain.Blind))
+	blind.ID = 2
+
+	vickrey := sampleAuctionOfType(domain.NewSingleSealedBidTypeWithReserve(domain.Vickrey, sek(5)))
+	vickrey.ID = 3
+
+	dutch := sampleAuctionOfType(domain.NewTimedDescendingType(domain.TimedDescendingOptions{
+		StartPrice:   sek(100),
+		Decrement:    sek(10),
+		TickInterval: time.Hour,
+		FloorPrice:   sek(10),
+	}))
+	dutch.ID = 4
+
+	ended := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+	ended.ID = 5
+
+	awaiting := sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()))
+	awaiting.ID = 6
+	awaiting.StartsAt = sampleEndsAt
+	awaiting.Expiry = sampleEndsAt.Add(24 * time.Hour)
+
+	multiUnit := sampleAuctionOfType(domain.NewMultiUnitType(domain.MultiUnitOptions{Units: 3, Pricing: domain.LowestAccepted}))
+	multiUnit.ID = 7
+
+	bidOn := func(id domain.AuctionId, bid domain.Bid) domain.Event {
+		bid.ForAuction = id
+		return domain.BidAcceptedEvent{Time: bid.At, Bid: bid}
+	}
+
+	proxy := domain.NewProxyBid(sampleAuctionId, buyer3, sampleStartsAt.Add(3*time.Second), sek(11), sek(20))
+
+	return []domain.Event{
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: english},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: blind},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: vickrey},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: dutch},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: ended},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: awaiting},
+		domain.AuctionAddedEvent{Time: sampleStartsAt, Auction: multiUnit},
+		bidOn(english.ID, createBid1()),
+		bidOn(english.ID, createBid2()),
+		bidOn(english.ID, proxy),
+		bidOn(blind.ID, createBid1()),
+		bidOn(blind.ID, createBid2()),
+		bidOn(vickrey.ID, createBid1()),
+		bidOn(dutch.ID, domain.Bid{Bidder: buyer1, At: sampleStartsAt.Add(2 * time.Hour), Amount: sek(80)}),
+		bidOn(ended.ID, createBid1()),
+		bidOn(multiUnit.ID, domain.Bid{Bidder: buyer1, At: sampleStartsAt.Add(time.Second), Amount: sek(10), Quantity: 2}),
+		domain.AuctionEndedEvent{Time: sampleEndsAt, AuctionId: ended.ID},
+	}
+}
+
+// restoreThroughJSON snapshots the repository and restores it from its JSON form
+func restoreThroughJSON(t *testing.T, repo domain.Repository) domain.Repository {
+	t.Helper()
+
+	snapshot, err := domain.NewRepositorySnapshot(repo)
+	if err != nil {
+		t.Fatalf("Failed to snapshot repository: %v", err)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to marshal snapshot: %v", err)
+	}
+
+	var decoded domain.RepositorySnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal snapshot: %v", err)
+	}
+
+	restored, err := decoded.Restore()
+	if err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	return restored
+}
+
+func TestRepositorySnapshot(t *testing.T) {
+	events := snapshotEvents()
+	replayed := domain.EventsToAuctionStates(events)
+
+	t.Run("Restores every state kind", func(t *testing.T) {
+		restored := restoreThroughJSON(t, replayed)
+		if !reflect.DeepEqual(restored, replayed) {
+			t.Errorf("Restored repository differs from replayed repository:\n%#v\n%#v", restored, replayed)
+		}
+	})
+
+	t.Run("Replaying the tail after a snapshot matches a full replay", func(t *testing.T) {
+		for offset := 0; offset <= len(events); offset++ {
+			restored := restoreThroughJSON(t, domain.EventsToAuctionStates(events[:offset]))
+			restored = domain.ApplyEvents(restored, events[offset:])
+			if !reflect.DeepEqual(restored, replayed) {
+				t.Errorf("Snapshot at offset %d followed by replay differs from full replay", offset)
+			}
+		}
+	})
+
+	t.Run("Restored states accept further bids", func(t *testing.T) {
+		restored := restoreThroughJSON(t, replayed)
+		bid := domain.Bid{
+			ForAuction: sampleAuctionId,
+			Bidder:     buyer2,
+			At:         sampleStartsAt.Add(4 * time.Second),
+			Amount:     sek(25),
+		}
+
+		fromSnapshot, err := restored[sampleAuctionId].State.AddBid(bid)
+		if err != nil {
+			t.Fatalf("Failed to add bid to restored state: %v", err)
+		}
+		fromReplay, err := replayed[sampleAuctionId].State.AddBid(bid)
+		if err != nil {
+			t.Fatalf("Failed to add bid to replayed state: %v", err)
+		}
+		if !reflect.DeepEqual(fromSnapshot, fromReplay) {
+			t.Errorf("States differ after bidding:\n%#v\n%#v", fromSnapshot, fromReplay)
+		}
+	})
+
+	t.Run("Fails for states that cannot be snapshotted", func(t *testing.T) {
+		repo := domain.Repository{
+			sampleAuctionId: {
+				Auction: sampleAuctionOfType(domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions())),
+				State:   &fixedPriceState{price: 10, expiry: sampleEndsAt},
+			},
+		}
+		if _, err := domain.NewRepositorySnapshot(repo); err == nil {
+			t.Error("Expected an error for a state without snapshot support")
+		}
+	})
+}
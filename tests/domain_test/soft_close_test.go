@@ -0,0 +1,143 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+)
+
+func TestSoftCloseOptions(t *testing.T) {
+	t.Run("ParsesAndFormatsPolicies", func(t *testing.T) {
+		for s, expected := range map[string]domain.SoftClosePolicy{
+			"English|SEK0.00|SEK1.00|60":                                  {},
+			"English|SEK0.00|SEK1.00|hard":                                {HardClose: true},
+			"English|SEK0.00|SEK1.00|60,within:300,by:120,max:5,upto:600": {Window: 5 * time.Minute, Extension: 2 * time.Minute, MaxExtensions: 5, MaxExtension: 10 * time.Minute},
+		} {
+			options, err := domain.ParseTimedAscendingOptions(s)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", s, err)
+			}
+			if options.SoftClose != expected {
+				t.Errorf("Expected %s to have the policy %+v, got %+v", s, expected, options.SoftClose)
+			}
+			if options.String() != s {
+				t.Errorf("Expected options to be formatted as %s, got %s", s, options)
+			}
+		}
+
+		for _, invalid := range []string{"English|0|1|-60", "English|0|1|60,within", "English|0|1|60,within:0", "English|0|1|60,until:300", "English|0|1|hard,max:1"} {
+			if _, err := domain.ParseTimedAscendingOptions(invalid); err == nil {
+				t.Errorf("Expected %s to be rejected", invalid)
+			}
+		}
+	})
+}
+
+func TestSoftClose(t *testing.T) {
+	// bidAt places a bid the given duration before the auction's original expiry
+	bidAt := func(t *testing.T, state domain.State, bidder domain.User, amount int64, beforeExpiry time.Duration) domain.State {
+		t.Helper()
+		next, err := state.AddBid(domain.NewBid(sampleAuctionId, bidder, sampleEndsAt.Add(-beforeExpiry), sek(amount)))
+		if err != nil {
+			t.Fatalf("Expected the bid of %v to be accepted, got %v", sek(amount), err)
+		}
+		return next
+	}
+	withPolicy := func(timeFrame time.Duration, policy domain.SoftClosePolicy) domain.State {
+		options := domain.TimedAscendingOptions{MinRaise: sek(1), TimeFrame: timeFrame, SoftClose: policy}
+		return sampleAuctionOfType(domain.NewTimedAscendingType(options)).CreateEmptyState()
+	}
+	expectExpiry := func(t *testing.T, state domain.State, expected time.Time) {
+		t.Helper()
+		if expiry, ok := domain.NextExpiry(state); !ok || !expiry.Equal(expected) {
+			t.Errorf("Expected the auction to end at %v, got %v", expected, expiry)
+		}
+	}
+
+	t.Run("ExtendsToTimeFrameAfterLateBids", func(t *testing.T) {
+		state := withPolicy(10*time.Minute, domain.SoftClosePolicy{})
+		state = bidAt(t, state, buyer1, 10, time.Hour)
+		expectExpiry(t, state, sampleEndsAt)
+
+		state = bidAt(t, state, buyer2, 12, time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(9*time.Minute))
+	})
+
+	t.Run("HardCloseNeverExtends", func(t *testing.T) {
+		state := withPolicy(0, domain.SoftClosePolicy{HardClose: true})
+		state = bidAt(t, state, buyer1, 10, time.Second)
+		expectExpiry(t, state, sampleEndsAt)
+		if !state.Increment(sampleEndsAt).HasEnded() {
+			t.Errorf("Expected the auction to end at its expiry")
+		}
+	})
+
+	t.Run("ExtendsOnlyWithinWindow", func(t *testing.T) {
+		state := withPolicy(10*time.Minute, domain.SoftClosePolicy{Window: 2 * time.Minute})
+		state = bidAt(t, state, buyer1, 10, 5*time.Minute)
+		expectExpiry(t, state, sampleEndsAt)
+
+		state = bidAt(t, state, buyer2, 12, time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(9*time.Minute))
+	})
+
+	t.Run("ExtendsByFixedAmount", func(t *testing.T) {
+		state := withPolicy(0, domain.SoftClosePolicy{Window: 5 * time.Minute, Extension: 2 * time.Minute})
+		state = bidAt(t, state, buyer1, 10, time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(2*time.Minute))
+
+		// A bid within the window of the extended expiry extends it again
+		state = bidAt(t, state, buyer2, 12, -time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(4*time.Minute))
+	})
+
+	t.Run("CapsNumberOfExtensions", func(t *testing.T) {
+		state := withPolicy(0, domain.SoftClosePolicy{Window: 5 * time.Minute, Extension: 2 * time.Minute, MaxExtensions: 1})
+		state = bidAt(t, state, buyer1, 10, time.Minute)
+		state = bidAt(t, state, buyer2, 12, -time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(2*time.Minute))
+	})
+
+	t.Run("CapsDurationOfExtensions", func(t *testing.T) {
+		state := withPolicy(0, domain.SoftClosePolicy{Window: 5 * time.Minute, Extension: 2 * time.Minute, MaxExtension: 3 * time.Minute})
+		state = bidAt(t, state, buyer1, 10, time.Minute)
+		state = bidAt(t, state, buyer2, 12, -time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(3*time.Minute))
+
+		// Once the cap is reached, bids no longer extend the auction
+		state = bidAt(t, state, buyer1, 14, -2*time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(3*time.Minute))
+		if !state.Increment(sampleEndsAt.Add(3 * time.Minute)).HasEnded() {
+			t.Errorf("Expected the auction to end at the capped expiry")
+		}
+	})
+
+	t.Run("LeaderRaisingTheirMaximumDoesNotExtend", func(t *testing.T) {
+		state := withPolicy(0, domain.SoftClosePolicy{Window: 5 * time.Minute, Extension: 2 * time.Minute, MaxExtensions: 1})
+		state = bidAt(t, state, buyer1, 10, time.Hour)
+		state = bidAt(t, state, buyer1, 20, time.Minute)
+		expectExpiry(t, state, sampleEndsAt)
+
+		// The raise did not use up the only extension, so a challenger still gets it
+		state = bidAt(t, state, buyer2, 12, time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(2*time.Minute))
+	})
+
+	t.Run("SnapshotKeepsExtensions", func(t *testing.T) {
+		auction := sampleAuctionOfType(domain.NewTimedAscendingType(domain.TimedAscendingOptions{
+			MinRaise:  sek(1),
+			SoftClose: domain.SoftClosePolicy{Window: 5 * time.Minute, Extension: 2 * time.Minute, MaxExtensions: 1},
+		}))
+		state := bidAt(t, auction.CreateEmptyState(), buyer1, 10, time.Minute)
+		repo := domain.Repository{sampleAuctionId: {Auction: auction, State: state}}
+
+		restored := restoreThroughJSON(t, repo)
+		if !reflect.DeepEqual(restored, repo) {
+			t.Fatalf("Restored repository differs:\n%#v\n%#v", restored, repo)
+		}
+		state = bidAt(t, restored[sampleAuctionId].State, buyer2, 12, -time.Minute)
+		expectExpiry(t, state, sampleEndsAt.Add(2*time.Minute))
+	})
+}
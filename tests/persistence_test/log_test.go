@@ -0,0 +1,230 @@
+package persistence_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+// writeLog writes events to a fresh log and returns its bytes
+func writeLog(t *testing.T, events []domain.Event) []byte {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := persistence.WriteEvents(path, events); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	return data
+}
+
+// quarantined returns the quarantine files next to the log at path
+func quarantined(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil {
+		t.Fatalf("Failed to list quarantine files: %v", err)
+	}
+	return matches
+}
+
+func TestAppendLogTornWrites(t *testing.T) {
+	_, events := sampleLogs()
+	full := writeLog(t, events)
+
+	// Offsets just past the closing brace of each record
+	var recordEnds []int
+	for i, b := range full {
+		if b == '\n' {
+			recordEnds = append(recordEnds, i)
+		}
+	}
+	if len(recordEnds) != len(events) {
+		t.Fatalf("Expected %d records, got %d", len(events), len(recordEnds))
+	}
+
+	extra := domain.AuctionEndedEvent{Time: events[0].GetTime(), AuctionId: 2}
+
+	for size := 0; size <= len(full); size++ {
+		complete := 0
+		for _, end := range recordEnds {
+			if end <= size {
+				complete++
+			}
+		}
+		atBoundary := size == 0 || (complete > 0 && (size == recordEnds[complete-1] || size == recordEnds[complete-1]+1))
+
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := os.WriteFile(path, full[:size], 0644); err != nil {
+			t.Fatalf("Failed to write partial log: %v", err)
+		}
+
+		// Readers ignore the torn record
+		read, err := persistence.ReadEvents(path)
+		if err != nil {
+			t.Fatalf("Offset %d: failed to read events: %v", size, err)
+		}
+		if !reflect.DeepEqual(read, events[:complete]) {
+			t.Fatalf("Offset %d: expected %d events, got %d", size, complete, len(read))
+		}
+
+		// Opening the log for appending quarantines the torn record
+		appendLog, err := persistence.OpenAppendLog(path, persistence.LogOptions{})
+		if err != nil {
+			t.Fatalf("Offset %d: failed to open log: %v", size, err)
+		}
+		if appendLog.Seq() != int64(complete) {
+			t.Errorf("Offset %d: expected sequence %d, got %d", size, complete, appendLog.Seq())
+		}
+
+		data, _ := json.Marshal(extra)
+		if err := appendLog.Append([][]byte{data}); err != nil {
+			t.Fatalf("Offset %d: failed to append: %v", size, err)
+		}
+		appendLog.Close()
+
+		read, err = persistence.ReadEvents(path)
+		if err != nil {
+			t.Fatalf("Offset %d: failed to read events after recovery: %v", size, err)
+		}
+		expected := append(append([]domain.Event{}, events[:complete]...), extra)
+		if !reflect.DeepEqual(read, expected) {
+			t.Fatalf("Offset %d: expected %d events after recovery, got %d", size, len(expected), len(read))
+		}
+
+		if files := quarantined(t, path); atBoundary && len(files) != 0 {
+			t.Errorf("Offset %d: expected nothing to be quarantined, got %v", size, files)
+		} else if !atBoundary && len(files) != 1 {
+			t.Errorf("Offset %d: expected the torn record to be quarantined", size)
+		}
+	}
+}
+
+func TestAppendLogCorruption(t *testing.T) {
+	_, events := sampleLogs()
+
+	t.Run("Corrupt last record is quarantined", func(t *testing.T) {
+		data := writeLog(t, events)
+		last := bytes.LastIndex(data[:len(data)-1], []byte("\n")) + 1
+		data[last+len(data[last:])/2] ^= 0x01
+
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		os.WriteFile(path, data, 0644)
+
+		read, err := persistence.ReadEvents(path)
+		if err != nil {
+			t.Fatalf("Failed to read events: %v", err)
+		}
+		if len(read) != len(events)-1 {
+			t.Errorf("Expected %d events, got %d", len(events)-1, len(read))
+		}
+
+		appendLog, err := persistence.OpenAppendLog(path, persistence.LogOptions{})
+		if err != nil {
+			t.Fatalf("Failed to open log: %v", err)
+		}
+		appendLog.Close()
+
+		files := quarantined(t, path)
+		if len(files) != 1 {
+			t.Fatalf("Expected a quarantine file, got %v", files)
+		}
+		tail, _ := os.ReadFile(files[0])
+		if !bytes.Equal(tail, data[last:]) {
+			t.Errorf("Expected the quarantine file to hold the corrupt record")
+		}
+	})
+
+	t.Run("Corrupt record before the tail fails", func(t *testing.T) {
+		data := writeLog(t, events)
+		first := bytes.IndexByte(data, '\n')
+		data[first+20] ^= 0x01
+
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		os.WriteFile(path, data, 0644)
+
+		_, err := persistence.ReadEvents(path)
+		var corrupt *persistence.CorruptLogError
+		if !errors.As(err, &corrupt) {
+			t.Fatalf("Expected a CorruptLogError, got %v", err)
+		}
+		if corrupt.Line != 2 || corrupt.Offset != int64(first+1) {
+			t.Errorf("Expected corruption at line 2, offset %d, got line %d, offset %d", first+1, corrupt.Line, corrupt.Offset)
+		}
+
+		if _, err := persistence.OpenAppendLog(path, persistence.LogOptions{}); !errors.As(err, &corrupt) {
+			t.Errorf("Expected opening the log to fail, got %v", err)
+		}
+	})
+}
+
+func TestAppendLogReadsLegacyFormat(t *testing.T) {
+	_, events := sampleLogs()
+
+	// Logs written before checksums joined bare records with newlines, without a trailing one
+	lines := make([]string, len(events))
+	for i, event := range events {
+		data, _ := json.Marshal(event)
+		lines[i] = string(data)
+	}
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	os.WriteFile(path, []byte(strings.Join(lines[:2], "\n")), 0644)
+
+	if err := persistence.WriteEvents(path, events[2:]); err != nil {
+		t.Fatalf("Failed to append to legacy log: %v", err)
+	}
+
+	read, err := persistence.ReadEvents(path)
+	if err != nil {
+		t.Fatalf("Failed to read events: %v", err)
+	}
+	if !reflect.DeepEqual(read, events) {
+		t.Errorf("Expected %d events, got %d", len(events), len(read))
+	}
+}
+
+func TestAppendLogGroupCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	appendLog, err := persistence.OpenAppendLog(path, persistence.LogOptions{GroupCommitWindow: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer appendLog.Close()
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event := domain.AuctionEndedEvent{Time: time.Unix(int64(i), 0).UTC(), AuctionId: domain.AuctionId(i)}
+			data, _ := json.Marshal(event)
+			if err := appendLog.Append([][]byte{data}); err != nil {
+				t.Errorf("Failed to append: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	read, err := persistence.ReadEvents(path)
+	if err != nil {
+		t.Fatalf("Failed to read events: %v", err)
+	}
+	if len(read) != writers || appendLog.Seq() != writers {
+		t.Errorf("Expected %d events, got %d (sequence %d)", writers, len(read), appendLog.Seq())
+	}
+}
@@ -0,0 +1,178 @@
+package persistence_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+// serve handles commands as the server does, logging every command and the events
+// of accepted commands, then closes the auctions at the given time
+func serve(t *testing.T, commands []domain.Command, closeAt time.Time) []domain.Event {
+	t.Helper()
+
+	repo := make(domain.Repository)
+	var events []domain.Event
+	for _, cmd := range commands {
+		event, newRepo, err := domain.Handle(cmd, repo)
+		if err != nil {
+			continue
+		}
+		repo = newRepo
+		events = append(events, event)
+	}
+
+	for _, auction := range domain.GetAuctions(repo) {
+		state := repo[auction.ID].State.Increment(closeAt)
+		if state.HasEnded() {
+			events = append(events, domain.CloseAuction(closeAt, auction, state)...)
+		}
+	}
+	return events
+}
+
+// replayCommands returns the sample commands with a rejected bid and a second auction
+func replayCommands() []domain.Command {
+	commands, _ := sampleLogs()
+	added := commands[0].(domain.AddAuctionCommand)
+	bid := commands[1].(domain.PlaceBidCommand)
+
+	second := added.Auction
+	second.ID = 2
+	buyer := domain.NewBuyerOrSeller("Other_Buyer", "Other")
+
+	return []domain.Command{
+		added,
+		domain.AddAuctionCommand{Time: added.Time, Auction: second},
+		// Sellers cannot bid on their own auction
+		domain.PlaceBidCommand{Time: bid.Time, Bid: domain.NewBid(1, added.Auction.Seller, bid.Time, domain.NewAmount(domain.SEK, 20))},
+		bid,
+		domain.PlaceBidCommand{Time: bid.Time, Bid: domain.NewBid(2, buyer, bid.Time, domain.NewAmount(domain.SEK, 15))},
+	}
+}
+
+// checkLogs writes the logs to a JSONL store and checks them
+func checkLogs(t *testing.T, commands []domain.Command, events []domain.Event) persistence.ReplayReport {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := persistence.OpenJSONLStore(filepath.Join(dir, "commands.jsonl"), filepath.Join(dir, "events.jsonl"), persistence.LogOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open logs: %v", err)
+	}
+	defer store.Close()
+	if err := store.WriteCommands(commands); err != nil {
+		t.Fatalf("Failed to write commands: %v", err)
+	}
+	if err := store.WriteEvents(events); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+
+	report, err := persistence.CheckReplay(store.ScanCommands, store.ScanEvents)
+	if err != nil {
+		t.Fatalf("Failed to check replay: %v", err)
+	}
+	return report
+}
+
+func TestCheckReplay(t *testing.T) {
+	commands := replayCommands()
+	closeAt := commands[0].(domain.AddAuctionCommand).Auction.Expiry.Add(time.Second)
+	events := serve(t, commands, closeAt)
+
+	t.Run("Matching logs have no divergences", func(t *testing.T) {
+		report := checkLogs(t, commands, events)
+		if report.Commands != len(commands) || report.Events != len(events) {
+			t.Errorf("Expected %d commands and %d events, got %+v", len(commands), len(events), report)
+		}
+		if len(report.Divergences) != 0 {
+			t.Errorf("Expected no divergences, got %v", report.Divergences)
+		}
+	})
+
+	t.Run("Events of different auctions may be logged out of command order", func(t *testing.T) {
+		reordered := append([]domain.Event{}, events...)
+		// Accepted bids on auctions 1 and 2
+		reordered[2], reordered[3] = reordered[3], reordered[2]
+
+		report := checkLogs(t, commands, reordered)
+		if len(report.Divergences) != 0 {
+			t.Errorf("Expected no divergences, got %v", report.Divergences)
+		}
+	})
+
+	t.Run("Compares events stored with plain amounts in the auction's currency", func(t *testing.T) {
+		added := commands[0].(domain.AddAuctionCommand)
+		bid := commands[3].(domain.PlaceBidCommand)
+		bid.Bid.Amount = domain.NewAmount(domain.SEK, 1500)
+		wholeUnits := []domain.Command{added, bid}
+
+		// Before amounts carried a currency, the log held "amount":15 rather than "amount":"SEK15.00"
+		var stored []domain.Event
+		for _, event := range serve(t, wholeUnits, closeAt) {
+			switch e := event.(type) {
+			case domain.BidAcceptedEvent:
+				e.Bid.Amount = domain.Amount{Value: 15}
+				event = e
+			case domain.AuctionWonEvent:
+				e.Price = domain.Amount{Value: 15}
+				event = e
+			}
+			stored = append(stored, event)
+		}
+
+		report := checkLogs(t, wholeUnits, stored)
+		if len(report.Divergences) != 0 {
+			t.Errorf("Expected no divergences, got %v", report.Divergences)
+		}
+	})
+
+	t.Run("Reports a changed outcome", func(t *testing.T) {
+		changed := append([]domain.Event{}, events...)
+		var seq int64
+		for i, event := range changed {
+			if won, ok := event.(domain.AuctionWonEvent); ok && won.AuctionId == 1 {
+				won.Price = domain.NewAmount(domain.SEK, 5)
+				changed[i] = won
+				seq = int64(i + 1)
+			}
+		}
+
+		report := checkLogs(t, commands, changed)
+		if len(report.Divergences) != 1 {
+			t.Fatalf("Expected 1 divergence, got %v", report.Divergences)
+		}
+		divergence := report.Divergences[0]
+		if divergence.AuctionId != 1 || divergence.Seq != seq || divergence.Expected == "" || divergence.Actual == "" {
+			t.Errorf("Unexpected divergence: %+v", divergence)
+		}
+	})
+
+	t.Run("Reports an accepted command without an event", func(t *testing.T) {
+		missing := append([]domain.Event{}, events[:3]...)
+		missing = append(missing, events[4:]...)
+
+		report := checkLogs(t, commands, missing)
+		if len(report.Divergences) == 0 {
+			t.Fatal("Expected divergences")
+		}
+		if report.Divergences[0].Seq != 0 || report.Divergences[0].Actual != "" {
+			t.Errorf("Expected a missing event, got %+v", report.Divergences[0])
+		}
+	})
+
+	t.Run("Reports an event without an accepted command", func(t *testing.T) {
+		report := checkLogs(t, commands[:3], events[:2])
+		if len(report.Divergences) != 0 {
+			t.Fatalf("Expected no divergences, got %v", report.Divergences)
+		}
+
+		report = checkLogs(t, commands[:2], events[:3])
+		if len(report.Divergences) != 1 || report.Divergences[0].Expected != "" {
+			t.Errorf("Expected an unexpected event, got %v", report.Divergences)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package persistence_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+func TestScanEvents(t *testing.T) {
+	_, events := sampleLogs()
+
+	t.Run("Streams events in order", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := persistence.WriteEvents(path, events); err != nil {
+			t.Fatalf("Failed to write events: %v", err)
+		}
+
+		var scanned []domain.Event
+		err := persistence.ScanEvents(path, persistence.LogOptions{}, func(_ int64, event domain.Event) error {
+			scanned = append(scanned, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to scan events: %v", err)
+		}
+		if !reflect.DeepEqual(scanned, events) {
+			t.Errorf("Expected %v, got %v", events, scanned)
+		}
+	})
+
+	t.Run("Stops at the first callback error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := persistence.WriteEvents(path, events); err != nil {
+			t.Fatalf("Failed to write events: %v", err)
+		}
+
+		stop := errors.New("stop")
+		count := 0
+		err := persistence.ScanEvents(path, persistence.LogOptions{}, func(int64, domain.Event) error {
+			count++
+			if count == 2 {
+				return stop
+			}
+			return nil
+		})
+		if err != stop {
+			t.Errorf("Expected the callback error, got %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected scanning to stop after 2 events, got %d", count)
+		}
+	})
+
+	t.Run("Folds incrementally to the same repository", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := persistence.WriteEvents(path, events); err != nil {
+			t.Fatalf("Failed to write events: %v", err)
+		}
+
+		repo := make(domain.Repository)
+		closed := make(map[domain.AuctionId]bool)
+		err := persistence.ScanEvents(path, persistence.LogOptions{}, func(_ int64, event domain.Event) error {
+			domain.ApplyEvent(repo, event)
+			domain.MarkClosed(closed, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to scan events: %v", err)
+		}
+		if !reflect.DeepEqual(repo, domain.EventsToAuctionStates(events)) {
+			t.Errorf("Incremental fold differs from EventsToAuctionStates")
+		}
+		if !reflect.DeepEqual(closed, domain.ClosedAuctions(events)) {
+			t.Errorf("Expected closed auctions %v, got %v", domain.ClosedAuctions(events), closed)
+		}
+	})
+
+	t.Run("Reports oversized records with their position", func(t *testing.T) {
+		// A second auction with a long title makes the second record the largest
+		large := events[0].(domain.AuctionAddedEvent)
+		large.Auction.ID = 2
+		large.Auction.Title = strings.Repeat("x", 4096)
+		withLarge := append([]domain.Event{events[0], large}, events[1:]...)
+
+		full := writeLog(t, withLarge)
+		lines := strings.SplitAfter(string(full), "\n")
+		limit := len(lines[0]) + 64
+
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := os.WriteFile(path, full, 0644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+
+		err := persistence.ScanEvents(path, persistence.LogOptions{MaxRecordSize: limit}, func(int64, domain.Event) error { return nil })
+		var corrupt *persistence.CorruptLogError
+		if !errors.As(err, &corrupt) {
+			t.Fatalf("Expected a CorruptLogError, got %v", err)
+		}
+		if corrupt.Line != 2 || corrupt.Offset != int64(len(lines[0])) {
+			t.Errorf("Expected line 2 at offset %d, got line %d at offset %d", len(lines[0]), corrupt.Line, corrupt.Offset)
+		}
+	})
+
+	t.Run("Reports undecodable records with their position", func(t *testing.T) {
+		lines := strings.SplitAfter(string(writeLog(t, events)), "\n")
+		lines[2] = "{\"$type\":\"Unknown\"}\n"
+
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+
+		err := persistence.ScanEvents(path, persistence.LogOptions{}, func(int64, domain.Event) error { return nil })
+		var corrupt *persistence.CorruptLogError
+		if !errors.As(err, &corrupt) {
+			t.Fatalf("Expected a CorruptLogError, got %v", err)
+		}
+		expectedOffset := int64(len(lines[0]) + len(lines[1]))
+		if corrupt.Line != 3 || corrupt.Offset != expectedOffset {
+			t.Errorf("Expected line 3 at offset %d, got line %d at offset %d", expectedOffset, corrupt.Line, corrupt.Offset)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package persistence_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+// openSegmented opens a segmented JSONL store with its event log in dir
+func openSegmented(t *testing.T, dir string, segments persistence.SegmentOptions) *persistence.JSONLStore {
+	t.Helper()
+
+	store, err := persistence.OpenSegmentedJSONLStore(filepath.Join(dir, "commands.jsonl"), filepath.Join(dir, "events"), "", persistence.LogOptions{}, segments)
+	if err != nil {
+		t.Fatalf("Failed to open segmented store: %v", err)
+	}
+	return store
+}
+
+// writeEach writes events one append at a time
+func writeEach(t *testing.T, store persistence.Store, events []domain.Event) {
+	t.Helper()
+
+	for _, event := range events {
+		if err := store.WriteEvents([]domain.Event{event}); err != nil {
+			t.Fatalf("Failed to write event: %v", err)
+		}
+	}
+}
+
+// scanWithSeqs returns the events of a store and their sequence numbers
+func scanWithSeqs(t *testing.T, store persistence.Store) ([]int64, []domain.Event) {
+	t.Helper()
+
+	var seqs []int64
+	var events []domain.Event
+	err := store.ScanEvents(func(seq int64, event domain.Event) error {
+		seqs = append(seqs, seq)
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan events: %v", err)
+	}
+	return seqs, events
+}
+
+// segmentEvents returns the sample events followed by a second auction that is still running
+func segmentEvents() []domain.Event {
+	_, events := sampleLogs()
+	added := events[0].(domain.AuctionAddedEvent)
+	running := added.Auction
+	running.ID = 2
+	running.Expiry = running.Expiry.Add(365 * 24 * time.Hour)
+	return append(events, domain.AuctionAddedEvent{Time: added.Time, Auction: running})
+}
+
+func TestSegmentedLog(t *testing.T) {
+	events := segmentEvents()
+
+	t.Run("Rolls over by size and reads across segments", func(t *testing.T) {
+		dir := t.TempDir()
+		store := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+		writeEach(t, store, events)
+		store.Close()
+
+		index, err := persistence.ReadSegmentIndex(filepath.Join(dir, "events"))
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		if len(index) != len(events) {
+			t.Fatalf("Expected %d segments, got %d", len(events), len(index))
+		}
+		for i, segment := range index[:len(index)-1] {
+			if !segment.Sealed || segment.FirstSeq != int64(i+1) || segment.LastSeq != int64(i+1) {
+				t.Errorf("Unexpected segment %d: %+v", i, segment)
+			}
+		}
+
+		reopened := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+		defer reopened.Close()
+		seqs, read := scanWithSeqs(t, reopened)
+		if !reflect.DeepEqual(read, events) {
+			t.Errorf("Expected %v, got %v", events, read)
+		}
+		if !reflect.DeepEqual(seqs, []int64{1, 2, 3, 4, 5}) {
+			t.Errorf("Expected consecutive sequence numbers, got %v", seqs)
+		}
+
+		// Appends after reopening continue the numbering
+		writeEach(t, reopened, events[:1])
+		seqs, _ = scanWithSeqs(t, reopened)
+		if seqs[len(seqs)-1] != 6 {
+			t.Errorf("Expected the next event to be 6, got %d", seqs[len(seqs)-1])
+		}
+	})
+
+	t.Run("Rolls over by age", func(t *testing.T) {
+		dir := t.TempDir()
+		store := openSegmented(t, dir, persistence.SegmentOptions{MaxAge: time.Nanosecond})
+		defer store.Close()
+		writeEach(t, store, events[:3])
+
+		index, err := persistence.ReadSegmentIndex(filepath.Join(dir, "events"))
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		if len(index) != 3 {
+			t.Errorf("Expected 3 segments, got %d", len(index))
+		}
+	})
+
+	t.Run("Keeps appending to one segment below the limits", func(t *testing.T) {
+		dir := t.TempDir()
+		store := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1 << 20, MaxAge: time.Hour})
+		defer store.Close()
+		writeEach(t, store, events)
+
+		index, err := persistence.ReadSegmentIndex(filepath.Join(dir, "events"))
+		if err != nil {
+			t.Fatalf("Failed to read index: %v", err)
+		}
+		if len(index) != 1 || index[0].Sealed {
+			t.Errorf("Expected a single active segment, got %+v", index)
+		}
+	})
+
+	t.Run("Scans after a position in a segment rewritten by compaction", func(t *testing.T) {
+		dir := t.TempDir()
+		store := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+		writeEach(t, store, events[:3])
+		pos, err := store.EventsPosition()
+		if err != nil {
+			t.Fatalf("Failed to get the event log position: %v", err)
+		}
+		writeEach(t, store, events[3:])
+		store.Close()
+
+		if _, err := persistence.CompactSegments(filepath.Join(dir, "events"), persistence.LogOptions{}); err != nil {
+			t.Fatalf("Failed to compact: %v", err)
+		}
+
+		reopened := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+		defer reopened.Close()
+		var seqs []int64
+		err = reopened.ScanEventsAfter(pos, func(seq int64, event domain.Event) error {
+			seqs = append(seqs, seq)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to scan events: %v", err)
+		}
+		// The archive of auction 1 replaces its last event, the added event of auction 2 follows
+		if !reflect.DeepEqual(seqs, []int64{4, 5}) {
+			t.Errorf("Expected events 4 and 5, got %v", seqs)
+		}
+	})
+
+	t.Run("Adopts an existing event log as the first segment", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy := filepath.Join(dir, "events.jsonl")
+		if err := persistence.WriteEvents(legacy, events[:2]); err != nil {
+			t.Fatalf("Failed to write events: %v", err)
+		}
+
+		store, err := persistence.OpenSegmentedJSONLStore(filepath.Join(dir, "commands.jsonl"), filepath.Join(dir, "events"), legacy, persistence.LogOptions{}, persistence.SegmentOptions{})
+		if err != nil {
+			t.Fatalf("Failed to open segmented store: %v", err)
+		}
+		defer store.Close()
+		if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+			t.Errorf("Expected the event log to be moved, got %v", err)
+		}
+
+		writeEach(t, store, events[2:])
+		seqs, read := scanWithSeqs(t, store)
+		if !reflect.DeepEqual(read, events) {
+			t.Errorf("Expected %v, got %v", events, read)
+		}
+		if !reflect.DeepEqual(seqs, []int64{1, 2, 3, 4, 5}) {
+			t.Errorf("Expected consecutive sequence numbers, got %v", seqs)
+		}
+	})
+}
+
+func TestCompactSegments(t *testing.T) {
+	events := segmentEvents()
+	dir := t.TempDir()
+	eventsDir := filepath.Join(dir, "events")
+
+	store := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+	writeEach(t, store, events)
+	store.Close()
+
+	result, err := persistence.CompactSegments(eventsDir, persistence.LogOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	// Auction 1 is archived; auction 2 is still running and only has an event in the active segment
+	if result.Auctions != 1 || result.EventsBefore != 5 || result.EventsAfter != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	reopened := openSegmented(t, dir, persistence.SegmentOptions{MaxBytes: 1})
+	defer reopened.Close()
+	seqs, compacted := scanWithSeqs(t, reopened)
+
+	t.Run("Keeps replay results identical", func(t *testing.T) {
+		if !reflect.DeepEqual(domain.EventsToAuctionStates(compacted), domain.EventsToAuctionStates(events)) {
+			t.Errorf("Compacted log replays to a different repository")
+		}
+		if !reflect.DeepEqual(domain.ClosedAuctions(compacted), domain.ClosedAuctions(events)) {
+			t.Errorf("Expected closed auctions %v, got %v", domain.ClosedAuctions(events), domain.ClosedAuctions(compacted))
+		}
+	})
+
+	t.Run("Keeps sequence numbers", func(t *testing.T) {
+		if !reflect.DeepEqual(seqs, []int64{4, 5}) {
+			t.Errorf("Expected the archive at the last event of the auction, got %v", seqs)
+		}
+		archived, ok := compacted[0].(domain.AuctionArchivedEvent)
+		if !ok || archived.Auction.ID != 1 || archived.Events != 4 {
+			t.Errorf("Expected an archive of the 4 events of auction 1, got %v", compacted[0])
+		}
+
+		writeEach(t, reopened, events[:1])
+		seqs, _ = scanWithSeqs(t, reopened)
+		if seqs[len(seqs)-1] != 6 {
+			t.Errorf("Expected the next event to be 6, got %d", seqs[len(seqs)-1])
+		}
+	})
+
+	t.Run("Is idempotent", func(t *testing.T) {
+		again, err := persistence.CompactSegments(eventsDir, persistence.LogOptions{})
+		if err != nil {
+			t.Fatalf("Failed to compact: %v", err)
+		}
+		if again.Auctions != 0 {
+			t.Errorf("Expected nothing left to archive, got %+v", again)
+		}
+	})
+}
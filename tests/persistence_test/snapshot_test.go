@@ -0,0 +1,175 @@
+package persistence_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+func sampleRepository(auctions int) domain.Repository {
+	startsAt := time.Date(2016, 1, 1, 8, 28, 0, 0, time.UTC)
+	seller := domain.NewBuyerOrSeller("Sample_Seller", "Seller")
+
+	var events []domain.Event
+	for i := 1; i <= auctions; i++ {
+		auction := domain.NewAuction(
+			domain.AuctionId(i),
+			startsAt,
+			"auction",
+			startsAt.Add(24*time.Hour),
+			seller,
+			domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()),
+			domain.SEK,
+		)
+		events = append(events, domain.AuctionAddedEvent{Time: startsAt, Auction: auction})
+	}
+	return domain.EventsToAuctionStates(events)
+}
+
+// sampleSnapshot returns a snapshot of sampleRepository covering the first offset events
+func sampleSnapshot(offset int64, auctions int) persistence.Snapshot {
+	return persistence.Snapshot{
+		Repo:     sampleRepository(auctions),
+		Position: persistence.LogPosition{Seq: offset, Offset: offset * 100},
+		Closed:   map[domain.AuctionId]bool{},
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	t.Run("Reads the newest snapshot", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(1, 1)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(2, 2)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+
+		snapshot, err := persistence.ReadLatestSnapshot(dir, 2)
+		if err != nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		if snapshot == nil || snapshot.Position.Seq != 2 {
+			t.Fatalf("Expected the snapshot at offset 2, got %+v", snapshot)
+		}
+		if !reflect.DeepEqual(snapshot.Repo, sampleRepository(2)) {
+			t.Errorf("Restored repository differs from the written one")
+		}
+	})
+
+	t.Run("Restores the log position and the closed auctions", func(t *testing.T) {
+		dir := t.TempDir()
+		written := sampleSnapshot(3, 2)
+		written.Position.Segment = "segment-00000000000000000001.jsonl"
+		written.Closed[2] = true
+		if err := persistence.WriteSnapshot(dir, written); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+
+		snapshot, err := persistence.ReadLatestSnapshot(dir, 3)
+		if err != nil || snapshot == nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		if snapshot.Position != written.Position {
+			t.Errorf("Expected position %+v, got %+v", written.Position, snapshot.Position)
+		}
+		if !reflect.DeepEqual(snapshot.Closed, written.Closed) {
+			t.Errorf("Expected closed auctions %v, got %v", written.Closed, snapshot.Closed)
+		}
+	})
+
+	t.Run("Returns nothing when there are no snapshots", func(t *testing.T) {
+		snapshot, err := persistence.ReadLatestSnapshot(filepath.Join(t.TempDir(), "missing"), 10)
+		if err != nil {
+			t.Fatalf("Failed to read snapshots: %v", err)
+		}
+		if snapshot != nil {
+			t.Errorf("Expected no snapshot, got offset %d", snapshot.Position.Seq)
+		}
+	})
+
+	t.Run("Skips snapshots ahead of the event log", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(1, 1)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(5, 2)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+
+		snapshot, err := persistence.ReadLatestSnapshot(dir, 3)
+		if err != nil || snapshot == nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		if snapshot.Position.Seq != 1 {
+			t.Errorf("Expected offset 1, got %d", snapshot.Position.Seq)
+		}
+	})
+
+	t.Run("Falls back when the newest snapshot is corrupt", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(1, 1)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+		if err := persistence.WriteSnapshot(dir, sampleSnapshot(2, 2)); err != nil {
+			t.Fatalf("Failed to write snapshot: %v", err)
+		}
+
+		// Flip a byte inside the auctions of the newest snapshot
+		path := filepath.Join(dir, "snapshot-00000000000000000002.json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read snapshot file: %v", err)
+		}
+		data[len(data)-20] ^= 0x01
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write snapshot file: %v", err)
+		}
+
+		snapshot, err := persistence.ReadLatestSnapshot(dir, 2)
+		if err != nil || snapshot == nil {
+			t.Fatalf("Failed to read snapshot: %v", err)
+		}
+		if snapshot.Position.Seq != 1 {
+			t.Errorf("Expected fallback to offset 1, got %d", snapshot.Position.Seq)
+		}
+	})
+
+	t.Run("Ignores snapshots of other versions", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "snapshot-00000000000000000001.json")
+		if err := os.WriteFile(path, []byte(`{"version":99,"offset":1,"checksum":0,"auctions":[]}`), 0644); err != nil {
+			t.Fatalf("Failed to write snapshot file: %v", err)
+		}
+
+		snapshot, err := persistence.ReadLatestSnapshot(dir, 1)
+		if err != nil {
+			t.Fatalf("Failed to read snapshots: %v", err)
+		}
+		if snapshot != nil {
+			t.Error("Expected snapshot of another version to be ignored")
+		}
+	})
+
+	t.Run("Keeps only the most recent snapshots", func(t *testing.T) {
+		dir := t.TempDir()
+		for offset := int64(1); offset <= 4; offset++ {
+			if err := persistence.WriteSnapshot(dir, sampleSnapshot(offset, 1)); err != nil {
+				t.Fatalf("Failed to write snapshot: %v", err)
+			}
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to list snapshots: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Errorf("Expected 2 snapshots to be kept, got %d", len(entries))
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package persistence_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/persistence"
+)
+
+func sampleLogs() ([]domain.Command, []domain.Event) {
+	at := time.Date(2016, 1, 1, 8, 28, 0, 0, time.UTC)
+	seller := domain.NewBuyerOrSeller("Sample_Seller", "Seller")
+	buyer := domain.NewBuyerOrSeller("Sample_Buyer", "Buyer")
+
+	auction := domain.NewAuction(1, at, "auction", at.Add(24*time.Hour), seller,
+		domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()), domain.SEK)
+	bid := domain.NewBid(1, buyer, at.Add(time.Hour), domain.NewAmount(domain.SEK, 10))
+
+	commands := []domain.Command{
+		domain.AddAuctionCommand{Time: at, Auction: auction},
+		domain.PlaceBidCommand{Time: bid.At, Bid: bid},
+	}
+	events := []domain.Event{
+		domain.AuctionAddedEvent{Time: at, Auction: auction},
+		domain.BidAcceptedEvent{Time: bid.At, Bid: bid},
+		domain.AuctionEndedEvent{Time: auction.Expiry, AuctionId: 1},
+		domain.AuctionWonEvent{Time: auction.Expiry, AuctionId: 1, Winner: buyer.ID, Price: domain.NewAmount(domain.SEK, 10)},
+	}
+	return commands, events
+}
+
+// storeBackends opens an empty store of every backend
+var storeBackends = map[string]func(t *testing.T) persistence.Store{
+	"JSONL": func(t *testing.T) persistence.Store {
+		dir := t.TempDir()
+		store, err := persistence.OpenJSONLStore(filepath.Join(dir, "commands.jsonl"), filepath.Join(dir, "events.jsonl"), persistence.LogOptions{})
+		if err != nil {
+			t.Fatalf("Failed to open logs: %v", err)
+		}
+		return store
+	},
+	"Segmented JSONL": func(t *testing.T) persistence.Store {
+		return openSegmented(t, t.TempDir(), persistence.SegmentOptions{MaxBytes: 1})
+	},
+	"SQLite": func(t *testing.T) persistence.Store {
+		store, err := persistence.OpenSQLiteStore(filepath.Join(t.TempDir(), "auctions.db"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		return store
+	},
+}
+
+func TestStores(t *testing.T) {
+	for name, open := range storeBackends {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			defer store.Close()
+
+			events, err := store.ReadEvents()
+			if err != nil {
+				t.Fatalf("Failed to read events: %v", err)
+			}
+			if len(events) != 0 {
+				t.Errorf("Expected an empty event log, got %d events", len(events))
+			}
+
+			commands, events := sampleLogs()
+
+			// Write one at a time, as the server does, then the rest in a batch
+			for _, cmd := range commands {
+				if err := store.WriteCommands([]domain.Command{cmd}); err != nil {
+					t.Fatalf("Failed to write command: %v", err)
+				}
+			}
+			if err := store.WriteEvents(events[:1]); err != nil {
+				t.Fatalf("Failed to write events: %v", err)
+			}
+			if err := store.WriteEvents(events[1:]); err != nil {
+				t.Fatalf("Failed to write events: %v", err)
+			}
+
+			readCommands, err := store.ReadCommands()
+			if err != nil {
+				t.Fatalf("Failed to read commands: %v", err)
+			}
+			if !reflect.DeepEqual(readCommands, commands) {
+				t.Errorf("Expected commands %+v, got %+v", commands, readCommands)
+			}
+
+			readEvents, err := store.ReadEvents()
+			if err != nil {
+				t.Fatalf("Failed to read events: %v", err)
+			}
+			if !reflect.DeepEqual(readEvents, events) {
+				t.Errorf("Expected events %+v, got %+v", events, readEvents)
+			}
+		})
+	}
+}
+
+func TestScanEventsAfter(t *testing.T) {
+	for name, open := range storeBackends {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			defer store.Close()
+
+			_, events := sampleLogs()
+			writeEach(t, store, events[:2])
+			pos, err := store.EventsPosition()
+			if err != nil {
+				t.Fatalf("Failed to get the event log position: %v", err)
+			}
+			if pos.Seq != 2 {
+				t.Errorf("Expected the position after event 2, got %+v", pos)
+			}
+			writeEach(t, store, events[2:])
+
+			var seqs []int64
+			var tail []domain.Event
+			err = store.ScanEventsAfter(pos, func(seq int64, event domain.Event) error {
+				seqs = append(seqs, seq)
+				tail = append(tail, event)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to scan events: %v", err)
+			}
+			if !reflect.DeepEqual(seqs, []int64{3, 4}) || !reflect.DeepEqual(tail, events[2:]) {
+				t.Errorf("Expected events 3 and 4, got %v: %+v", seqs, tail)
+			}
+
+			beyond := pos
+			beyond.Seq = 99
+			err = store.ScanEventsAfter(beyond, func(int64, domain.Event) error { return nil })
+			if !errors.Is(err, persistence.ErrPositionNotInLog) {
+				t.Errorf("Expected a position beyond the log to be rejected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auctions.db")
+	_, events := sampleLogs()
+
+	store, err := persistence.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := store.WriteEvents(events); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+	store.Close()
+
+	store, err = persistence.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer store.Close()
+
+	readEvents, err := store.ReadEvents()
+	if err != nil {
+		t.Fatalf("Failed to read events: %v", err)
+	}
+	if !reflect.DeepEqual(readEvents, events) {
+		t.Errorf("Expected events %+v, got %+v", events, readEvents)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	commands, events := sampleLogs()
+
+	from, err := persistence.OpenJSONLStore(filepath.Join(dir, "commands.jsonl"), filepath.Join(dir, "events.jsonl"), persistence.LogOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open logs: %v", err)
+	}
+	defer from.Close()
+	if err := from.WriteCommands(commands); err != nil {
+		t.Fatalf("Failed to write commands: %v", err)
+	}
+	if err := from.WriteEvents(events); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+
+	to, err := persistence.OpenSQLiteStore(filepath.Join(dir, "auctions.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer to.Close()
+
+	migratedCommands, migratedEvents, err := persistence.Migrate(from, to)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if migratedCommands != len(commands) || migratedEvents != len(events) {
+		t.Errorf("Expected %d commands and %d events, got %d and %d", len(commands), len(events), migratedCommands, migratedEvents)
+	}
+
+	readEvents, err := to.ReadEvents()
+	if err != nil {
+		t.Fatalf("Failed to read events: %v", err)
+	}
+	if !reflect.DeepEqual(domain.EventsToAuctionStates(readEvents), domain.EventsToAuctionStates(events)) {
+		t.Error("Expected migrated events to replay to the same repository")
+	}
+
+	if _, _, err := persistence.Migrate(from, to); err == nil {
+		t.Error("Expected migrating into a non-empty store to fail")
+	}
+}
@@ -72,9 +72,17 @@ func TestBidDeserialization(t *testing.T) {
 		t.Fatalf("Failed to unmarshal bid request: %v", err)
 	}
 
-	// Check the parsed values
-	if req.Amount != 10 {
-		t.Errorf("Expected Amount to be 10, got %d", req.Amount)
+	// Plain numbers are in the auction's currency
+	if req.Amount != (domain.Amount{Value: 10}) {
+		t.Errorf("Expected Amount to be 10 without a currency, got %v", req.Amount)
+	}
+
+	// Amounts may also be given with their currency
+	if err := json.Unmarshal([]byte(`{ "amount": "SEK100", "maxAmount": 150 }`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal bid request: %v", err)
+	}
+	if req.Amount != domain.NewAmount(domain.SEK, 10000) || req.MaxAmount != (domain.Amount{Value: 150}) {
+		t.Errorf("Expected SEK100 up to 150, got %v up to %v", req.Amount, req.MaxAmount)
 	}
 }
 
@@ -167,4 +175,14 @@ func TestAuctionTypeSerialization(t *testing.T) {
 	if string(data) != `"English|0|0|0"` {
 		t.Errorf("Expected auction type to serialize as \"English|0|0|0\", got %s", string(data))
 	}
+
+	// And a timed descending auction type
+	var dutchType domain.AuctionType
+	if err := json.Unmarshal([]byte(`"Dutch|100|10|3600|20"`), &dutchType); err != nil {
+		t.Fatalf("Failed to unmarshal Dutch auction type: %v", err)
+	}
+	if dutchType.Type != domain.TimedDescending || dutchType.Options != "Dutch|100|10|3600|20" {
+		t.Errorf("Expected AuctionType to be TimedDescending with options Dutch|100|10|3600|20, got %v with options %s",
+			dutchType.Type, dutchType.Options)
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -115,6 +117,32 @@ func TestAPI(t *testing.T) {
 		}
 	})
 
+	// Test auctions in unknown currencies are rejected
+	t.Run("CantAddAuctionInUnknownCurrency", func(t *testing.T) {
+		unknownReq := strings.Replace(strings.Replace(auctionReq, `"VAC"`, `"XYZ"`, 1), `"id": 1`, `"id": 99`, 1)
+		req, _ := http.NewRequest("POST", "/auctions", bytes.NewBufferString(unknownReq))
+		req.Header.Set("x-jwt-payload", sellerJWT)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode error body: %v", err)
+		}
+		if got, want := body["type"], "UnknownCurrency"; got != want {
+			t.Errorf("wrong error type: got %v want %v", got, want)
+		}
+		if got, want := body["currency"], "XYZ"; got != want {
+			t.Errorf("wrong currency in error body: got %v want %v", got, want)
+		}
+	})
+
 	// Test get auctions
 	t.Run("GetAuctions", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/auctions", nil)
@@ -217,8 +245,8 @@ func TestAPI(t *testing.T) {
 			t.Errorf("expected auction ID 1, got %d", bidAcceptedEvent.Bid.ForAuction)
 		}
 
-		if bidAcceptedEvent.Bid.Amount != 11 {
-			t.Errorf("expected bid amount 11, got %d", bidAcceptedEvent.Bid.Amount)
+		if bidAcceptedEvent.Bid.Amount != domain.NewAmount(domain.VAC, 11) {
+			t.Errorf("expected bid amount VAC11, got %v", bidAcceptedEvent.Bid.Amount)
 		}
 	})
 
@@ -246,8 +274,8 @@ func TestAPI(t *testing.T) {
 		if len(auction.Bids) != 1 {
 			t.Errorf("expected 1 bid, got %d", len(auction.Bids))
 		} else {
-			if auction.Bids[0].Amount != 11 {
-				t.Errorf("expected bid amount 11, got %d", auction.Bids[0].Amount)
+			if auction.Bids[0].Amount != domain.NewAmount(domain.VAC, 11) {
+				t.Errorf("expected bid amount VAC11, got %v", auction.Bids[0].Amount)
 			}
 
 			// Check bidder
@@ -337,7 +365,33 @@ func TestAPI(t *testing.T) {
 		if got, want := body["type"], "MustPlaceBidOverHighestBid"; got != want {
 			t.Errorf("wrong error type: got %v want %v", got, want)
 		}
-		if got, want := body["amount"], float64(11); got != want {
+		if got, want := body["amount"], "VAC11"; got != want {
+			t.Errorf("wrong amount in error body: got %v want %v", got, want)
+		}
+	})
+
+	// Test bids must be positive
+	t.Run("BidMustBePositive", func(t *testing.T) {
+		bidReq := `{"amount": "VAC0"}`
+		req, _ := http.NewRequest("POST", "/auctions/1/bids", bytes.NewBufferString(bidReq))
+		req.Header.Set("x-jwt-payload", buyer2JWT)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode error body: %v", err)
+		}
+		if got, want := body["type"], "BidAmountMustBePositive"; got != want {
+			t.Errorf("wrong error type: got %v want %v", got, want)
+		}
+		if got, want := body["amount"], "VAC0"; got != want {
 			t.Errorf("wrong amount in error body: got %v want %v", got, want)
 		}
 	})
@@ -366,3 +420,23 @@ func TestAPI(t *testing.T) {
 		}
 	})
 }
+
+// TestDutchAuctionOptionErrors tests the errors for the options of a Dutch clock that cannot run down
+func TestDutchAuctionOptionErrors(t *testing.T) {
+	getCurrentTime := func() time.Time {
+		return time.Date(2018, 8, 4, 0, 0, 0, 0, time.UTC)
+	}
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, getCurrentTime)
+
+	for typ, want := range map[string]map[string]interface{}{
+		"Dutch|VAC0|VAC10|3600|VAC0":     {"type": "AmountMustBePositive", "option": "startPrice", "amount": "VAC0"},
+		"Dutch|VAC100|VAC0|3600|VAC0":    {"type": "AmountMustBePositive", "option": "decrement", "amount": "VAC0"},
+		"Dutch|VAC100|VAC10|3600|VAC200": {"type": "FloorPriceMustNotExceedStartPrice", "floorPrice": "VAC200", "startPrice": "VAC100"},
+	} {
+		auctionReq := `{"id": 1, "startsAt": "2018-08-03T00:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Dutch auction", "currency": "VAC", "typ": "` + typ + `"}`
+		body := errorBody(t, send(app, "POST", "/auctions", sellerJWT, auctionReq), http.StatusBadRequest)
+		if !reflect.DeepEqual(body, want) {
+			t.Errorf("%s: expected %v, got %v", typ, want, body)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestBuyNow tests buying the item of an English auction at its buy-now price
+func TestBuyNow(t *testing.T) {
+	fixedTime, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	getCurrentTime := func() time.Time {
+		return fixedTime
+	}
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, getCurrentTime)
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "First auction", "currency": "VAC", "typ": "English|VAC0|VAC1|0|VAC100"}`
+	if rr := send(app, "POST", "/auctions", sellerJWT, auctionReq); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("ShowsBuyNowPrice", func(t *testing.T) {
+		auction := getAuction(t, app, 1)
+		if auction.BuyNowPrice == nil || *auction.BuyNowPrice != domain.NewAmount(domain.VAC, 100) {
+			t.Errorf("expected a buy-now price of VAC100, got %v", auction.BuyNowPrice)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		if rr := send(app, "POST", "/auctions/1/buy-now", "", ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", rr.Code)
+		}
+	})
+
+	t.Run("BuyNow", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/buy-now", buyerJWT, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		auction := getAuction(t, app, 1)
+		if auction.Winner == nil || *auction.Winner != "a2" || auction.WinnerPrice == nil || *auction.WinnerPrice != domain.NewAmount(domain.VAC, 100) {
+			t.Errorf("expected a2 to win at VAC100, got %v at %v", auction.Winner, auction.WinnerPrice)
+		}
+		if auction.BuyNowPrice != nil {
+			t.Errorf("expected no buy-now price once the item was bought, got %v", auction.BuyNowPrice)
+		}
+	})
+
+	t.Run("AlreadyBought", func(t *testing.T) {
+		body := errorBody(t, send(app, "POST", "/auctions/1/buy-now", buyerJWT, ""), http.StatusBadRequest)
+		if got, want := body["type"], "AuctionHasEnded"; got != want {
+			t.Errorf("wrong error type: got %v want %v", got, want)
+		}
+	})
+}
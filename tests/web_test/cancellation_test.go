@@ -0,0 +1,108 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestCancellationAPI tests cancelling auctions and retracting bids over HTTP
+func TestCancellationAPI(t *testing.T) {
+	fixedTime, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	getCurrentTime := func() time.Time {
+		return fixedTime
+	}
+
+	var recordedEvents []domain.Event
+	onEvent := func(event domain.Event) error {
+		recordedEvents = append(recordedEvents, event)
+		return nil
+	}
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, onEvent, getCurrentTime)
+
+	expect := func(t *testing.T, rr *httptest.ResponseRecorder, status int, errorType string) {
+		t.Helper()
+		if rr.Code != status {
+			t.Fatalf("expected status %d, got %d: %s", status, rr.Code, rr.Body.String())
+		}
+		if errorType == "" {
+			return
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if body["type"] != errorType {
+			t.Errorf("expected error type %s, got %v", errorType, body["type"])
+		}
+	}
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "First auction", "currency": "VAC"}`
+	expect(t, send(app, "POST", "/auctions", sellerJWT, auctionReq), http.StatusOK, "")
+	expect(t, send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": 11}`), http.StatusOK, "")
+
+	t.Run("SellerCannotCancelAfterFirstBid", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/cancel", sellerJWT, `{"reason": "Changed my mind"}`)
+		expect(t, rr, http.StatusForbidden, "NotAuthorized")
+	})
+
+	t.Run("BuyerCannotRetractBids", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/bids/retract", buyerJWT, `{"bidder": "a2", "reason": "Mistake"}`)
+		expect(t, rr, http.StatusForbidden, "NotAuthorized")
+	})
+
+	t.Run("RetractionRequiresReason", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/bids/retract", supportJWT, `{"bidder": "a2"}`)
+		expect(t, rr, http.StatusBadRequest, "ReasonRequired")
+	})
+
+	t.Run("RetractUnknownBid", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/bids/retract", supportJWT, `{"bidder": "a9", "reason": "Mistake"}`)
+		expect(t, rr, http.StatusNotFound, "BidNotFound")
+	})
+
+	t.Run("SupportRetractsBid", func(t *testing.T) {
+		recordedEvents = nil
+		rr := send(app, "POST", "/auctions/1/bids/retract", supportJWT, `{"bidder": "a2", "reason": "Mistake"}`)
+		expect(t, rr, http.StatusOK, "")
+
+		if len(recordedEvents) != 1 {
+			t.Fatalf("expected 1 event, got %d", len(recordedEvents))
+		}
+		if _, ok := recordedEvents[0].(domain.BidRetractedEvent); !ok {
+			t.Errorf("expected BidRetractedEvent, got %T", recordedEvents[0])
+		}
+
+		auction := getAuction(t, app, 1)
+		if len(auction.Bids) != 0 {
+			t.Errorf("expected no bids after retraction, got %v", auction.Bids)
+		}
+	})
+
+	t.Run("SupportCancelsAuction", func(t *testing.T) {
+		rr := send(app, "POST", "/auctions/1/cancel", supportJWT, `{"reason": "Counterfeit item"}`)
+		expect(t, rr, http.StatusOK, "")
+
+		var items []web.AuctionListItem
+		json.Unmarshal(send(app, "GET", "/auctions?status=cancelled", "", "").Body.Bytes(), &items)
+		if len(items) != 1 || items[0].Status != domain.AuctionCancelled {
+			t.Errorf("expected the auction to be listed as cancelled, got %v", items)
+		}
+
+		rr = send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": 12}`)
+		expect(t, rr, http.StatusBadRequest, "AuctionCancelled")
+	})
+
+	t.Run("SellerCancelsBeforeFirstBid", func(t *testing.T) {
+		auctionReq := `{"id": 2, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Second auction", "currency": "VAC"}`
+		expect(t, send(app, "POST", "/auctions", sellerJWT, auctionReq), http.StatusOK, "")
+
+		rr := send(app, "POST", "/auctions/2/cancel", sellerJWT, `{"reason": "Changed my mind"}`)
+		expect(t, rr, http.StatusOK, "")
+	})
+}
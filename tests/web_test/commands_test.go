@@ -0,0 +1,130 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestCommandQueries tests looking up commands and their outcomes as a support user
+func TestCommandQueries(t *testing.T) {
+	fixedTime, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	getCurrentTime := func() time.Time {
+		return fixedTime
+	}
+
+	// Keep the command log as JSON, as the stores do
+	var commandLog [][]byte
+	onCommand := func(cmd domain.Command) error {
+		data, err := json.Marshal(cmd)
+		if err != nil {
+			return err
+		}
+		commandLog = append(commandLog, data)
+		return nil
+	}
+	app := web.NewApp(domain.Repository{}, onCommand, func(domain.Event) error { return nil }, getCurrentTime)
+
+	// query returns the records of a support query
+	type commandRecord struct {
+		Seq     int64                `json:"seq"`
+		Command json.RawMessage      `json:"command"`
+		Outcome *web.OutcomeResponse `json:"outcome"`
+	}
+	query := func(t *testing.T, path string) []commandRecord {
+		t.Helper()
+		rr := send(app, "GET", path, supportJWT, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var records []commandRecord
+		if err := json.Unmarshal(rr.Body.Bytes(), &records); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return records
+	}
+
+	t.Run("NotAvailableWithoutCommandLog", func(t *testing.T) {
+		rr := send(app, "GET", "/auctions/1/commands", supportJWT, "")
+		if rr.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", rr.Code)
+		}
+	})
+
+	app.ScanCommands = func(fn func(int64, domain.Command) error) error {
+		for i, data := range commandLog {
+			cmd, err := domain.UnmarshalCommand(data)
+			if err != nil {
+				return err
+			}
+			if err := fn(int64(i+1), cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "First auction", "currency": "VAC"}`
+	send(app, "POST", "/auctions", sellerJWT, auctionReq)
+	send(app, "POST", "/auctions/1/bids", sellerJWT, `{"amount": 11}`)
+	send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": 11}`)
+
+	t.Run("OutcomesAreLogged", func(t *testing.T) {
+		// Three commands, each followed by its outcome
+		if len(commandLog) != 6 {
+			t.Fatalf("expected 6 records in the command log, got %d", len(commandLog))
+		}
+	})
+
+	t.Run("ByAuction", func(t *testing.T) {
+		records := query(t, "/auctions/1/commands")
+		if len(records) != 3 {
+			t.Fatalf("expected 3 commands, got %d", len(records))
+		}
+
+		added, rejected, accepted := records[0], records[1], records[2]
+		if added.Seq != 1 || rejected.Seq != 3 || accepted.Seq != 5 {
+			t.Errorf("expected commands at 1, 3 and 5, got %d, %d and %d", added.Seq, rejected.Seq, accepted.Seq)
+		}
+		if added.Outcome == nil || !added.Outcome.Accepted || added.Outcome.EventSeq != 1 {
+			t.Errorf("expected the auction to be added with event 1, got %+v", added.Outcome)
+		}
+		if rejected.Outcome == nil || rejected.Outcome.Accepted || rejected.Outcome.Error["type"] != "SellerCannotPlaceBids" {
+			t.Errorf("expected the seller's bid to be rejected, got %+v", rejected.Outcome)
+		}
+		if accepted.Outcome == nil || !accepted.Outcome.Accepted || accepted.Outcome.EventSeq != 2 {
+			t.Errorf("expected the buyer's bid to be accepted with event 2, got %+v", accepted.Outcome)
+		}
+	})
+
+	t.Run("ByUser", func(t *testing.T) {
+		records := query(t, "/users/a2/commands")
+		if len(records) != 1 {
+			t.Fatalf("expected 1 command, got %d", len(records))
+		}
+		cmd, err := domain.UnmarshalCommand(records[0].Command)
+		if bid, ok := cmd.(domain.PlaceBidCommand); err != nil || !ok || bid.Bid.Bidder.ID != "a2" {
+			t.Errorf("expected the buyer's bid, got %s", records[0].Command)
+		}
+
+		if records := query(t, "/users/nobody/commands"); len(records) != 0 {
+			t.Errorf("expected no commands, got %v", records)
+		}
+	})
+
+	t.Run("SupportOnly", func(t *testing.T) {
+		if rr := send(app, "GET", "/auctions/1/commands", buyerJWT, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rr.Code)
+		}
+		if rr := send(app, "GET", "/users/a2/commands", buyerJWT, ""); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rr.Code)
+		}
+		if rr := send(app, "GET", "/users/a2/commands", "", ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", rr.Code)
+		}
+	})
+}
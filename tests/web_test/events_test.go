@@ -0,0 +1,200 @@
+package web_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// sseEvent is a parsed Server-Sent Event
+type sseEvent struct {
+	ID   string
+	Name string
+	Data string
+}
+
+// readSseEvents reads count events from an SSE stream
+func readSseEvents(t *testing.T, reader *bufio.Reader, count int) []sseEvent {
+	var events []sseEvent
+	var current sseEvent
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			current.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+// TestEventStream verifies live streaming, resume and redaction of auction events
+func TestEventStream(t *testing.T) {
+	fixedTime, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	var mu sync.Mutex
+	var persisted []domain.Event
+	onEvent := func(event domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		persisted = append(persisted, event)
+		return nil
+	}
+	scanEvents := func(fn func(int64, domain.Event) error) error {
+		mu.Lock()
+		events := append([]domain.Event{}, persisted...)
+		mu.Unlock()
+		for i, event := range events {
+			if err := fn(int64(i+1), event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, onEvent, func() time.Time { return fixedTime })
+	app.Events.SetHistory(0, scanEvents)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	post := func(path, body, jwt string) {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("x-jwt-payload", jwt)
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("POST %s failed with %v: %s", path, rr.Code, rr.Body.String())
+		}
+	}
+	subscribe := func(path, lastEventId string) (*bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+		if lastEventId != "" {
+			req.Header.Set("Last-Event-ID", lastEventId)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		if resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("expected text/event-stream, got %s", resp.Header.Get("Content-Type"))
+		}
+		return bufio.NewReader(resp.Body), func() {
+			cancel()
+			resp.Body.Close()
+		}
+	}
+
+	post("/auctions", `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "English"}`, sellerJWT)
+	post("/auctions", `{"id": 2, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Sealed", "typ": "Blind"}`, sellerJWT)
+
+	t.Run("StreamsLiveBidsForAuction", func(t *testing.T) {
+		reader, cancel := subscribe("/auctions/1/events", "2")
+		defer cancel()
+
+		post("/auctions/2/bids", `{"amount": 10}`, buyerJWT)
+		post("/auctions/1/bids", `{"amount": 11, "maxAmount": 50}`, buyerJWT)
+
+		events := readSseEvents(t, reader, 1)
+		if events[0].ID != "4" || events[0].Name != "BidAccepted" {
+			t.Fatalf("expected BidAccepted with id 4, got %+v", events[0])
+		}
+		if strings.Contains(events[0].Data, "maxAmount") || !strings.Contains(events[0].Data, `"amount":"VAC11"`) {
+			t.Errorf("expected visible amount without maximum, got %s", events[0].Data)
+		}
+	})
+
+	t.Run("ResumesFromLastEventId", func(t *testing.T) {
+		reader, cancel := subscribe("/events", "1")
+		defer cancel()
+
+		events := readSseEvents(t, reader, 3)
+		ids := []string{events[0].ID, events[1].ID, events[2].ID}
+		if strings.Join(ids, ",") != "2,3,4" {
+			t.Errorf("expected events 2,3,4, got %v", ids)
+		}
+
+		var sealedBid struct {
+			Bid map[string]interface{} `json:"bid"`
+		}
+		if err := json.Unmarshal([]byte(events[1].Data), &sealedBid); err != nil {
+			t.Fatalf("failed to parse event data: %v", err)
+		}
+		if _, ok := sealedBid.Bid["amount"]; ok {
+			t.Errorf("expected sealed bid amount to be hidden, got %s", events[1].Data)
+		}
+	})
+
+	t.Run("StreamsVisibleLeadingBid", func(t *testing.T) {
+		reader, cancel := subscribe("/auctions/1/events", "4")
+		defer cancel()
+
+		// Without a minimum raise the proxy of the leader defends against a bid of 20 at 20,
+		// as the earlier bidder wins ties
+		post("/auctions/1/bids", `{"amount": 20}`, buyer3JWT)
+		live := readSseEvents(t, reader, 1)[0]
+
+		var payload struct {
+			Bid        map[string]interface{} `json:"bid"`
+			HighestBid web.AuctionBidResponse `json:"highestBid"`
+		}
+		if err := json.Unmarshal([]byte(live.Data), &payload); err != nil {
+			t.Fatalf("failed to parse event data: %v", err)
+		}
+		if payload.Bid["amount"] != "VAC20" {
+			t.Errorf("expected the bid to be shown at VAC20, got %s", live.Data)
+		}
+		if payload.HighestBid.Amount != domain.NewAmount(domain.VAC, 20) || payload.HighestBid.Bidder.ID != "a2" {
+			t.Errorf("expected a2 to lead at VAC20, got %s", live.Data)
+		}
+
+		// Resuming streams the bid as it was streamed live
+		resumed, cancelResumed := subscribe("/auctions/1/events", "4")
+		defer cancelResumed()
+		if missed := readSseEvents(t, resumed, 1)[0]; missed.Data != live.Data {
+			t.Errorf("expected %s after resuming, got %s", live.Data, missed.Data)
+		}
+	})
+
+	t.Run("StreamsDutchBidsAtClockPrice", func(t *testing.T) {
+		post("/auctions", `{"id": 3, "startsAt": "2018-08-03T00:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Dutch", "currency": "VAC", "typ": "Dutch|VAC100|VAC10|3600|VAC20"}`, sellerJWT)
+		reader, cancel := subscribe("/auctions/3/events", "6")
+		defer cancel()
+
+		// A day after the start the clock has run down to the floor
+		post("/auctions/3/bids", `{"amount": 90}`, buyerJWT)
+		if event := readSseEvents(t, reader, 1)[0]; !strings.Contains(event.Data, `"bid":{"amount":"VAC20"`) || !strings.Contains(event.Data, `"highestBid":{"amount":"VAC20"`) {
+			t.Errorf("expected the bid to be shown at the clock price VAC20, got %s", event.Data)
+		}
+	})
+
+	t.Run("UnknownAuction", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/auctions/999/events")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected 404, got %v", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package web_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// JWT payloads as passed on by the trusted proxy:
+// {"sub": <id>, "name": <name>, "u_typ": "0"} (0 = BuyerOrSeller)
+const (
+	sellerJWT = "eyJzdWIiOiJhMSIsICJuYW1lIjoiVGVzdCIsICJ1X3R5cCI6IjAifQo="     // sub=a1, name=Test
+	buyerJWT  = "eyJzdWIiOiJhMiIsICJuYW1lIjoiQnV5ZXIiLCAidV90eXAiOiIwIn0K"     // sub=a2, name=Buyer
+	buyer3JWT = "eyJzdWIiOiJhMyIsICJuYW1lIjoiQnV5ZXIgMyIsICJ1X3R5cCI6IjAifQo=" // sub=a3, name=Buyer 3
+)
+
+// supportJWT is the JWT payload of a support user (u_typ 1)
+var supportJWT = base64.StdEncoding.EncodeToString([]byte(`{"sub":"s1","u_typ":"1"}`))
+
+// send serves a request with a JSON body, as the user of the JWT payload unless it is empty
+func send(app *web.App, method, path, jwt, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if jwt != "" {
+		req.Header.Set("x-jwt-payload", jwt)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	app.Router.ServeHTTP(rr, req)
+	return rr
+}
+
+// getAuction returns the auction with the given ID as GET /auctions/:id shows it
+func getAuction(t *testing.T, app *web.App, id domain.AuctionId) web.AuctionResponse {
+	t.Helper()
+	rr := send(app, "GET", fmt.Sprintf("/auctions/%d", id), "", "")
+	var auction web.AuctionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &auction); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return auction
+}
+
+// errorBody returns the decoded body of an error response with the given status
+func errorBody(t *testing.T, rr *httptest.ResponseRecorder, status int) map[string]interface{} {
+	t.Helper()
+	if rr.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, rr.Code, rr.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode error body: %v", err)
+	}
+	return body
+}
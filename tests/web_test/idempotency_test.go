@@ -0,0 +1,179 @@
+package web_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestIdempotencyKeys tests retrying write requests with an Idempotency-Key header
+func TestIdempotencyKeys(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	getCurrentTime := func() time.Time {
+		return now
+	}
+
+	var commandLog []domain.Command
+	onCommand := func(cmd domain.Command) error {
+		commandLog = append(commandLog, cmd)
+		return nil
+	}
+	scanCommands := func(fn func(int64, domain.Command) error) error {
+		for i, cmd := range commandLog {
+			if err := fn(int64(i+1), cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var recordedEvents []domain.Event
+	onEvent := func(event domain.Event) error {
+		recordedEvents = append(recordedEvents, event)
+		return nil
+	}
+
+	newApp := func(repo domain.Repository) *web.App {
+		app := web.NewApp(repo, onCommand, onEvent, getCurrentTime)
+		app.Idempotency.SetTTL(time.Hour)
+		return app
+	}
+	app := newApp(domain.Repository{})
+
+	sendWithKey := func(path, jwt, key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("x-jwt-payload", jwt)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(web.IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	expectReplay := func(t *testing.T, first, retry *httptest.ResponseRecorder) {
+		t.Helper()
+		if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+			t.Errorf("expected %d %s, got %d %s", first.Code, first.Body.String(), retry.Code, retry.Body.String())
+		}
+		if retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("expected the response to be marked as replayed")
+		}
+	}
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "First auction", "currency": "VAC"}`
+
+	t.Run("CreateAuctionOnce", func(t *testing.T) {
+		first := sendWithKey("/auctions", sellerJWT, "create-1", auctionReq)
+		if first.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", first.Code, first.Body.String())
+		}
+		// Whitespace does not make a different request
+		retry := sendWithKey("/auctions", sellerJWT, "create-1", " "+auctionReq+"\n")
+		expectReplay(t, first, retry)
+
+		if len(recordedEvents) != 1 {
+			t.Errorf("expected 1 event, got %d", len(recordedEvents))
+		}
+	})
+
+	t.Run("PlaceBidOnce", func(t *testing.T) {
+		recordedEvents = nil
+		first := sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 11}`)
+		if first.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", first.Code, first.Body.String())
+		}
+		expectReplay(t, first, sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 11}`))
+
+		if len(recordedEvents) != 1 {
+			t.Errorf("expected 1 event, got %d", len(recordedEvents))
+		}
+	})
+
+	t.Run("ReplaysRejections", func(t *testing.T) {
+		first := sendWithKey("/auctions/1/bids", sellerJWT, "bid-1", `{"amount": 12}`)
+		if first.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", first.Code, first.Body.String())
+		}
+		expectReplay(t, first, sendWithKey("/auctions/1/bids", sellerJWT, "bid-1", `{"amount": 12}`))
+	})
+
+	t.Run("RejectsKeyReuseWithDifferentBody", func(t *testing.T) {
+		recordedEvents = nil
+		rr := sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 20}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d: %s", rr.Code, rr.Body.String())
+		}
+		rr = sendWithKey("/auctions", buyerJWT, "bid-1", auctionReq)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422 for another endpoint, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if len(recordedEvents) != 0 {
+			t.Errorf("expected no events, got %d", len(recordedEvents))
+		}
+	})
+
+	t.Run("RecordsResponsesInCommandLog", func(t *testing.T) {
+		var responses []domain.IdempotentResponse
+		for _, cmd := range commandLog {
+			if response, ok := cmd.(domain.IdempotentResponse); ok {
+				responses = append(responses, response)
+			}
+		}
+		if len(responses) != 3 {
+			t.Fatalf("expected 3 recorded responses, got %d", len(responses))
+		}
+		if responses[1].AuctionId != 1 || responses[1].UserId != "a2" || responses[1].Key != "bid-1" {
+			t.Errorf("unexpected recorded response: %+v", responses[1])
+		}
+
+		data, err := json.Marshal(responses[1])
+		if err != nil {
+			t.Fatalf("failed to marshal response: %v", err)
+		}
+		decoded, err := domain.UnmarshalCommand(data)
+		if err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if decoded.(domain.IdempotentResponse).RequestHash != responses[1].RequestHash {
+			t.Errorf("expected %+v, got %+v", responses[1], decoded)
+		}
+	})
+
+	t.Run("SurvivesRestart", func(t *testing.T) {
+		first := sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 11}`)
+
+		app = newApp(app.State.GetRepository())
+		if err := app.Idempotency.Load(scanCommands, now); err != nil {
+			t.Fatalf("failed to load keys: %v", err)
+		}
+		recordedEvents = nil
+		expectReplay(t, first, sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 11}`))
+		if len(recordedEvents) != 0 {
+			t.Errorf("expected no events, got %d", len(recordedEvents))
+		}
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		rr := sendWithKey("/auctions/1/bids", buyerJWT, "bid-1", `{"amount": 20}`)
+		if rr.Code != http.StatusOK || rr.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("expected the expired key to be used for a new bid, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		app = newApp(app.State.GetRepository())
+		if err := app.Idempotency.Load(scanCommands, now); err != nil {
+			t.Fatalf("failed to load keys: %v", err)
+		}
+		rr = sendWithKey("/auctions", sellerJWT, "create-1", auctionReq)
+		if rr.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("expected expired keys not to be loaded")
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package web_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestNextAcceptableBid tests that a bid that is too low is told the next acceptable bid
+func TestNextAcceptableBid(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, func() time.Time { return now })
+
+	for id, typ := range map[int]string{1: "English|VAC0|VAC1,VAC100:VAC5|0", 2: "English|VAC0|VAC1|0"} {
+		auctionReq := fmt.Sprintf(`{"id": %d, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Increments", "currency": "VAC", "typ": "%s"}`, id, typ)
+		if rr := send(app, "POST", "/auctions", sellerJWT, auctionReq); rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	t.Run("RendersNextAcceptableBid", func(t *testing.T) {
+		if rr := send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": "VAC100"}`); rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		// Over 100 bids are raised by 5, so the next acceptable bid is 105
+		body := errorBody(t, send(app, "POST", "/auctions/1/bids", buyer3JWT, `{"amount": "VAC102"}`), http.StatusBadRequest)
+		if body["type"] != "MustPlaceBidOverHighestBid" || body["amount"] != "VAC105" {
+			t.Errorf("expected a bid of at least VAC105, got %v", body)
+		}
+	})
+
+	t.Run("RendersOverflow", func(t *testing.T) {
+		if rr := send(app, "POST", "/auctions/2/bids", buyerJWT, `{"amount": "VAC9223372036854775807"}`); rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		body := errorBody(t, send(app, "POST", "/auctions/2/bids", buyer3JWT, `{"amount": "VAC9223372036854775807"}`), http.StatusBadRequest)
+		if body["type"] != "AmountOverflow" {
+			t.Errorf("expected an AmountOverflow error, got %v", body)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package web_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// signToken creates a compact JWT signed with the given algorithm and key
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerData, _ := json.Marshal(header)
+	claimsData, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerData) + "." + base64.RawURLEncoding.EncodeToString(claimsData)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestJwtVerifier verifies signature and claim checks of compact JWTs
+func TestJwtVerifier(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	secret := []byte("shared-secret")
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "a1",
+			"name":  "Test",
+			"u_typ": "0",
+			"iss":   "auctions",
+			"aud":   []string{"api"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"nbf":   now.Add(-time.Hour).Unix(),
+		}
+	}
+	verifier := &web.JwtVerifier{
+		Keys:           []web.JwtKey{web.NewHS256Key(secret)},
+		Issuer:         "auctions",
+		Audience:       "api",
+		GetCurrentTime: func() time.Time { return now },
+	}
+
+	t.Run("AcceptsValidHS256Token", func(t *testing.T) {
+		user, err := verifier.Verify(signToken(t, "HS256", "", secret, validClaims()))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user != domain.NewBuyerOrSeller("a1", "Test") {
+			t.Errorf("expected BuyerOrSeller a1, got %+v", user)
+		}
+	})
+
+	rejected := map[string]func() string{
+		"WrongSecret": func() string {
+			return signToken(t, "HS256", "", []byte("other"), validClaims())
+		},
+		"AlgNone": func() string {
+			token := signToken(t, "HS256", "", secret, validClaims())
+			header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+			return header + token[bytes.IndexByte([]byte(token), '.'):]
+		},
+		"Expired": func() string {
+			claims := validClaims()
+			claims["exp"] = now.Unix()
+			return signToken(t, "HS256", "", secret, claims)
+		},
+		"MissingExpiry": func() string {
+			claims := validClaims()
+			delete(claims, "exp")
+			return signToken(t, "HS256", "", secret, claims)
+		},
+		"NotYetValid": func() string {
+			claims := validClaims()
+			claims["nbf"] = now.Add(time.Minute).Unix()
+			return signToken(t, "HS256", "", secret, claims)
+		},
+		"WrongIssuer": func() string {
+			claims := validClaims()
+			claims["iss"] = "someone-else"
+			return signToken(t, "HS256", "", secret, claims)
+		},
+		"WrongAudience": func() string {
+			claims := validClaims()
+			claims["aud"] = "other-api"
+			return signToken(t, "HS256", "", secret, claims)
+		},
+		"Malformed": func() string {
+			return "not-a-token"
+		},
+	}
+	for name, token := range rejected {
+		token := token
+		t.Run("Rejects"+name, func(t *testing.T) {
+			if _, err := verifier.Verify(token()); err == nil {
+				t.Errorf("expected token to be rejected")
+			}
+		})
+	}
+
+	t.Run("AcceptsRS256TokenFromPem", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		path := filepath.Join(t.TempDir(), "key.pem")
+		os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+
+		keys, err := web.LoadJwtKeys(path)
+		if err != nil {
+			t.Fatalf("failed to load keys: %v", err)
+		}
+		rsaVerifier := &web.JwtVerifier{Keys: keys, GetCurrentTime: func() time.Time { return now }}
+
+		if _, err := rsaVerifier.Verify(signToken(t, "RS256", "", rsaKey, validClaims())); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		// An HS256 token signed with the public key bytes must not be accepted
+		if _, err := rsaVerifier.Verify(signToken(t, "HS256", "", der, validClaims())); err == nil {
+			t.Errorf("expected HS256 token to be rejected by RS256 key")
+		}
+	})
+
+	t.Run("AcceptsES256TokenFromJwks", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		coordinate := func(i *big.Int) string {
+			b := make([]byte, 32)
+			i.FillBytes(b)
+			return base64.RawURLEncoding.EncodeToString(b)
+		}
+		jwks, _ := json.Marshal(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "EC", "kid": "k1", "use": "sig", "crv": "P-256",
+				"x": coordinate(ecKey.X), "y": coordinate(ecKey.Y),
+			}},
+		})
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		os.WriteFile(path, jwks, 0644)
+
+		keys, err := web.LoadJwtKeys(path)
+		if err != nil {
+			t.Fatalf("failed to load keys: %v", err)
+		}
+		ecVerifier := &web.JwtVerifier{Keys: keys, GetCurrentTime: func() time.Time { return now }}
+
+		if _, err := ecVerifier.Verify(signToken(t, "ES256", "k1", ecKey, validClaims())); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if _, err := ecVerifier.Verify(signToken(t, "ES256", "k2", ecKey, validClaims())); err == nil {
+			t.Errorf("expected token with unknown key ID to be rejected")
+		}
+	})
+
+	t.Run("BearerAuthenticatorProtectsRoutes", func(t *testing.T) {
+		app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, func() time.Time { return now })
+		app.Authenticator = web.NewBearerAuthenticator(verifier)
+		auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "First auction"}`
+
+		req, _ := http.NewRequest("POST", "/auctions", bytes.NewBufferString(auctionReq))
+		req.Header.Set("x-jwt-payload", "eyJzdWIiOiJhMSIsICJuYW1lIjoiVGVzdCIsICJ1X3R5cCI6IjAifQo=")
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected proxy header to be ignored in verify mode, got %v", rr.Code)
+		}
+
+		req, _ = http.NewRequest("POST", "/auctions", bytes.NewBufferString(auctionReq))
+		req.Header.Set("Authorization", "Bearer "+signToken(t, "HS256", "", secret, validClaims()))
+		rr = httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected verified token to be accepted, got %v: %s", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package web_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestMultiUnitAuction tests bidding for quantities of units and the allocations of the winners
+func TestMultiUnitAuction(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, func() time.Time { return now })
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Tickets", "currency": "VAC", "typ": "MultiUnit|5|LowestAccepted"}`
+	if rr := send(app, "POST", "/auctions", sellerJWT, auctionReq); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("QuantityMustBeWithinUnits", func(t *testing.T) {
+		body := errorBody(t, send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": 10, "quantity": 6}`), http.StatusBadRequest)
+		if body["type"] != "InvalidQuantity" || body["quantity"] != float64(6) || body["units"] != float64(5) {
+			t.Errorf("expected an InvalidQuantity error for 6 of 5 units, got %v", body)
+		}
+	})
+
+	t.Run("RendersAllocations", func(t *testing.T) {
+		for jwt, body := range map[string]string{
+			buyerJWT:  `{"amount": 12, "quantity": 3}`,
+			buyer3JWT: `{"amount": 10, "quantity": 3}`,
+		} {
+			if rr := send(app, "POST", "/auctions/1/bids", jwt, body); rr.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+		}
+		if auction := getAuction(t, app, 1); len(auction.Allocations) != 0 {
+			t.Errorf("expected no allocations before the auction ends, got %v", auction.Allocations)
+		}
+
+		now, _ = time.Parse(time.RFC3339, "2019-01-01T10:00:00Z")
+		expected := []web.AllocationResponse{
+			{Bidder: "a2", Quantity: 3, Price: domain.NewAmount(domain.VAC, 10)},
+			{Bidder: "a3", Quantity: 2, Price: domain.NewAmount(domain.VAC, 10)},
+		}
+		if auction := getAuction(t, app, 1); !reflect.DeepEqual(auction.Allocations, expected) {
+			t.Errorf("expected allocations %+v, got %+v", expected, auction.Allocations)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package web_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestListAuctions verifies filtering, sorting and pagination of GET /auctions
+func TestListAuctions(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	alice := domain.NewBuyerOrSeller("alice", "Alice")
+	bob := domain.NewBuyerOrSeller("bob", "Bob")
+	buyer := domain.NewBuyerOrSeller("buyer", "Buyer")
+	english := domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions())
+	blind := domain.NewSingleSealedBidType(domain.Blind)
+
+	auctions := []domain.Auction{
+		domain.NewAuction(1, now.Add(-48*time.Hour), "Old lamp", now.Add(-24*time.Hour), alice, english, domain.SEK),
+		domain.NewAuction(2, now.Add(-time.Hour), "Red bicycle", now.Add(72*time.Hour), alice, english, domain.VAC),
+		domain.NewAuction(3, now.Add(-time.Hour), "Blue bicycle", now.Add(24*time.Hour), bob, blind, domain.VAC),
+		domain.NewAuction(4, now.Add(time.Hour), "Painting", now.Add(48*time.Hour), bob, english, domain.VAC),
+	}
+	var events []domain.Event
+	for _, auction := range auctions {
+		events = append(events, domain.AuctionAddedEvent{Time: auction.StartsAt, Auction: auction})
+	}
+	bidAt := now.Add(-30 * time.Minute)
+	events = append(events,
+		domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(2, buyer, bidAt, domain.NewAmount(domain.VAC, 15))},
+		domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(3, buyer, bidAt, domain.NewAmount(domain.VAC, 20))},
+	)
+
+	app := web.NewApp(domain.EventsToAuctionStates(events), func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, func() time.Time { return now })
+
+	list := func(t *testing.T, query string) ([]web.AuctionListItem, *httptest.ResponseRecorder) {
+		req, _ := http.NewRequest("GET", "/auctions?"+query, nil)
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		var items []web.AuctionListItem
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+		}
+		return items, rr
+	}
+	ids := func(items []web.AuctionListItem) string {
+		var result []string
+		for _, item := range items {
+			result = append(result, fmt.Sprint(item.ID))
+		}
+		return strings.Join(result, ",")
+	}
+
+	filters := map[string]string{
+		"":                                 "1,2,3,4",
+		"status=ended":                     "1",
+		"status=ongoing":                   "2,3",
+		"status=awaiting":                  "4",
+		"currency=SEK":                     "1",
+		"type=Blind":                       "3",
+		"seller=bob":                       "3,4",
+		"title=BICYCLE":                    "2,3",
+		"startsAfter=2018-08-03T00:00:00Z": "2,3,4",
+		"endsBefore=2018-08-05T12:00:00Z":  "1,3",
+		"sort=expiry":                      "1,3,4,2",
+		"sort=-start":                      "4,3,2,1",
+	}
+	for query, expected := range filters {
+		query, expected := query, expected
+		t.Run("Query"+query, func(t *testing.T) {
+			items, rr := list(t, query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %v: %s", rr.Code, rr.Body.String())
+			}
+			if got := ids(items); got != expected {
+				t.Errorf("expected auctions %s, got %s", expected, got)
+			}
+		})
+	}
+
+	t.Run("StatusAndHighestBid", func(t *testing.T) {
+		items, _ := list(t, "")
+		if items[1].Status != domain.AuctionOngoing || items[1].HighestBid == nil || *items[1].HighestBid != domain.NewAmount(domain.VAC, 15) {
+			t.Errorf("expected ongoing auction 2 with highest bid 15, got %+v", items[1])
+		}
+		if items[2].HighestBid != nil {
+			t.Errorf("expected sealed bid amount to be hidden, got %v", *items[2].HighestBid)
+		}
+	})
+
+	t.Run("CursorPagination", func(t *testing.T) {
+		var pages []string
+		query := "sort=expiry&limit=3"
+		for i := 0; i < 3 && query != ""; i++ {
+			items, rr := list(t, query)
+			pages = append(pages, ids(items))
+
+			query = ""
+			if link := rr.Header().Get("Link"); link != "" {
+				next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(link, "<"), ">; rel=\"next\""))
+				if err != nil {
+					t.Fatalf("invalid Link header %s: %v", link, err)
+				}
+				query = next.RawQuery
+			}
+		}
+
+		if got := strings.Join(pages, "|"); got != "1,3,4|2" {
+			t.Errorf("expected pages 1,3,4|2, got %s", got)
+		}
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		for _, query := range []string{"status=sold", "sort=title", "limit=0", "startsAfter=yesterday", "cursor=garbage"} {
+			if _, rr := list(t, query); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %s, got %v", query, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package web_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestReserve tests that bidders can see whether the reserve is met, but only see
+// the reserve price itself when the seller shows it
+func TestReserve(t *testing.T) {
+	fixedTime, _ := time.Parse(time.RFC3339, "2018-08-04T00:00:00Z")
+	var mu sync.Mutex
+	var persisted []domain.Event
+	onEvent := func(event domain.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		persisted = append(persisted, event)
+		return nil
+	}
+	scanEvents := func(fn func(int64, domain.Event) error) error {
+		mu.Lock()
+		events := append([]domain.Event{}, persisted...)
+		mu.Unlock()
+		for i, event := range events {
+			if err := fn(int64(i+1), event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, onEvent, func() time.Time { return fixedTime })
+	app.Events.SetHistory(0, scanEvents)
+	server := httptest.NewServer(app.Router)
+	defer server.Close()
+
+	post := func(path, body, jwt string) {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("x-jwt-payload", jwt)
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("POST %s failed with %v: %s", path, rr.Code, rr.Body.String())
+		}
+	}
+	getAuction := func(t *testing.T, path string) (web.AuctionResponse, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		app.Router.ServeHTTP(rr, req)
+		var auction web.AuctionResponse
+		var fields map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &auction); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return auction, fields
+	}
+
+	post("/auctions", `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Hidden reserve", "currency": "VAC", "typ": "English|VAC20|VAC1|0"}`, sellerJWT)
+	post("/auctions", `{"id": 2, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2019-01-01T10:00:00.000Z", "title": "Shown reserve", "currency": "VAC", "typ": "English|VAC20|VAC1|0", "showReserve": true}`, sellerJWT)
+
+	t.Run("HidesReservePrice", func(t *testing.T) {
+		auction, fields := getAuction(t, "/auctions/1")
+		if auction.ReserveMet {
+			t.Errorf("expected the reserve not to be met without bids")
+		}
+		if _, ok := fields["reservePrice"]; ok {
+			t.Errorf("expected the reserve price to be hidden, got %v", fields["reservePrice"])
+		}
+	})
+
+	t.Run("ShowsReservePrice", func(t *testing.T) {
+		auction, _ := getAuction(t, "/auctions/2")
+		if auction.ReservePrice == nil || *auction.ReservePrice != domain.NewAmount(domain.VAC, 20) {
+			t.Errorf("expected a reserve price of VAC20, got %v", auction.ReservePrice)
+		}
+	})
+
+	t.Run("ReserveMet", func(t *testing.T) {
+		post("/auctions/1/bids", `{"amount": 15}`, buyerJWT)
+		if auction, _ := getAuction(t, "/auctions/1"); auction.ReserveMet {
+			t.Errorf("expected a bid of VAC15 not to meet the reserve")
+		}
+
+		post("/auctions/1/bids", `{"amount": 25}`, buyer3JWT)
+		if auction, _ := getAuction(t, "/auctions/1"); !auction.ReserveMet {
+			t.Errorf("expected a bid of VAC25 to meet the reserve")
+		}
+	})
+
+	t.Run("StreamsReservePriceOnlyWhenShown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/events", nil)
+		req.Header.Set("Last-Event-ID", "0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer resp.Body.Close()
+
+		events := readSseEvents(t, bufio.NewReader(resp.Body), 2)
+		if strings.Contains(events[0].Data, "VAC20") {
+			t.Errorf("expected the hidden reserve price not to be streamed, got %s", events[0].Data)
+		}
+		if !strings.Contains(events[1].Data, "English|VAC20|VAC1|0") {
+			t.Errorf("expected the shown reserve price to be streamed, got %s", events[1].Data)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package web_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestScheduler verifies that ended auctions are closed exactly once
+func TestScheduler(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2018-01-01T10:00:00Z")
+	endsAt, _ := time.Parse(time.RFC3339, "2018-01-02T10:00:00Z")
+	seller := domain.NewBuyerOrSeller("a1", "Test")
+	buyer := domain.NewBuyerOrSeller("a2", "Buyer")
+
+	newAuction := func(id domain.AuctionId, options domain.TimedAscendingOptions) domain.Auction {
+		return domain.NewAuction(id, startsAt, "auction", endsAt, seller, domain.NewTimedAscendingType(options), domain.VAC)
+	}
+	sold := newAuction(1, domain.DefaultTimedAscendingOptions())
+	reserved := newAuction(2, domain.TimedAscendingOptions{ReservePrice: domain.NewAmount(domain.VAC, 100)})
+	bidAt := startsAt.Add(time.Hour)
+
+	repo := domain.EventsToAuctionStates([]domain.Event{
+		domain.AuctionAddedEvent{Time: startsAt, Auction: sold},
+		domain.AuctionAddedEvent{Time: startsAt, Auction: reserved},
+		domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(1, buyer, bidAt, domain.NewAmount(domain.VAC, 10))},
+		domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(2, buyer, bidAt, domain.NewAmount(domain.VAC, 10))},
+	})
+
+	now := startsAt.Add(2 * time.Hour)
+	var recordedEvents []domain.Event
+	onEvents := func(events []domain.Event) error {
+		recordedEvents = append(recordedEvents, events...)
+		return nil
+	}
+	scheduler := web.NewScheduler(web.NewAppState(repo), nil, onEvents, func() time.Time { return now })
+
+	t.Run("NothingClosesBeforeExpiry", func(t *testing.T) {
+		if events := scheduler.Tick(); len(events) != 0 {
+			t.Errorf("expected no events, got %v", events)
+		}
+	})
+
+	t.Run("ClosesEndedAuctions", func(t *testing.T) {
+		now = endsAt.Add(time.Second)
+		scheduler.Tick()
+
+		if len(recordedEvents) != 4 {
+			t.Fatalf("expected 4 events, got %d", len(recordedEvents))
+		}
+
+		var won *domain.AuctionWonEvent
+		var unsold *domain.AuctionUnsoldEvent
+		ended := 0
+		for _, event := range recordedEvents {
+			switch e := event.(type) {
+			case domain.AuctionEndedEvent:
+				ended++
+			case domain.AuctionWonEvent:
+				won = &e
+			case domain.AuctionUnsoldEvent:
+				unsold = &e
+			}
+		}
+
+		if ended != 2 {
+			t.Errorf("expected 2 AuctionEnded events, got %d", ended)
+		}
+		for _, event := range recordedEvents {
+			if !event.GetTime().Equal(endsAt) {
+				t.Errorf("expected closing events at the expiry %v, got %v", endsAt, event)
+			}
+		}
+		if won == nil || won.AuctionId != 1 || won.Winner != buyer.ID || won.Price != domain.NewAmount(domain.VAC, 10) {
+			t.Errorf("expected auction 1 to be won by %s at 10, got %+v", buyer.ID, won)
+		}
+		if unsold == nil || unsold.AuctionId != 2 || !unsold.ReserveNotMet {
+			t.Errorf("expected auction 2 to be unsold with reserve not met, got %+v", unsold)
+		}
+	})
+
+	t.Run("ReportsClosedAuctions", func(t *testing.T) {
+		closed := scheduler.Closed()
+		if !reflect.DeepEqual(closed, domain.ClosedAuctions(recordedEvents)) {
+			t.Errorf("expected auctions 1 and 2 to be closed, got %v", closed)
+		}
+	})
+
+	t.Run("ClosesOnlyOnce", func(t *testing.T) {
+		now = endsAt.Add(time.Minute)
+		if events := scheduler.Tick(); len(events) != 0 {
+			t.Errorf("expected no further events, got %v", events)
+		}
+	})
+
+	t.Run("NeverClosesCancelledAuctions", func(t *testing.T) {
+		cancelled := newAuction(3, domain.DefaultTimedAscendingOptions())
+		state := web.NewAppState(domain.EventsToAuctionStates([]domain.Event{
+			domain.AuctionAddedEvent{Time: startsAt, Auction: cancelled},
+			domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(3, buyer, bidAt, domain.NewAmount(domain.VAC, 10))},
+			domain.AuctionCancelledEvent{Time: bidAt, AuctionId: 3, CancelledBy: domain.NewSupport("s1")},
+		}))
+
+		observed := 0
+		events, err := state.CloseAuction(3, endsAt.Add(time.Second), func(events []domain.Event) error {
+			observed += len(events)
+			return nil
+		})
+		if err != nil || len(events) != 0 || observed != 0 {
+			t.Errorf("expected no closing events for a cancelled auction, got %v (%v)", events, err)
+		}
+	})
+
+	t.Run("SkipsAuctionsAlreadyClosed", func(t *testing.T) {
+		closed := domain.ClosedAuctions(recordedEvents)
+		restarted := web.NewScheduler(web.NewAppState(repo), closed, onEvents, func() time.Time { return now })
+		if events := restarted.Tick(); len(events) != 0 {
+			t.Errorf("expected no events after restart, got %v", events)
+		}
+	})
+}
+
+// TestSchedulerRetriesFailedClose verifies that closing events that fail to persist
+// are retried as a whole, so that no AuctionEnded event is recorded twice
+func TestSchedulerRetriesFailedClose(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2018-01-01T10:00:00Z")
+	endsAt, _ := time.Parse(time.RFC3339, "2018-01-02T10:00:00Z")
+	seller := domain.NewBuyerOrSeller("a1", "Test")
+	buyer := domain.NewBuyerOrSeller("a2", "Buyer")
+	auction := domain.NewAuction(1, startsAt, "auction", endsAt, seller, domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()), domain.VAC)
+	bidAt := startsAt.Add(time.Hour)
+	state := web.NewAppState(domain.EventsToAuctionStates([]domain.Event{
+		domain.AuctionAddedEvent{Time: startsAt, Auction: auction},
+		domain.BidAcceptedEvent{Time: bidAt, Bid: domain.NewBid(1, buyer, bidAt, domain.NewAmount(domain.VAC, 10))},
+	}))
+
+	var recordedEvents []domain.Event
+	failing := true
+	onEvents := func(events []domain.Event) error {
+		if failing {
+			return errors.New("disk full")
+		}
+		recordedEvents = append(recordedEvents, events...)
+		return nil
+	}
+	scheduler := web.NewScheduler(state, nil, onEvents, func() time.Time { return endsAt.Add(time.Second) })
+
+	if events := scheduler.Tick(); len(events) != 0 {
+		t.Errorf("expected no events while they cannot be persisted, got %v", events)
+	}
+	if _, current, _ := state.GetAuction(1); current.HasEnded() {
+		t.Errorf("expected the ended state not to be stored")
+	}
+
+	failing = false
+	scheduler.Tick()
+	if len(recordedEvents) != 2 {
+		t.Fatalf("expected AuctionEnded and AuctionWon once, got %v", recordedEvents)
+	}
+	if _, ok := recordedEvents[0].(domain.AuctionEndedEvent); !ok {
+		t.Errorf("expected an AuctionEnded event first, got %v", recordedEvents[0])
+	}
+	if _, ok := recordedEvents[1].(domain.AuctionWonEvent); !ok {
+		t.Errorf("expected an AuctionWon event, got %v", recordedEvents[1])
+	}
+}
+
+// TestSchedulerFollowsExpiries verifies that auctions are closed when they end as bids
+// extend them, including auctions added after the scheduler started
+func TestSchedulerFollowsExpiries(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2018-01-01T10:00:00Z")
+	endsAt, _ := time.Parse(time.RFC3339, "2018-01-02T10:00:00Z")
+	seller := domain.NewBuyerOrSeller("a1", "Test")
+	buyer := domain.NewBuyerOrSeller("a2", "Buyer")
+	options := domain.TimedAscendingOptions{MinRaise: domain.NewAmount(domain.VAC, 1), TimeFrame: time.Hour}
+	auction := domain.NewAuction(1, startsAt, "auction", endsAt, seller, domain.NewTimedAscendingType(options), domain.VAC)
+
+	state := web.NewAppState(domain.Repository{})
+	now := startsAt
+	var recordedEvents []domain.Event
+	onEvents := func(events []domain.Event) error {
+		recordedEvents = append(recordedEvents, events...)
+		return nil
+	}
+	scheduler := web.NewScheduler(state, nil, onEvents, func() time.Time { return now })
+
+	handle := func(cmd domain.Command) {
+		t.Helper()
+		_, err := state.HandleCommand(cmd, func(domain.Command) error { return nil }, func(domain.Event) (int64, error) { return 0, nil })
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	handle(domain.AddAuctionCommand{Time: startsAt, Auction: auction})
+
+	// A bid half an hour before the expiry extends the auction by the time frame
+	bidAt := endsAt.Add(-30 * time.Minute)
+	handle(domain.PlaceBidCommand{Time: bidAt, Bid: domain.NewBid(1, buyer, bidAt, domain.NewAmount(domain.VAC, 10))})
+	extendedExpiry := bidAt.Add(time.Hour)
+
+	now = endsAt.Add(time.Second)
+	if events := scheduler.Tick(); len(events) != 0 {
+		t.Errorf("expected the extended auction not to close at its original expiry, got %v", events)
+	}
+
+	now = extendedExpiry.Add(time.Minute)
+	scheduler.Tick()
+	if len(recordedEvents) != 2 {
+		t.Fatalf("expected AuctionEnded and AuctionWon, got %v", recordedEvents)
+	}
+	if ended, ok := recordedEvents[0].(domain.AuctionEndedEvent); !ok || !ended.Time.Equal(extendedExpiry) {
+		t.Errorf("expected the auction to end at the extended expiry %v, got %v", extendedExpiry, recordedEvents[0])
+	}
+}
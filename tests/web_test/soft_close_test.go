@@ -0,0 +1,38 @@
+package web_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestNextExpiry tests that the response shows when an auction extended by late bids ends
+func TestNextExpiry(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-12-31T09:59:00Z")
+	app := web.NewApp(domain.Repository{}, func(domain.Command) error { return nil }, func(domain.Event) error { return nil }, func() time.Time { return now })
+
+	nextExpiry := func(t *testing.T) time.Time {
+		t.Helper()
+		return getAuction(t, app, 1).NextExpiry
+	}
+
+	auctionReq := `{"id": 1, "startsAt": "2018-01-01T10:00:00.000Z", "endsAt": "2018-12-31T10:00:00.000Z", "title": "Soft close", "currency": "VAC", "typ": "English|VAC0|VAC1|0,within:300,by:120"}`
+	if rr := send(app, "POST", "/auctions", sellerJWT, auctionReq); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	expiry, _ := time.Parse(time.RFC3339, "2018-12-31T10:00:00Z")
+	if got := nextExpiry(t); !got.Equal(expiry) {
+		t.Errorf("expected the auction to end at %v before any bids, got %v", expiry, got)
+	}
+
+	if rr := send(app, "POST", "/auctions/1/bids", buyerJWT, `{"amount": 10}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got, want := nextExpiry(t), expiry.Add(2*time.Minute); !got.Equal(want) {
+		t.Errorf("expected a late bid to extend the auction to %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,81 @@
+package web_test
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"auction-site-go/internal/domain"
+	"auction-site-go/internal/web"
+)
+
+// TestConcurrentBids fires concurrent bids at several auctions and verifies that
+// no accepted bid is lost and that the final state matches a replay of the event log
+func TestConcurrentBids(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2018-01-01T10:00:00Z")
+	endsAt, _ := time.Parse(time.RFC3339, "2019-01-01T10:00:00Z")
+	bidAt := startsAt.Add(time.Hour)
+	seller := domain.NewBuyerOrSeller("seller", "Seller")
+
+	const auctionCount = 4
+	const bidCount = 4000
+
+	var mu sync.Mutex
+	var recordedEvents []domain.Event
+	onCommand := func(domain.Command) error { return nil }
+	onEvent := func(event domain.Event) (int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		recordedEvents = append(recordedEvents, event)
+		return int64(len(recordedEvents)), nil
+	}
+
+	state := web.NewAppState(domain.Repository{})
+	for i := 1; i <= auctionCount; i++ {
+		auction := domain.NewAuction(domain.AuctionId(i), startsAt, "auction", endsAt, seller,
+			domain.NewTimedAscendingType(domain.DefaultTimedAscendingOptions()), domain.VAC)
+		if _, err := state.HandleCommand(domain.AddAuctionCommand{Time: startsAt, Auction: auction}, onCommand, onEvent); err != nil {
+			t.Fatalf("failed to add auction: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < bidCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bidder := domain.NewBuyerOrSeller(domain.UserId(fmt.Sprintf("buyer%d", i)), "Buyer")
+			bid := domain.NewBid(domain.AuctionId(i%auctionCount+1), bidder, bidAt, domain.NewAmount(domain.VAC, int64(rand.Intn(bidCount))))
+			state.HandleCommand(domain.PlaceBidCommand{Time: bidAt, Bid: bid}, onCommand, onEvent)
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := make(map[domain.AuctionId]int)
+	for _, event := range recordedEvents {
+		if e, ok := event.(domain.BidAcceptedEvent); ok {
+			accepted[e.Bid.ForAuction]++
+		}
+	}
+
+	final := state.GetRepository()
+	replayed := domain.EventsToAuctionStates(recordedEvents)
+	for id, entry := range final {
+		bids := entry.State.GetBids()
+		if len(bids) != accepted[id] {
+			t.Errorf("auction %d: expected %d bids, got %d", id, accepted[id], len(bids))
+		}
+
+		replayedBids := replayed[id].State.GetBids()
+		if len(replayedBids) != len(bids) {
+			t.Fatalf("auction %d: expected %d bids after replay, got %d", id, len(bids), len(replayedBids))
+		}
+		for i := range bids {
+			if bids[i] != replayedBids[i] {
+				t.Errorf("auction %d: expected bid %d to be %+v after replay, got %+v", id, i, bids[i], replayedBids[i])
+			}
+		}
+	}
+}